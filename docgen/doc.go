@@ -35,15 +35,60 @@ type docFnSection struct {
 }
 
 type docFnEntry struct {
-	Name        string                   `json:"name"`
-	Namespace   string                   `json:"namespace"`
-	Signature   string                   `json:"signature"`
-	Description string                   `json:"description"`
-	Tags        []lang.FunctionTag       `json:"tags"`
-	Args        []lang.FunctionArg       `json:"args"`
-	Return      *lang.FunctionReturn     `json:"return"`
-	Attributes  []lang.FunctionAttribute `json:"attributes"`
-	Examples    []lang.ProgramExample    `json:"examples"`
+	Name         string                    `json:"name"`
+	Namespace    string                    `json:"namespace"`
+	Signature    string                    `json:"signature"`
+	Description  string                    `json:"description"`
+	Tags         []lang.FunctionTag        `json:"tags"`
+	Args         []*docFnArg               `json:"args"`
+	Return       *lang.FunctionReturn      `json:"return"`
+	Attributes   []lang.FunctionAttribute  `json:"attributes"`
+	Capabilities []lang.FunctionCapability `json:"capabilities"`
+	Examples     []lang.ProgramExample     `json:"examples"`
+}
+
+type docFnArg struct {
+	Name        string
+	Description string
+	Types       []lang.PublicType
+	Optional    bool
+	Default     string // JSON encoding of the default value, or empty if there is none
+}
+
+func newDocFnArg(arg lang.FunctionArg) *docFnArg {
+	ret := &docFnArg{
+		Name:        arg.Name,
+		Description: arg.Description,
+		Types:       arg.Types,
+		Optional:    arg.Optional,
+	}
+	if arg.Default != nil {
+		if b, err := arg.Default.MarshalJSON(); err == nil {
+			ret.Default = string(b)
+		}
+	}
+	return ret
+}
+
+func newDocFnEntry(fnEntry *lang.FunctionEntry) *docFnEntry {
+	ret := &docFnEntry{
+		Name:         fnEntry.Name,
+		Namespace:    fnEntry.Namespace,
+		Signature:    fnEntry.Signature(),
+		Description:  fnEntry.Description,
+		Tags:         fnEntry.Tags,
+		Args:         []*docFnArg{},
+		Attributes:   fnEntry.Attributes,
+		Capabilities: fnEntry.Capabilities,
+		Examples:     fnEntry.Examples,
+	}
+	for _, arg := range fnEntry.Args {
+		ret.Args = append(ret.Args, newDocFnArg(arg))
+	}
+	if fnEntry.Return != nil {
+		ret.Return = fnEntry.Return
+	}
+	return ret
 }
 
 func (dfn *docFnEntry) JoinedTags() string {
@@ -54,6 +99,14 @@ func (dfn *docFnEntry) JoinedTags() string {
 	return strings.Join(tagList, ", ")
 }
 
+func (dfn *docFnEntry) JoinedCapabilities() string {
+	capList := []string{}
+	for _, entry := range dfn.Capabilities {
+		capList = append(capList, string(entry))
+	}
+	return strings.Join(capList, ", ")
+}
+
 func (dfn *docFnEntry) FormatReturn() string {
 	if dfn.Return == nil {
 		return "No usable or assignable object is returned from this function"
@@ -75,13 +128,15 @@ var orderedTagList = []lang.FunctionTag{
 	lang.FUNCTION_TAG_ARRAYS,
 	lang.FUNCTION_TAG_MAPS,
 	lang.FUNCTION_TAG_TIME,
+	lang.FUNCTION_TAG_LOOKUPS,
+	lang.FUNCTION_TAG_STATE,
 	lang.FUNCTION_TAG_HIGHER_ORDER,
 }
 
 func (d *FunctionDocs) buildSections(fs *lang.FunctionStore) {
 	//list namespaces by starting with "std", but then use alphabetical order after
 	nsNameList := []string{}
-	for nsName := range fs.Namespaces {
+	for _, nsName := range fs.NamespaceNames() {
 		if nsName == "std" {
 			continue
 		}
@@ -92,7 +147,7 @@ func (d *FunctionDocs) buildSections(fs *lang.FunctionStore) {
 
 	// now we can iterate through in order
 	for _, nsName := range nsNameList {
-		ns := fs.Namespaces[nsName]
+		functions := fs.Functions(nsName)
 		nsToAdd := &docFnNamespace{
 			Name:             nsName,
 			FunctionSections: []*docFnSection{},
@@ -102,34 +157,28 @@ func (d *FunctionDocs) buildSections(fs *lang.FunctionStore) {
 
 			fnSectionToAdd := &docFnSection{
 				Tag:       string(tag),
-				Namespace: ns.Name,
+				Namespace: nsName,
 				Functions: []*docFnEntry{},
 			}
 
-			for _, fnEntry := range ns.Functions {
-				if !slices.Contains(fnEntry.Tags, tag) {
+			for _, fnEntry := range functions {
+				// a function is listed in the section of the first tag that any of its overloads has
+				hasTag := slices.ContainsFunc(fnEntry.Overloads(), func(overload *lang.FunctionEntry) bool {
+					return slices.Contains(overload.Tags, tag)
+				})
+				if !hasTag {
 					continue
 				}
 				if slices.Contains(alreadyIncluded, fnEntry.Name) {
 					continue
 				}
-				fnToAdd := &docFnEntry{
-					Name:        fnEntry.Name,
-					Namespace:   fnEntry.Namespace,
-					Signature:   fnEntry.Signature(),
-					Description: fnEntry.Description,
-					Tags:        fnEntry.Tags,
-					Args:        fnEntry.Args,
-					Attributes:  fnEntry.Attributes,
-					Examples:    fnEntry.Examples,
-				}
-				if fnEntry.Return != nil {
-					fnToAdd.Return = fnEntry.Return
+				// each overload is listed as its own entry, after the entry it was registered against
+				for _, overload := range fnEntry.Overloads() {
+					fnSectionToAdd.Functions = append(fnSectionToAdd.Functions, newDocFnEntry(overload))
 				}
-				fnSectionToAdd.Functions = append(fnSectionToAdd.Functions, fnToAdd)
 				alreadyIncluded = append(alreadyIncluded, fnEntry.Name)
 			}
-			slices.SortFunc(fnSectionToAdd.Functions, func(a *docFnEntry, b *docFnEntry) int {
+			slices.SortStableFunc(fnSectionToAdd.Functions, func(a *docFnEntry, b *docFnEntry) int {
 				return strings.Compare(a.Name, b.Name)
 			})
 			if len(fnSectionToAdd.Functions) > 0 {
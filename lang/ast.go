@@ -2,7 +2,6 @@ package lang
 
 import (
 	"fmt"
-	"sort"
 	"strings"
 )
 
@@ -121,6 +120,32 @@ func (d *delStatement) position() position {
 
 //
 
+type paramStatement struct {
+	tok   token
+	name  *identifierExpression
+	value expression // default value. nil if the parameter is required
+}
+
+func (ps *paramStatement) statementNode() {}
+func (ps *paramStatement) token() token   { return ps.tok }
+func (ps *paramStatement) string() string {
+	if ps.value == nil {
+		return fmt.Sprintf("%s %s", ps.tok.value, ps.name.string())
+	}
+	return fmt.Sprintf("%s %s = %s", ps.tok.value, ps.name.string(), ps.value.string())
+}
+func (ps *paramStatement) position() position {
+	if ps.value == nil {
+		return ps.name.position()
+	}
+	return position{
+		start: ps.tok.start,
+		end:   ps.value.position().end,
+	}
+}
+
+//
+
 type ifStatement struct {
 	tok         token
 	condition   expression
@@ -220,6 +245,7 @@ func (ie *infixExpression) position() position {
 type mapLiteral struct {
 	tok    token
 	pairs  map[string]expression
+	keys   []string // the keys of pairs, in the order they were written
 	endPos int
 }
 
@@ -227,12 +253,7 @@ func (m *mapLiteral) expressionNode() {}
 func (m *mapLiteral) token() token    { return m.tok }
 func (m *mapLiteral) string() string {
 	stringList := []string{}
-	keys := []string{}
-	for k := range m.pairs {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	for _, k := range keys {
+	for _, k := range m.keys {
 		v := m.pairs[k]
 		stringList = append(stringList, fmt.Sprintf("%q: %s", k, v.string()))
 	}
@@ -291,6 +312,14 @@ func (ie *indexExpression) position() position {
 	}
 }
 
+// index expressions are assignable so that indexes can be chained, like item[0][1], but SET and DEL still reject them as targets
+func (ie *indexExpression) toAssignPath() *assignPath {
+	return &assignPath{stepType: assign_step_invalid}
+}
+func (ie *indexExpression) checkAssignPathPure() (bool, string) {
+	return false, "index expressions cannot be assigned to in SET and DEL statements"
+}
+
 //
 
 type identifierExpression struct {
@@ -511,12 +540,24 @@ func (te *templateExpression) string() string {
 //
 
 type callExpression struct {
-	tok       token
-	name      assignable // path or ident
-	arguments []expression
-	isPipe    bool
-	pipeTok   token
-	endPos    int
+	tok            token
+	name           assignable // path or ident
+	arguments      []expression
+	namedArguments []*namedArgument // arguments passed as name=value, which always follow the positional arguments
+	overload       *FunctionEntry   // set at compile time when the overload can be picked from the argument types
+	isPipe         bool
+	pipeTok        token
+	endPos         int
+}
+
+type namedArgument struct {
+	tok   token
+	name  string
+	value expression
+}
+
+func (na *namedArgument) string() string {
+	return fmt.Sprintf("%s=%s", na.name, na.value.string())
 }
 
 func (c *callExpression) expressionNode() {}
@@ -536,6 +577,9 @@ func (c *callExpression) string() string {
 	for _, entry := range args {
 		argStringList = append(argStringList, entry.string())
 	}
+	for _, entry := range c.namedArguments {
+		argStringList = append(argStringList, entry.string())
+	}
 	return fmt.Sprintf("%s(%s)", outer, strings.Join(argStringList, ", "))
 }
 func (c *callExpression) position() position {
@@ -547,6 +591,27 @@ func (c *callExpression) position() position {
 
 //
 
+// checks whether a variable or path exists, without reading it. written like a call: has(@in.a.b)
+type hasExpression struct {
+	tok    token
+	target expression // an identifier, path, or index expression
+	endPos int
+}
+
+func (he *hasExpression) expressionNode() {}
+func (he *hasExpression) token() token    { return he.tok }
+func (he *hasExpression) string() string {
+	return fmt.Sprintf("has(%s)", he.target.string())
+}
+func (he *hasExpression) position() position {
+	return position{
+		start: he.tok.start,
+		end:   he.endPos,
+	}
+}
+
+//
+
 type arrowFunctionExpression struct {
 	tok       token
 	paramName *identifierExpression
@@ -2,51 +2,125 @@ package lang
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata" // embeds the time zone database, so that time zone names work on hosts without one
+	"unicode"
+	"unicode/utf8"
 )
 
 func newBuiltinFunctionStore() *FunctionStore {
 	store := NewFunctionStore()
+	var errs []error
+	check := func(err error) {
+		errs = append(errs, err)
+	}
 
 	//nulls and err handling
-	store.Register(builtinCatchEntry())
-	store.Register(builtinCoalesceEntry())
-	store.Register(builtinFallbackEntry())
+	check(store.Register(builtinCatchEntry()))
+	check(store.Register(builtinCoalesceEntry()))
+	check(store.Register(builtinFallbackEntry()))
 
 	//type coercion
-	store.Register(builtinIntEntry())
-	store.Register(builtinFloatEntry())
-	store.Register(builtinStringEntry())
-	store.Register(builtinTimeEntry())
+	check(store.Register(builtinIntEntry()))
+	check(store.Register(builtinFloatEntry()))
+	check(store.Register(builtinStringEntry()))
+	check(store.Register(builtinTimeEntry()))
+	check(store.Register(builtinDurationEntry()))
 
 	//flow control
-	store.Register(builtinDropEntry())
-	store.Register(builtinEmitEntry())
+	check(store.Register(builtinDropEntry()))
+	check(store.Register(builtinEmitEntry()))
+	check(store.Register(builtinEmitRecordEntry()))
 
 	//general
-	store.Register(builtinLenEntry())
-	store.Register(builtinContainsEntry())
+	check(store.Register(builtinLenEntry()))
+	check(store.RegisterOverload(builtinLenArrayEntry()))
+	check(store.RegisterOverload(builtinLenMapEntry()))
+	check(store.Register(builtinContainsEntry()))
 
 	//numbers
-	store.Register(builtinMinEntry())
-	store.Register(builtinMaxEntry())
+	check(store.Register(builtinMinEntry()))
+	check(store.Register(builtinMaxEntry()))
+
+	//strings
+	check(store.Register(builtinSplitEntry()))
+	check(store.Register(builtinJoinEntry()))
+	check(store.Register(builtinUpperEntry()))
+	check(store.Register(builtinLowerEntry()))
+	check(store.Register(builtinTitleEntry()))
+	check(store.Register(builtinTrimEntry()))
+	check(store.Register(builtinTrimLeftEntry()))
+	check(store.Register(builtinTrimRightEntry()))
+	check(store.Register(builtinTrimPrefixEntry()))
+	check(store.Register(builtinTrimSuffixEntry()))
+	check(store.Register(builtinReplaceEntry()))
+	check(store.Register(builtinSubstringEntry()))
+	check(store.Register(builtinStartsWithEntry()))
+	check(store.Register(builtinEndsWithEntry()))
+	check(store.Register(builtinIndexOfEntry()))
+	check(store.Register(builtinPadLeftEntry()))
+	check(store.Register(builtinPadRightEntry()))
+	check(store.Register(builtinRepeatEntry()))
 
 	//arrays
-	store.Register(builtinAppendEntry())
+	check(store.Register(builtinAppendEntry()))
+
+	//paths
+	check(store.Register(builtinGetPathEntry()))
+	check(store.Register(builtinSetPathEntry()))
+	check(store.Register(builtinDelPathEntry()))
+	check(store.Register(builtinHasPathEntry()))
+	check(store.Register(builtinGetPointerEntry()))
+	check(store.Register(builtinSetPointerEntry()))
+	check(store.Register(builtinDelPointerEntry()))
+	check(store.Register(builtinHasPointerEntry()))
 
 	//higher order funcs
-	store.Register(builtinMapEntry())
-	store.Register(builtinFilterEntry())
-	store.Register(builtinReduceEntry())
+	check(store.Register(builtinMapEntry()))
+	check(store.RegisterOverload(builtinMapMapEntry()))
+	check(store.Register(builtinFilterEntry()))
+	check(store.Register(builtinReduceEntry()))
 
 	//time
-	store.Register(builtinNowEntry())
-	store.Register(builtinParseTimeEntry())
+	check(store.Register(builtinNowEntry()))
+	check(store.Register(builtinParseTimeEntry()))
+	check(store.Register(builtinAddTimeEntry()))
+	check(store.Register(builtinTimeDiffEntry()))
+	check(store.Register(builtinTruncateTimeEntry()))
+	check(store.Register(builtinDurationSecondsEntry()))
+	check(store.Register(builtinFormatTimeEntry()))
+	check(store.Register(builtinToTimezoneEntry()))
+	check(store.Register(builtinStartOfEntry()))
+	check(store.Register(builtinYearEntry()))
+	check(store.Register(builtinMonthEntry()))
+	check(store.Register(builtinDayEntry()))
+	check(store.Register(builtinHourEntry()))
+	check(store.Register(builtinMinuteEntry()))
+	check(store.Register(builtinSecondEntry()))
+	check(store.Register(builtinWeekdayEntry()))
+	check(store.Register(builtinISOWeekEntry()))
+	check(store.Register(builtinDayOfYearEntry()))
+
+	//lookups
+	check(store.Register(builtinLookupEntry()))
+	check(store.Register(builtinLookupDefaultEntry()))
+	check(store.Register(builtinLookupManyEntry()))
 
+	//state
+	check(store.RegisterToNamespace("state", builtinStateGetEntry()))
+	check(store.RegisterToNamespace("state", builtinStatePutEntry()))
+	check(store.RegisterToNamespace("state", builtinStateIncrEntry()))
+	check(store.RegisterToNamespace("state", builtinStateSeenEntry()))
+
+	// the builtins are fixed at compile time, so an error here is a bug in this package
+	if err := errors.Join(errs...); err != nil {
+		panic(fmt.Sprintf("invalid builtin function store: %v", err))
+	}
 	return store
 }
 
@@ -125,13 +199,12 @@ func builtinCatch(ctx context.Context, args ...*Object) *Object {
 			if err != nil {
 				return ObjectError(err.Error())
 			}
-			envOut := arrow.Run(inputErr.Error())
-			mapOut, ok := envOut.(map[string]interface{})
-			if !ok {
-				return ObjectError("error calling fallback arrow function: unable to extract 'return' field from arrow function")
+			envOut := arrow.runObjects(inputErr.Error())
+			if arrow.HasError() {
+				return arrow.GetError()
 			}
-			if ret, ok := mapOut["return"]; ok {
-				return CastAuto(ret)
+			if ret, ok := envOut["return"]; ok {
+				return &Object{inner: ret}
 			}
 		}
 		return fallback
@@ -360,7 +433,7 @@ func builtinStringEntry() *FunctionEntry {
 			NewFunctionArg(
 				"target",
 				"The expression to convert into a string",
-				INTEGER, FLOAT, BOOLEAN, TIME, STRING,
+				INTEGER, FLOAT, BOOLEAN, TIME, DURATION, STRING,
 			),
 		),
 		WithReturn(
@@ -458,12 +531,78 @@ func builtinTime(ctx context.Context, args ...*Object) *Object {
 	return CastTime(val)
 }
 
+func builtinDurationEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"duration",
+		"Attempts to convert the target item into a duration type",
+		builtinDuration,
+
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				`The expression to convert into a duration.
+Strings are a sequence of numbers with units, such as "1h30m", "90s", or "-1.5h". Valid units are "ns", "us", "ms", "s", "m", and "h".
+Integers and floats are a number of seconds.`,
+				DURATION, STRING, INTEGER, FLOAT,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The resultant duration object after conversion. Throws an error if unable to convert.",
+				DURATION,
+			),
+		),
+		WithTags(FUNCTION_TAG_TYPE_COERCION),
+		WithExamples(
+			NewProgramExample(
+				`null`,
+				`SET @out.result = duration("1h30m")`,
+				`{"result": "1h30m0s"}`,
+			),
+			NewProgramExample(
+				`{"timeout_seconds": 90}`,
+				`SET @out.result = duration(@in.timeout_seconds)`,
+				`{"result": "1m30s"}`,
+			),
+		),
+	)
+}
+func builtinDuration(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(1, args); !ok {
+		return res
+	}
+	switch v := args[0].inner.(type) {
+	case *objectDuration:
+		return args[0]
+	case *objectString:
+		return CastDuration(v.value)
+	case *objectInteger, *objectFloat:
+		seconds, _ := objectNumberToFloat64(v)
+		d := durationFromFloat(seconds * float64(time.Second))
+		if isObjectErr(d) {
+			msg := fmt.Sprintf("unable to convert item to DURATION. %v seconds is out of range", seconds)
+			return ObjectError(msg)
+		}
+		return &Object{inner: d}
+	default:
+		msg := fmt.Sprintf("unable to convert item to DURATION. invalid input type: %s", args[0].Type())
+		return ObjectError(msg)
+	}
+}
+
 func builtinDropEntry() *FunctionEntry {
 	return NewFunctionEntry(
 		"drop",
 		"Stops the current run of Morph statements, and returns NULL",
 		builtinDrop,
-
+		WithArgs(
+			NewOptionalFunctionArg(
+				"reason",
+				"An optional reason for dropping the data, which is reported to the host application",
+				nil,
+				STRING,
+			),
+		),
 		WithTags(FUNCTION_TAG_FLOW_CONTROL),
 		WithExamples(
 			NewProgramExample(
@@ -472,21 +611,53 @@ func builtinDropEntry() *FunctionEntry {
 drop()`,
 				`null`,
 			),
+			NewProgramExample(
+				`{"level": "debug"}`,
+				`//drop with a reason
+IF @in.level == "debug" :: {
+	drop("debug logs are not forwarded")
+}
+SET @out = @in`,
+				`null`,
+			),
 		),
 	)
 }
 func builtinDrop(ctx context.Context, args ...*Object) *Object {
-	if ret, ok := IsArgCountEqual(0, args); !ok {
-		return ret
+	reason, errObj := builtinTerminateReason(args)
+	if errObj != nil {
+		return errObj
 	}
-	return ObjectTerminateDrop
+	if len(reason) == 0 {
+		return ObjectTerminateDrop
+	}
+	return ObjectTerminateDropWithReason(reason)
+}
+
+// extracts the optional reason argument shared by drop() and emit()
+func builtinTerminateReason(args []*Object) (string, *Object) {
+	if len(args) == 0 || args[0].Type() == string(NULL) {
+		return "", nil
+	}
+	reason, err := args[0].AsString()
+	if err != nil {
+		return "", ObjectError(err.Error())
+	}
+	return reason, nil
 }
 func builtinEmitEntry() *FunctionEntry {
 	return NewFunctionEntry(
 		"emit",
 		"Stops the current run of Morph statements, and returns data in its current state",
 		builtinEmit,
-
+		WithArgs(
+			NewOptionalFunctionArg(
+				"reason",
+				"An optional reason for stopping early, which is reported to the host application",
+				nil,
+				STRING,
+			),
+		),
 		WithTags(FUNCTION_TAG_FLOW_CONTROL),
 		WithExamples(
 			NewProgramExample(
@@ -500,23 +671,66 @@ SET @out.result = 0`,
 	)
 }
 func builtinEmit(ctx context.Context, args ...*Object) *Object {
-	if ret, ok := IsArgCountEqual(0, args); !ok {
-		return ret
+	reason, errObj := builtinTerminateReason(args)
+	if errObj != nil {
+		return errObj
+	}
+	if len(reason) == 0 {
+		return ObjectTerminate
+	}
+	return ObjectTerminateWithReason(reason)
+}
+
+func builtinEmitRecordEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"emit_record",
+		`Adds a copy of the value to the list of records produced by the current run, and continues processing.
+This allows a single input to fan out into multiple output records, which are returned by the host API in the order they were emitted, followed by @out if it was set.
+Calling drop() discards every record emitted during the run, while emit() keeps the records emitted so far.`,
+		builtinEmitRecord,
+		WithArgs(
+			NewFunctionArg(
+				"record",
+				"The value to add as an output record",
+				BASIC_WITHOUT_ERROR...,
+			),
+		),
+		WithTags(FUNCTION_TAG_FLOW_CONTROL),
+		WithExamples(
+			NewProgramExample(
+				`{"events": [{"id": 1}, {"id": 2}]}`,
+				`//emits {"id": 1} and {"id": 2} as separate records. @out is never set, so it is not part of the output
+map(@in.events, e ~> {
+	emit_record(e.value)
+})`,
+				`null`,
+			),
+		),
+	)
+}
+func builtinEmitRecord(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(1, args); !ok {
+		return res
+	}
+	sink, ok := recordSinkFromContext(ctx)
+	if !ok {
+		return ObjectError("emit_record() is not supported outside of a program run")
 	}
-	return ObjectTerminate
+	sink.records = append(sink.records, args[0].inner.clone())
+	return ObjectNull
 }
 
 func builtinLenEntry() *FunctionEntry {
 	return NewFunctionEntry(
 		"len",
-		"Gets the length of the target string, array, or map",
+		"Gets the length of the target string, in characters",
 		builtinLen,
 
 		WithArgs(
 			NewFunctionArg(
 				"target",
-				"The item to check for length",
-				STRING, ARRAY, MAP,
+				"The string to check for length",
+				STRING,
 			),
 		),
 		WithReturn(
@@ -525,7 +739,7 @@ func builtinLenEntry() *FunctionEntry {
 				INTEGER,
 			),
 		),
-		WithTags(FUNCTION_TAG_GENERAL, FUNCTION_TAG_MAPS, FUNCTION_TAG_ARRAYS, FUNCTION_TAG_STRINGS),
+		WithTags(FUNCTION_TAG_GENERAL, FUNCTION_TAG_STRINGS),
 		WithExamples(
 			NewProgramExample(
 				`null`,
@@ -533,12 +747,84 @@ func builtinLenEntry() *FunctionEntry {
 SET @out.result = len("car")`,
 				`{"result": 3}`,
 			),
+		),
+	)
+}
+
+func builtinLen(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(1, args); !ok {
+		return res
+	}
+	s, err := args[0].AsString()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	return CastInt(utf8.RuneCountInString(s))
+}
+
+func builtinLenArrayEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"len",
+		"Gets the number of entries in the target array",
+		builtinLenArray,
+
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The array to check for length",
+				ARRAY,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The length of the target",
+				INTEGER,
+			),
+		),
+		WithTags(FUNCTION_TAG_ARRAYS),
+		WithExamples(
 			NewProgramExample(
 				`null`,
 				`//len of array
 SET @out.result = len(["a", "b", "c"])`,
 				`{"result": 3}`,
 			),
+		),
+	)
+}
+
+func builtinLenArray(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(1, args); !ok {
+		return res
+	}
+	arr, ok := args[0].inner.(*objectArray)
+	if !ok {
+		return ObjectError(fmt.Sprintf("len() target must be an array. got=%s", args[0].Type()))
+	}
+	return CastInt(len(arr.entries))
+}
+
+func builtinLenMapEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"len",
+		"Gets the number of keys in the target map",
+		builtinLenMap,
+
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The map to check for length",
+				MAP,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The length of the target",
+				INTEGER,
+			),
+		),
+		WithTags(FUNCTION_TAG_MAPS),
+		WithExamples(
 			NewProgramExample(
 				`{"k1": "v1", "k2": "v2", "k3": "v3"}`,
 				`//len of map
@@ -549,39 +835,15 @@ SET @out.result = len(@in)`,
 	)
 }
 
-func builtinLen(ctx context.Context, args ...*Object) *Object {
-	if len(args) != 1 {
-		msg := fmt.Sprintf("function len() requires a single argument. got=%d", len(args))
-		return ObjectError(msg)
-	}
-	arg := args[0]
-	acceptableTypes := []string{string(ARRAY), string(STRING), string(MAP)}
-	if !slices.Contains(acceptableTypes, arg.Type()) {
-		msg := fmt.Sprintf("invalid argument type. Expect one of STRING, ARRAY, or MAP. got=%s", arg.Type())
-		return ObjectError(msg)
+func builtinLenMap(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(1, args); !ok {
+		return res
 	}
-	ret := 0
-	switch arg.Type() {
-	case string(STRING):
-		s, err := arg.AsString()
-		if err != nil {
-			ObjectError(err.Error())
-		}
-		ret = len(s)
-	case string(ARRAY):
-		a, err := arg.AsArray()
-		if err != nil {
-			return ObjectError(err.Error())
-		}
-		ret = len(a)
-	case string(MAP):
-		m, err := arg.AsMap()
-		if err != nil {
-			return ObjectError(err.Error())
-		}
-		ret = len(m)
+	m, ok := args[0].inner.(*objectMap)
+	if !ok {
+		return ObjectError(fmt.Sprintf("len() target must be a map. got=%s", args[0].Type()))
 	}
-	return CastInt(ret)
+	return CastInt(len(m.kvPairs))
 }
 
 func builtinMinEntry() *FunctionEntry {
@@ -833,331 +1095,1359 @@ func builtinContains(ctx context.Context, args ...*Object) *Object {
 	}
 	return CastBool(ret)
 }
-func builtinAppendEntry() *FunctionEntry {
+func builtinSplitEntry() *FunctionEntry {
 	return NewFunctionEntry(
-		"append",
-		"Determines whether a parent item contains specified contents",
-		builtinAppend,
+		"split",
+		"Splits a string into an array of the parts between each separator",
+		builtinSplit,
 		WithArgs(
 			NewFunctionArg(
-				"array",
-				"the target array to add an item to",
-				ARRAY,
+				"target",
+				"The string to split",
+				STRING,
 			),
 			NewFunctionArg(
-				"item",
-				"the item to add to the target array",
-				BASIC_WITHOUT_ERROR...,
+				"separator",
+				"The separator between parts. An empty separator splits the string into its individual characters",
+				STRING,
 			),
 		),
 		WithReturn(
 			NewFunctionReturn(
-				"Whether the contents exist within the parent",
-				ARRAY,
+				"The parts of the string, without the separators",
+				ArrayOf(STRING),
 			),
 		),
-		WithTags(FUNCTION_TAG_ARRAYS),
+		WithTags(FUNCTION_TAG_STRINGS),
 		WithExamples(
 			NewProgramExample(
-				`[1, 2]`,
-				`SET @out.result = append(@in, 3)`,
-				`{"result": [1, 2, 3]}`,
+				`{"tags": "red,green,blue"}`,
+				`SET @out = split(@in.tags, ",")`,
+				`["red", "green", "blue"]`,
+			),
+			NewProgramExample(
+				`{"word": "héllo"}`,
+				`SET @out = split(@in.word, "")`,
+				`["h", "é", "l", "l", "o"]`,
 			),
 		),
 	)
 }
 
-func builtinAppend(ctx context.Context, args ...*Object) *Object {
+func builtinSplit(ctx context.Context, args ...*Object) *Object {
 	if res, ok := IsArgCountEqual(2, args); !ok {
 		return res
 	}
-	arr, err := args[0].AsArray()
+	target, err := args[0].AsString()
 	if err != nil {
-		msg := fmt.Sprintf("append() invalid array argument of type %s", args[0].Type())
-		return ObjectError(msg)
+		return ObjectError(err.Error())
 	}
-	toAdd, err := args[1].AsAny()
+	separator, err := args[1].AsString()
 	if err != nil {
-		msg := fmt.Sprintf("append() invalid second argument of type %s", args[1].Type())
-		return ObjectError(msg)
+		return ObjectError(err.Error())
 	}
-	arr = append(arr, toAdd)
-	return CastArray(arr)
+	ret := &objectArray{entries: []object{}}
+	for _, part := range strings.Split(target, separator) {
+		ret.entries = append(ret.entries, &objectString{value: part})
+	}
+	return &Object{inner: ret}
 }
-func builtinMapEntry() *FunctionEntry {
+
+func builtinJoinEntry() *FunctionEntry {
 	return NewFunctionEntry(
-		"map",
-		"Iterates over each entry of the target (map or array), and remap their value based on the 'return' key set in the arrow function",
-		builtinMap,
+		"join",
+		"Joins an array of strings into a single string, with a separator between each entry",
+		builtinJoin,
 		WithArgs(
 			NewFunctionArg(
-				"target",
-				"The target array or map to iterate over",
-				MAP, ARRAY,
-			),
-			NewFunctionArg(
-				"map_function",
-				`The re-mapping arrow function.
-The original entry value will be replaced by whatever value is in the "return" variable when the arrow function is finished.
-The orignal value can be accessed via the ".value" path from the named variable passed to the arrow function.
-For maps: The key name for an entry is accessible via the ".key" path from the named variable passed to the arrow function; you cannot reassign keys using this function.
-For arrays: The index number for an entry is accessible via the ".index" path from the named variable passed to the arrow function; you cannot reassign index numbers using this function.`,
-				ARROWFUNC,
+				"items",
+				"The strings to join",
+				ArrayOf(STRING),
+			),
+			NewOptionalFunctionArg(
+				"separator",
+				"The separator to put between each entry",
+				CastString(""),
+				STRING,
 			),
 		),
 		WithReturn(
 			NewFunctionReturn(
-				"The map or array containing the newly assigned values",
-				MAP, ARRAY,
+				"The joined string",
+				STRING,
 			),
 		),
-		WithTags(FUNCTION_TAG_HIGHER_ORDER),
+		WithTags(FUNCTION_TAG_STRINGS, FUNCTION_TAG_ARRAYS),
 		WithExamples(
 			NewProgramExample(
-				`[1, "2"]`,
-				`//remap an array
-SET @out.result = map(@in, e ~> {
-	SET return = int(e.value) * 2
-})`,
-				`{"result": [2, 4]}`,
-			),
-			NewProgramExample(
-				`{"a": 1, "b": "2"}`,
-				`//remap a map
-SET @out.result = map(@in, e ~> {
-	SET return = int(e.value) * 2
-})`,
-				`{"result": {"a": 2, "b": 4}}`,
+				`{"tags": ["red", "green", "blue"]}`,
+				`SET @out = join(@in.tags, ", ")`,
+				`"red, green, blue"`,
 			),
 		),
 	)
 }
 
-func builtinMap(ctx context.Context, args ...*Object) *Object {
+func builtinJoin(ctx context.Context, args ...*Object) *Object {
 	if res, ok := IsArgCountEqual(2, args); !ok {
 		return res
 	}
-	arrowFn, err := args[1].AsArrowFunction()
+	separator, err := args[1].AsString()
 	if err != nil {
-		msg := fmt.Sprintf("invalid argument for map(): second argument must be a valid ARROWFUNC. got type of %s", args[1].Type())
-		return ObjectError(msg)
+		return ObjectError(err.Error())
 	}
-	switch args[0].Type() {
-	case string(MAP):
-		in, err := args[0].AsMap()
+	items := []string{}
+	for _, entry := range args[0].Elements() {
+		item, err := entry.AsString()
 		if err != nil {
-			msg := fmt.Sprintf("error calling map(): data issue with first argument of type %s", args[0].Type())
-			return ObjectError(msg)
-		}
-		ret := make(map[string]interface{})
-		keyList := []string{}
-		for k := range in {
-			keyList = append(keyList, k)
+			return ObjectError(err.Error())
 		}
-		slices.Sort(keyList)
-		for _, key := range keyList {
-			value := in[key]
-			input := make(map[string]interface{})
-			input["key"] = key
-			input["value"] = value
-			subEnv := arrowFn.Run(input)
-			if arrowFn.HasError() {
-				return arrowFn.errObj
-			}
-			m, ok := subEnv.(map[string]interface{})
-			if !ok {
-				return ObjectError("error calling map() arrow function: unable to extract return value from arrow function")
-			}
-			out, ok := m["return"]
-			if !ok {
-				ret[key] = value // if return is nil, simply use the existing entry
-				continue
-			}
-			ret[key] = out
+		items = append(items, item)
+	}
+	return CastString(strings.Join(items, separator))
+}
+
+// creates the entry of a function that changes a string without any other arguments, such as upper()
+func builtinStringChangeEntry(name string, description string, change func(s string) string, example ProgramExample) *FunctionEntry {
+	fn := func(ctx context.Context, args ...*Object) *Object {
+		if res, ok := IsArgCountEqual(1, args); !ok {
+			return res
 		}
-		return CastMap(ret)
-	case string(ARRAY):
-		in, err := args[0].AsArray()
+		target, err := args[0].AsString()
 		if err != nil {
-			msg := fmt.Sprintf("error calling map(): data issue with first argument of type %s", args[0].Type())
-			return ObjectError(msg)
+			return ObjectError(err.Error())
 		}
-		ret := []interface{}{}
-		for idx, entry := range in {
-			input := make(map[string]interface{})
-			input["index"] = int64(idx)
-			input["value"] = entry
-			subEnv := arrowFn.Run(input)
-			if arrowFn.HasError() {
-				return arrowFn.GetError()
-			}
-			m, ok := subEnv.(map[string]interface{})
-			if !ok {
-				return ObjectError("error calling map() arrow function: unable to extract return value from arrow function")
-			}
-			toAdd, ok := m["return"]
-			if !ok {
-				ret = append(ret, entry)
-				continue
-			}
-			ret = append(ret, toAdd)
+		return CastString(change(target))
+	}
+	return NewFunctionEntry(
+		name,
+		description,
+		fn,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The string to change",
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The changed string",
+				STRING,
+			),
+		),
+		WithTags(FUNCTION_TAG_STRINGS),
+		WithExamples(example),
+	)
+}
+
+func builtinUpperEntry() *FunctionEntry {
+	return builtinStringChangeEntry("upper", "Converts every letter of a string to upper case", strings.ToUpper,
+		NewProgramExample(`{"name": "Ünïcode ok"}`, `SET @out = upper(@in.name)`, `"ÜNÏCODE OK"`),
+	)
+}
+
+func builtinLowerEntry() *FunctionEntry {
+	return builtinStringChangeEntry("lower", "Converts every letter of a string to lower case", strings.ToLower,
+		NewProgramExample(`{"email": "Ada@Example.COM"}`, `SET @out = lower(@in.email)`, `"ada@example.com"`),
+	)
+}
+
+func builtinTitleEntry() *FunctionEntry {
+	return builtinStringChangeEntry("title", "Capitalizes the first letter of each word, where words are separated by whitespace. Other letters are left as they are", builtinTitleCase,
+		NewProgramExample(`{"name": "ada lovelace"}`, `SET @out = title(@in.name)`, `"Ada Lovelace"`),
+	)
+}
+
+func builtinTitleCase(s string) string {
+	var sb strings.Builder
+	wordStart := true
+	for _, char := range s {
+		if wordStart {
+			sb.WriteRune(unicode.ToTitle(char))
+		} else {
+			sb.WriteRune(char)
 		}
+		wordStart = unicode.IsSpace(char)
+	}
+	return sb.String()
+}
 
-		return CastArray(ret)
-	default:
-		msg := fmt.Sprintf("invalid argument for map(): first argument must be an ARRAY or MAP. got type of %s", args[0].Type())
-		return ObjectError(msg)
+// creates the entry of a trim function, which removes whitespace or the characters in an optional cutset
+func builtinTrimCutsetEntry(name string, description string, trimSpace func(s string) string, trimCutset func(s string, cutset string) string, example ProgramExample) *FunctionEntry {
+	fn := func(ctx context.Context, args ...*Object) *Object {
+		if res, ok := IsArgCountAtLeast(1, args); !ok {
+			return res
+		}
+		target, err := args[0].AsString()
+		if err != nil {
+			return ObjectError(err.Error())
+		}
+		if len(args) < 2 || args[1].Type() == string(NULL) {
+			return CastString(trimSpace(target))
+		}
+		cutset, err := args[1].AsString()
+		if err != nil {
+			return ObjectError(err.Error())
+		}
+		return CastString(trimCutset(target, cutset))
 	}
+	return NewFunctionEntry(
+		name,
+		description,
+		fn,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The string to trim",
+				STRING,
+			),
+			NewOptionalFunctionArg(
+				"characters",
+				"The characters to remove, in any order. Whitespace is removed if this is left out",
+				nil,
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The trimmed string",
+				STRING,
+			),
+		),
+		WithTags(FUNCTION_TAG_STRINGS),
+		WithExamples(example),
+	)
 }
-func builtinFilterEntry() *FunctionEntry {
+
+func builtinTrimEntry() *FunctionEntry {
+	return builtinTrimCutsetEntry("trim", "Removes whitespace, or the given characters, from both ends of a string", strings.TrimSpace, strings.Trim,
+		NewProgramExample(`{"name": "  ada  ", "code": "--x--"}`, `SET @out = [trim(@in.name), trim(@in.code, "-")]`, `["ada", "x"]`),
+	)
+}
+
+func builtinTrimLeftEntry() *FunctionEntry {
+	return builtinTrimCutsetEntry("trim_left", "Removes whitespace, or the given characters, from the start of a string",
+		func(s string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) }, strings.TrimLeft,
+		NewProgramExample(`{"id": "000123"}`, `SET @out = trim_left(@in.id, "0")`, `"123"`),
+	)
+}
+
+func builtinTrimRightEntry() *FunctionEntry {
+	return builtinTrimCutsetEntry("trim_right", "Removes whitespace, or the given characters, from the end of a string",
+		func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) }, strings.TrimRight,
+		NewProgramExample(`{"line": "done!!!\n"}`, `SET @out = trim_right(@in.line) | trim_right("!")`, `"done"`),
+	)
+}
+
+// creates the entry of a function that takes a string and another string to look for in it, such as starts_with()
+func builtinStringAffixEntry(name string, description string, argName string, argDescription string, returnType PublicType, returnDescription string, fn func(s string, affix string) *Object, example ProgramExample) *FunctionEntry {
+	call := func(ctx context.Context, args ...*Object) *Object {
+		if res, ok := IsArgCountEqual(2, args); !ok {
+			return res
+		}
+		target, err := args[0].AsString()
+		if err != nil {
+			return ObjectError(err.Error())
+		}
+		affix, err := args[1].AsString()
+		if err != nil {
+			return ObjectError(err.Error())
+		}
+		return fn(target, affix)
+	}
 	return NewFunctionEntry(
-		"filter",
-		"Iterates over each entry of the target (map or array), and either keep or remove the entry based on the 'return' key being set to true(keep) or false(remove) in the arrow function",
-		builtinFilter,
+		name,
+		description,
+		call,
 		WithArgs(
 			NewFunctionArg(
 				"target",
-				"The target array or map to iterate over",
-				MAP, ARRAY,
+				"The string to check",
+				STRING,
 			),
 			NewFunctionArg(
-				"filter_function",
-				`The filtering arrow function.
-The original entry value will either be kept (true) or discarded (false) based on the value of the "return" variable when the arrow function is finished.
-The orignal value can be accessed via the ".value" path from the named variable passed to the arrow function.
-For maps: The key name for an entry is accessible via the ".key" path from the named variable passed to the arrow function; you cannot reassign keys using this function.
-For arrays: The index number for an entry is accessible via the ".index" path from the named variable passed to the arrow function; you cannot reassign index numbers using this function.`,
-				ARROWFUNC,
+				argName,
+				argDescription,
+				STRING,
 			),
 		),
 		WithReturn(
 			NewFunctionReturn(
-				"The map or array containing the newly filtered values",
-				MAP, ARRAY,
+				returnDescription,
+				returnType,
 			),
 		),
-		WithTags(FUNCTION_TAG_HIGHER_ORDER),
+		WithTags(FUNCTION_TAG_STRINGS),
+		WithExamples(example),
+	)
+}
+
+func builtinTrimPrefixEntry() *FunctionEntry {
+	return builtinStringAffixEntry("trim_prefix", "Removes a prefix from the start of a string, if the string starts with it", "prefix", "The prefix to remove", STRING, "The string without the prefix",
+		func(s string, prefix string) *Object { return CastString(strings.TrimPrefix(s, prefix)) },
+		NewProgramExample(`{"url": "https://example.com"}`, `SET @out = trim_prefix(@in.url, "https://")`, `"example.com"`),
+	)
+}
+
+func builtinTrimSuffixEntry() *FunctionEntry {
+	return builtinStringAffixEntry("trim_suffix", "Removes a suffix from the end of a string, if the string ends with it", "suffix", "The suffix to remove", STRING, "The string without the suffix",
+		func(s string, suffix string) *Object { return CastString(strings.TrimSuffix(s, suffix)) },
+		NewProgramExample(`{"file": "report.csv"}`, `SET @out = trim_suffix(@in.file, ".csv")`, `"report"`),
+	)
+}
+
+func builtinStartsWithEntry() *FunctionEntry {
+	return builtinStringAffixEntry("starts_with", "Determines whether a string starts with a prefix", "prefix", "The prefix to check for", BOOLEAN, "Whether the string starts with the prefix",
+		func(s string, prefix string) *Object { return CastBool(strings.HasPrefix(s, prefix)) },
+		NewProgramExample(`{"path": "/api/users"}`, `SET @out = starts_with(@in.path, "/api/")`, `true`),
+	)
+}
+
+func builtinEndsWithEntry() *FunctionEntry {
+	return builtinStringAffixEntry("ends_with", "Determines whether a string ends with a suffix", "suffix", "The suffix to check for", BOOLEAN, "Whether the string ends with the suffix",
+		func(s string, suffix string) *Object { return CastBool(strings.HasSuffix(s, suffix)) },
+		NewProgramExample(`{"file": "report.csv"}`, `SET @out = ends_with(@in.file, ".json")`, `false`),
+	)
+}
+
+func builtinIndexOfEntry() *FunctionEntry {
+	return builtinStringAffixEntry("index_of", "Finds the first position of a substring within a string", "substring", "The substring to look for", INTEGER, "The position of the first character of the substring, counted in characters from 0, or -1 if the string does not contain it",
+		func(s string, substring string) *Object {
+			idx := strings.Index(s, substring)
+			if idx < 0 {
+				return CastInt(-1)
+			}
+			return CastInt(utf8.RuneCountInString(s[:idx]))
+		},
+		NewProgramExample(`{"name": "señor smith"}`, `SET @out = index_of(@in.name, "smith")`, `6`),
+	)
+}
+
+func builtinReplaceEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"replace",
+		"Replaces occurrences of a substring within a string",
+		builtinReplace,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The string to replace substrings in",
+				STRING,
+			),
+			NewFunctionArg(
+				"old",
+				"The substring to replace",
+				STRING,
+			),
+			NewFunctionArg(
+				"new",
+				"The replacement",
+				STRING,
+			),
+			NewOptionalFunctionArg(
+				"count",
+				"The number of occurrences to replace, starting from the beginning of the string. Every occurrence is replaced if this is left out or negative",
+				CastInt(-1),
+				INTEGER,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The string with the occurrences replaced",
+				STRING,
+			),
+		),
+		WithTags(FUNCTION_TAG_STRINGS),
 		WithExamples(
 			NewProgramExample(
-				`[1, "2"]`,
-				`//filter an array
-SET @out.result = filter(@in, e ~> {
-	SET return = int(e.value) % 2 == 0
-})`,
-				`{"result": ["2"]}`,
+				`{"phone": "555-123-4567"}`,
+				`SET @out = replace(@in.phone, "-", "")`,
+				`"5551234567"`,
 			),
 			NewProgramExample(
-				`{"a": 1, "b": "2"}`,
-				`//filter a map
-SET @out.result = filter(@in, e ~> {
-	SET return = int(e.value) % 2 == 0
-})`,
-				`{"result": {"b": "2"}}`,
+				`{"phone": "555-123-4567"}`,
+				`SET @out = replace(@in.phone, "-", " ", 1)`,
+				`"555 123-4567"`,
 			),
 		),
 	)
 }
 
-func builtinFilter(ctx context.Context, args ...*Object) *Object {
-	if res, ok := IsArgCountEqual(2, args); !ok {
+func builtinReplace(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(4, args); !ok {
 		return res
 	}
-	arrowFn, err := args[1].AsArrowFunction()
+	target, err := args[0].AsString()
 	if err != nil {
-		msg := fmt.Sprintf("filter() second argument must be a valid ARROWFUNC. got type of %s", args[1].Type())
-		return ObjectError(msg)
+		return ObjectError(err.Error())
+	}
+	old, err := args[1].AsString()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	replacement, err := args[2].AsString()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	count, err := args[3].AsInt()
+	if err != nil {
+		return ObjectError(err.Error())
 	}
+	if count < 0 {
+		count = -1
+	}
+	return CastString(strings.Replace(target, old, replacement, int(count)))
+}
 
-	switch args[0].Type() {
-	case string(MAP):
-		in, err := args[0].AsMap()
+func builtinSubstringEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"substring",
+		"Gets part of a string, by the positions of its characters",
+		builtinSubstring,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The string to get part of",
+				STRING,
+			),
+			NewFunctionArg(
+				"start",
+				"The position of the first character to include, counted from 0",
+				INTEGER,
+			),
+			NewOptionalFunctionArg(
+				"end",
+				"The position after the last character to include. Goes to the end of the string if this is left out. Positions past the end of the string are treated as the end of the string",
+				nil,
+				INTEGER,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The characters from start up to, but not including, end",
+				STRING,
+			),
+		),
+		WithTags(FUNCTION_TAG_STRINGS),
+		WithExamples(
+			NewProgramExample(
+				`{"code": "ABC-12345"}`,
+				`SET @out.prefix = substring(@in.code, 0, 3)
+SET @out.number = substring(@in.code, 4)`,
+				`{"prefix": "ABC", "number": "12345"}`,
+			),
+			NewProgramExample(
+				`{"word": "naïve"}`,
+				`SET @out = substring(@in.word, 2, 3)`,
+				`"ï"`,
+			),
+		),
+	)
+}
+
+func builtinSubstring(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountAtLeast(2, args); !ok {
+		return res
+	}
+	target, err := args[0].AsString()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	runes := []rune(target)
+	start, err := args[1].AsInt()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	end := int64(len(runes))
+	if len(args) > 2 && args[2].Type() != string(NULL) {
+		end, err = args[2].AsInt()
 		if err != nil {
-			msg := fmt.Sprintf("filter() input data argument issue. type is not compatible with map operation: %s", args[0].Type())
-			return ObjectError(msg)
+			return ObjectError(err.Error())
 		}
-		keyList := []string{}
-		for k := range in {
-			keyList = append(keyList, k)
+	}
+	if start < 0 || end < 0 {
+		msg := fmt.Sprintf("substring() positions cannot be negative. got start=%d end=%d", start, end)
+		return ObjectError(msg)
+	}
+	if start > end && end < int64(len(runes)) {
+		msg := fmt.Sprintf("substring() start cannot be after end. got start=%d end=%d", start, end)
+		return ObjectError(msg)
+	}
+	start = min(start, int64(len(runes)))
+	end = min(end, int64(len(runes)))
+	return CastString(string(runes[start:end]))
+}
+
+// the longest string that repeat() and the pad functions will build, so that a bad count can't exhaust memory
+const maxBuiltinStringBytes = 64 << 20
+
+// creates the entry of a pad function, which adds padding to one side of a string until it has the desired number of characters
+func builtinPadEntry(name string, description string, left bool, example ProgramExample) *FunctionEntry {
+	fn := func(ctx context.Context, args ...*Object) *Object {
+		if res, ok := IsArgCountEqual(3, args); !ok {
+			return res
 		}
-		slices.Sort(keyList)
-		ret := make(map[string]interface{})
-		for _, key := range keyList {
-			value := in[key]
-			input := make(map[string]interface{})
-			input["key"] = key
-			input["value"] = value
-			subEnv := arrowFn.Run(input)
-			if arrowFn.HasError() {
-				return arrowFn.errObj
-			}
-			m, ok := subEnv.(map[string]interface{})
-			if !ok {
-				return ObjectError("filter() unable to extract return value from arrow function")
-			}
-			out, ok := m["return"]
-			if !ok {
-				continue
-			}
-			if resBool, ok := out.(bool); ok {
-				if resBool {
-					ret[key] = value
-				}
-			}
+		target, err := args[0].AsString()
+		if err != nil {
+			return ObjectError(err.Error())
 		}
-		return CastMap(ret)
-	case string(ARRAY):
-		in, err := args[0].AsArray()
+		length, err := args[1].AsInt()
 		if err != nil {
-			msg := fmt.Sprintf("filter() input data argument issue. type is not compatible with array operation: %s", args[0].Type())
-			return ObjectError(msg)
+			return ObjectError(err.Error())
 		}
-		ret := []interface{}{}
-		for idx, entry := range in {
-			input := make(map[string]interface{})
-			input["index"] = int64(idx)
-			input["value"] = entry
-			subEnv := arrowFn.Run(input)
-			if arrowFn.HasError() {
-				return arrowFn.GetError()
-			}
-			m, ok := subEnv.(map[string]interface{})
-			if !ok {
-				return ObjectError("filter() unable to extract return value from arrow function")
-			}
-			out, ok := m["return"]
-			if !ok {
-				continue
-			}
-			if resBool, ok := out.(bool); ok {
-				if resBool {
-					ret = append(ret, entry)
-				}
-			}
+		padding, err := args[2].AsString()
+		if err != nil {
+			return ObjectError(err.Error())
 		}
-		return CastAuto(ret)
-	default:
-		msg := fmt.Sprintf("invalid argument for filter(): first argument must be an ARRAY or MAP. got type of %s", args[0].Type())
-		return ObjectError(msg)
+		if len(padding) == 0 {
+			return ObjectError(fmt.Sprintf("%s() padding cannot be empty", name))
+		}
+		missing := length - int64(utf8.RuneCountInString(target))
+		if missing <= 0 {
+			return CastString(target)
+		}
+		if missing > maxBuiltinStringBytes {
+			return ObjectError(fmt.Sprintf("%s() length is too large. got=%d", name, length))
+		}
+		paddingRunes := []rune(padding)
+		padRunes := []rune(strings.Repeat(padding, int(missing)/len(paddingRunes)+1))[:missing]
+		if left {
+			return CastString(string(padRunes) + target)
+		}
+		return CastString(target + string(padRunes))
 	}
-}
-func builtinReduceEntry() *FunctionEntry {
 	return NewFunctionEntry(
-		"reduce",
-		"Iterates over each entry of the target (map or array), and updates the accumulator based on the 'return' key being set in the arrow function",
-		builtinReduce,
+		name,
+		description,
+		fn,
 		WithArgs(
 			NewFunctionArg(
 				"target",
-				"The target array or map to iterate over",
-				MAP, ARRAY,
+				"The string to pad",
+				STRING,
 			),
 			NewFunctionArg(
-				"accumulator",
-				"The value that will eventually be returned by the function after being updated each iteration",
-				BASIC...,
+				"length",
+				"The number of characters the result should have. Strings that are already this long are returned as they are",
+				INTEGER,
 			),
-			NewFunctionArg(
-				"reduce_function",
+			NewOptionalFunctionArg(
+				"padding",
+				"The characters to pad with, which are repeated as needed",
+				CastString(" "),
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The padded string",
+				STRING,
+			),
+		),
+		WithTags(FUNCTION_TAG_STRINGS),
+		WithExamples(example),
+	)
+}
+
+func builtinPadLeftEntry() *FunctionEntry {
+	return builtinPadEntry("pad_left", "Adds padding to the start of a string until it has the desired number of characters", true,
+		NewProgramExample(`{"id": "42"}`, `SET @out = pad_left(@in.id, 6, "0")`, `"000042"`),
+	)
+}
+
+func builtinPadRightEntry() *FunctionEntry {
+	return builtinPadEntry("pad_right", "Adds padding to the end of a string until it has the desired number of characters", false,
+		NewProgramExample(`{"name": "café"}`, `SET @out = pad_right(@in.name, 8, ".") + "|"`, `"café....|"`),
+	)
+}
+
+func builtinRepeatEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"repeat",
+		"Repeats a string a number of times",
+		builtinRepeat,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The string to repeat",
+				STRING,
+			),
+			NewFunctionArg(
+				"count",
+				"The number of times to repeat the string, which cannot be negative",
+				INTEGER,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The repeated string",
+				STRING,
+			),
+		),
+		WithTags(FUNCTION_TAG_STRINGS),
+		WithExamples(
+			NewProgramExample(
+				`null`,
+				`SET @out = repeat("ab", 3)`,
+				`"ababab"`,
+			),
+		),
+	)
+}
+
+func builtinRepeat(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	target, err := args[0].AsString()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	count, err := args[1].AsInt()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	if count < 0 {
+		msg := fmt.Sprintf("repeat() count cannot be negative. got=%d", count)
+		return ObjectError(msg)
+	}
+	if len(target) > 0 && count > maxBuiltinStringBytes/int64(len(target)) {
+		msg := fmt.Sprintf("repeat() result is too large. got count=%d", count)
+		return ObjectError(msg)
+	}
+	return CastString(strings.Repeat(target, int(count)))
+}
+
+func builtinAppendEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"append",
+		"Determines whether a parent item contains specified contents",
+		builtinAppend,
+		WithArgs(
+			NewFunctionArg(
+				"array",
+				"the target array to add an item to",
+				ARRAY,
+			),
+			NewFunctionArg(
+				"item",
+				"the item to add to the target array",
+				BASIC_WITHOUT_ERROR...,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"Whether the contents exist within the parent",
+				ARRAY,
+			),
+		),
+		WithTags(FUNCTION_TAG_ARRAYS),
+		WithExamples(
+			NewProgramExample(
+				`[1, 2]`,
+				`SET @out.result = append(@in, 3)`,
+				`{"result": [1, 2, 3]}`,
+			),
+		),
+	)
+}
+
+func builtinAppend(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	arr, err := args[0].AsArray()
+	if err != nil {
+		msg := fmt.Sprintf("append() invalid array argument of type %s", args[0].Type())
+		return ObjectError(msg)
+	}
+	toAdd, err := args[1].AsAny()
+	if err != nil {
+		msg := fmt.Sprintf("append() invalid second argument of type %s", args[1].Type())
+		return ObjectError(msg)
+	}
+	arr = append(arr, toAdd)
+	return CastArray(arr)
+}
+func builtinGetPathEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"get_path",
+		`Gets the value at a path held in a string, using the same path syntax as programs, such as "a.b[2].c".
+Missing keys resolve to NULL, just like they do in programs.`,
+		builtinGetPath,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The item to read the value from",
+				BASIC_WITHOUT_ERROR...,
+			),
+			NewFunctionArg(
+				"path",
+				`The path of the value, relative to the target. an empty path refers to the target itself`,
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The value at the path, or NULL if the path does not exist. Throws an error if the path tries to index a non-array, or access a key on a non-map",
+				BASIC_WITHOUT_ERROR...,
+			),
+		),
+		WithTags(FUNCTION_TAG_MAPS, FUNCTION_TAG_ARRAYS),
+		WithExamples(
+			NewProgramExample(
+				`{"user": {"emails": ["a@example.com", "b@example.com"]}, "field": "user.emails[1]"}`,
+				`SET @out.result = get_path(@in, @in.field)`,
+				`{"result": "b@example.com"}`,
+			),
+			NewProgramExample(
+				`{"user": {"name": "ada"}}`,
+				`SET @out.result = get_path(@in, "user.address.city")`,
+				`{"result": null}`,
+			),
+		),
+	)
+}
+
+func builtinGetPath(ctx context.Context, args ...*Object) *Object {
+	return builtinPathGet("get_path", parsePath, args)
+}
+
+func builtinGetPointerEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"get_pointer",
+		`Gets the value at a JSON Pointer (RFC 6901), such as "/a/b/2/c".
+Missing keys resolve to NULL, just like they do in programs.`,
+		builtinGetPointer,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The item to read the value from",
+				BASIC_WITHOUT_ERROR...,
+			),
+			NewFunctionArg(
+				"pointer",
+				`The JSON Pointer of the value, relative to the target. an empty pointer refers to the target itself`,
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The value at the pointer, or NULL if it does not exist. Throws an error if the pointer tries to index a non-array, or access a key on a non-map",
+				BASIC_WITHOUT_ERROR...,
+			),
+		),
+		WithTags(FUNCTION_TAG_MAPS, FUNCTION_TAG_ARRAYS),
+		WithExamples(
+			NewProgramExample(
+				`{"user": {"emails": ["a@example.com", "b@example.com"]}}`,
+				`SET @out.result = get_pointer(@in, "/user/emails/1")`,
+				`{"result": "b@example.com"}`,
+			),
+		),
+	)
+}
+
+func builtinGetPointer(ctx context.Context, args ...*Object) *Object {
+	return builtinPathGet("get_pointer", parseJSONPointer, args)
+}
+
+func builtinPathGet(name string, parse func(string) ([]pathStep, error), args []*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	steps, errObj := builtinPathSteps(name, parse, args[1])
+	if errObj != nil {
+		return errObj
+	}
+	ret, err := getPath(args[0].inner, steps)
+	if err != nil {
+		return ObjectError(fmt.Sprintf("%s() %s", name, err.Error()))
+	}
+	return &Object{inner: ret}
+}
+
+func builtinSetPathEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"set_path",
+		`Sets the value at a path held in a string, using the same path syntax as programs, such as "a.b[2].c".
+Missing maps along the path are created, just like SET does. Existing array entries can be replaced, but arrays are never grown.`,
+		builtinSetPath,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The item to set the value in. It is not changed; a changed copy is returned instead. NULL is treated as an empty map",
+				MAP, ARRAY, NULL,
+			),
+			NewFunctionArg(
+				"path",
+				"The path to set, relative to the target",
+				STRING,
+			),
+			NewFunctionArg(
+				"value",
+				"The value to set",
+				BASIC_WITHOUT_ERROR...,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"A copy of the target with the value set",
+				MAP, ARRAY,
+			),
+		),
+		WithTags(FUNCTION_TAG_MAPS, FUNCTION_TAG_ARRAYS),
+		WithExamples(
+			NewProgramExample(
+				`{"field": "user.address.city"}`,
+				`SET @out = set_path(@out, @in.field, "Boston")`,
+				`{"user": {"address": {"city": "Boston"}}}`,
+			),
+			NewProgramExample(
+				`{"tags": ["a", "b"]}`,
+				`SET @out = set_path(@in, "tags[0]", "z")`,
+				`{"tags": ["z", "b"]}`,
+			),
+		),
+	)
+}
+
+func builtinSetPath(ctx context.Context, args ...*Object) *Object {
+	return builtinPathSet("set_path", parsePath, args)
+}
+
+func builtinSetPointerEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"set_pointer",
+		`Sets the value at a JSON Pointer (RFC 6901), such as "/a/b/2/c".
+Missing maps along the pointer are created, just like SET does. Existing array entries can be replaced, but arrays are never grown.`,
+		builtinSetPointer,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The item to set the value in. It is not changed; a changed copy is returned instead. NULL is treated as an empty map",
+				MAP, ARRAY, NULL,
+			),
+			NewFunctionArg(
+				"pointer",
+				"The JSON Pointer to set, relative to the target",
+				STRING,
+			),
+			NewFunctionArg(
+				"value",
+				"The value to set",
+				BASIC_WITHOUT_ERROR...,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"A copy of the target with the value set",
+				MAP, ARRAY,
+			),
+		),
+		WithTags(FUNCTION_TAG_MAPS, FUNCTION_TAG_ARRAYS),
+		WithExamples(
+			NewProgramExample(
+				`{"a/b": {"items": [1, 2]}}`,
+				`SET @out = set_pointer(@in, "/a~1b/items/1", 5)`,
+				`{"a/b": {"items": [1, 5]}}`,
+			),
+		),
+	)
+}
+
+func builtinSetPointer(ctx context.Context, args ...*Object) *Object {
+	return builtinPathSet("set_pointer", parseJSONPointer, args)
+}
+
+func builtinPathSet(name string, parse func(string) ([]pathStep, error), args []*Object) *Object {
+	if res, ok := IsArgCountEqual(3, args); !ok {
+		return res
+	}
+	steps, errObj := builtinPathSteps(name, parse, args[1])
+	if errObj != nil {
+		return errObj
+	}
+	var target object = newObjectMap()
+	if args[0].inner != obj_global_null {
+		target = args[0].inner.clone()
+	}
+	if err := setPath(target, steps, args[2].inner.clone()); err != nil {
+		return ObjectError(fmt.Sprintf("%s() %s", name, err.Error()))
+	}
+	return &Object{inner: target}
+}
+
+func builtinDelPathEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"del_path",
+		`Removes the value at a path held in a string, using the same path syntax as programs, such as "a.b[2].c".
+Removing an array entry shifts the entries after it down. Paths that do not exist are ignored.`,
+		builtinDelPath,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The item to remove the value from. It is not changed; a changed copy is returned instead",
+				MAP, ARRAY, NULL,
+			),
+			NewFunctionArg(
+				"path",
+				"The path to remove, relative to the target",
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"A copy of the target without the value",
+				MAP, ARRAY, NULL,
+			),
+		),
+		WithTags(FUNCTION_TAG_MAPS, FUNCTION_TAG_ARRAYS),
+		WithExamples(
+			NewProgramExample(
+				`{"user": {"name": "ada", "password": "hunter2"}}`,
+				`SET @out = del_path(@in, "user.password")`,
+				`{"user": {"name": "ada"}}`,
+			),
+			NewProgramExample(
+				`{"tags": ["a", "b", "c"]}`,
+				`SET @out = del_path(@in, "tags[1]")`,
+				`{"tags": ["a", "c"]}`,
+			),
+		),
+	)
+}
+
+func builtinDelPath(ctx context.Context, args ...*Object) *Object {
+	return builtinPathDel("del_path", parsePath, args)
+}
+
+func builtinDelPointerEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"del_pointer",
+		`Removes the value at a JSON Pointer (RFC 6901), such as "/a/b/2/c".
+Removing an array entry shifts the entries after it down. Pointers that do not exist are ignored.`,
+		builtinDelPointer,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The item to remove the value from. It is not changed; a changed copy is returned instead",
+				MAP, ARRAY, NULL,
+			),
+			NewFunctionArg(
+				"pointer",
+				"The JSON Pointer to remove, relative to the target",
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"A copy of the target without the value",
+				MAP, ARRAY, NULL,
+			),
+		),
+		WithTags(FUNCTION_TAG_MAPS, FUNCTION_TAG_ARRAYS),
+		WithExamples(
+			NewProgramExample(
+				`{"user": {"name": "ada", "password": "hunter2"}}`,
+				`SET @out = del_pointer(@in, "/user/password")`,
+				`{"user": {"name": "ada"}}`,
+			),
+		),
+	)
+}
+
+func builtinDelPointer(ctx context.Context, args ...*Object) *Object {
+	return builtinPathDel("del_pointer", parseJSONPointer, args)
+}
+
+func builtinPathDel(name string, parse func(string) ([]pathStep, error), args []*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	steps, errObj := builtinPathSteps(name, parse, args[1])
+	if errObj != nil {
+		return errObj
+	}
+	target := args[0].inner.clone()
+	if _, err := delPath(target, steps); err != nil {
+		return ObjectError(fmt.Sprintf("%s() %s", name, err.Error()))
+	}
+	return &Object{inner: target}
+}
+
+func builtinHasPathEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"has_path",
+		`Determines whether a path held in a string exists, using the same path syntax as programs, such as "a.b[2].c".
+Keys that are set to NULL exist.`,
+		builtinHasPath,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The item to check",
+				BASIC_WITHOUT_ERROR...,
+			),
+			NewFunctionArg(
+				"path",
+				"The path to check for, relative to the target",
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"Whether the path exists. Paths that index a non-array, or access a key on a non-map, do not exist",
+				BOOLEAN,
+			),
+		),
+		WithTags(FUNCTION_TAG_MAPS, FUNCTION_TAG_ARRAYS),
+		WithExamples(
+			NewProgramExample(
+				`{"user": {"nickname": null, "emails": ["a@example.com"]}}`,
+				`SET @out.nickname = has_path(@in, "user.nickname")
+SET @out.second_email = has_path(@in, "user.emails[1]")`,
+				`{"nickname": true, "second_email": false}`,
+			),
+		),
+	)
+}
+
+func builtinHasPath(ctx context.Context, args ...*Object) *Object {
+	return builtinPathHas("has_path", parsePath, args)
+}
+
+func builtinHasPointerEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"has_pointer",
+		`Determines whether a JSON Pointer (RFC 6901), such as "/a/b/2/c", exists.
+Keys that are set to NULL exist.`,
+		builtinHasPointer,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The item to check",
+				BASIC_WITHOUT_ERROR...,
+			),
+			NewFunctionArg(
+				"pointer",
+				"The JSON Pointer to check for, relative to the target",
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"Whether the pointer exists. Pointers that index a non-array, or access a key on a non-map, do not exist",
+				BOOLEAN,
+			),
+		),
+		WithTags(FUNCTION_TAG_MAPS, FUNCTION_TAG_ARRAYS),
+		WithExamples(
+			NewProgramExample(
+				`{"user": {"emails": ["a@example.com"]}}`,
+				`SET @out.result = has_pointer(@in, "/user/emails/0")`,
+				`{"result": true}`,
+			),
+		),
+	)
+}
+
+func builtinHasPointer(ctx context.Context, args ...*Object) *Object {
+	return builtinPathHas("has_pointer", parseJSONPointer, args)
+}
+
+func builtinPathHas(name string, parse func(string) ([]pathStep, error), args []*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	steps, errObj := builtinPathSteps(name, parse, args[1])
+	if errObj != nil {
+		return errObj
+	}
+	return CastBool(hasPath(args[0].inner, steps))
+}
+
+func builtinPathSteps(name string, parse func(string) ([]pathStep, error), pathArg *Object) ([]pathStep, *Object) {
+	path, err := pathArg.AsString()
+	if err != nil {
+		return nil, ObjectError(fmt.Sprintf("%s() invalid path argument of type %s", name, pathArg.Type()))
+	}
+	steps, err := parse(path)
+	if err != nil {
+		return nil, ObjectError(fmt.Sprintf("%s() %s", name, err.Error()))
+	}
+	return steps, nil
+}
+
+func builtinMapEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"map",
+		"Iterates over each entry of the target array, and remaps its value based on the 'return' key set in the arrow function",
+		builtinMap,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The target array to iterate over",
+				ARRAY,
+			),
+			NewFunctionArg(
+				"map_function",
+				`The re-mapping arrow function.
+The original entry value will be replaced by whatever value is in the "return" variable when the arrow function is finished.
+The orignal value can be accessed via the ".value" path from the named variable passed to the arrow function.
+The index number for an entry is accessible via the ".index" path from the named variable passed to the arrow function; you cannot reassign index numbers using this function.`,
+				ARROWFUNC,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The array containing the newly assigned values",
+				ARRAY,
+			),
+		),
+		WithTags(FUNCTION_TAG_HIGHER_ORDER),
+		WithExamples(
+			NewProgramExample(
+				`[1, "2"]`,
+				`//remap an array
+SET @out.result = map(@in, e ~> {
+	SET return = int(e.value) * 2
+})`,
+				`{"result": [2, 4]}`,
+			),
+		),
+	)
+}
+
+func builtinMap(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	arrowFn, err := args[1].AsArrowFunction()
+	if err != nil {
+		msg := fmt.Sprintf("invalid argument for map(): second argument must be a valid ARROWFUNC. got type of %s", args[1].Type())
+		return ObjectError(msg)
+	}
+	if args[0].Type() != string(ARRAY) {
+		msg := fmt.Sprintf("error calling map(): data issue with first argument of type %s", args[0].Type())
+		return ObjectError(msg)
+	}
+	ret := &objectArray{entries: []object{}}
+	for idx, entry := range args[0].Elements() {
+		input := make(map[string]interface{})
+		input["index"] = int64(idx)
+		input["value"] = entry.Clone()
+		subEnv := arrowFn.runObjects(input)
+		if arrowFn.HasError() {
+			return arrowFn.GetError()
+		}
+		toAdd, ok := subEnv["return"]
+		if !ok {
+			ret.entries = append(ret.entries, entry.inner.clone())
+			continue
+		}
+		ret.entries = append(ret.entries, toAdd)
+	}
+	return &Object{inner: ret}
+}
+
+func builtinMapMapEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"map",
+		"Iterates over each entry of the target map, and remaps its value based on the 'return' key set in the arrow function",
+		builtinMapMap,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The target map to iterate over",
+				MAP,
+			),
+			NewFunctionArg(
+				"map_function",
+				`The re-mapping arrow function.
+The original entry value will be replaced by whatever value is in the "return" variable when the arrow function is finished.
+The orignal value can be accessed via the ".value" path from the named variable passed to the arrow function.
+The key name for an entry is accessible via the ".key" path from the named variable passed to the arrow function; you cannot reassign keys using this function.`,
+				ARROWFUNC,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The map containing the newly assigned values",
+				MAP,
+			),
+		),
+		WithTags(FUNCTION_TAG_HIGHER_ORDER),
+		WithExamples(
+			NewProgramExample(
+				`{"a": 1, "b": "2"}`,
+				`//remap a map
+SET @out.result = map(@in, e ~> {
+	SET return = int(e.value) * 2
+})`,
+				`{"result": {"a": 2, "b": 4}}`,
+			),
+		),
+	)
+}
+
+func builtinMapMap(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	arrowFn, err := args[1].AsArrowFunction()
+	if err != nil {
+		msg := fmt.Sprintf("invalid argument for map(): second argument must be a valid ARROWFUNC. got type of %s", args[1].Type())
+		return ObjectError(msg)
+	}
+	if args[0].Type() != string(MAP) {
+		msg := fmt.Sprintf("error calling map(): data issue with first argument of type %s", args[0].Type())
+		return ObjectError(msg)
+	}
+	ret := newObjectMap()
+	for key, value := range args[0].All() {
+		input := make(map[string]interface{})
+		input["key"] = key
+		input["value"] = value.Clone()
+		subEnv := arrowFn.runObjects(input)
+		if arrowFn.HasError() {
+			return arrowFn.errObj
+		}
+		out, ok := subEnv["return"]
+		if !ok {
+			ret.set(key, value.inner.clone()) // if return is nil, simply use the existing entry
+			continue
+		}
+		ret.set(key, out)
+	}
+	return &Object{inner: ret}
+}
+
+func builtinFilterEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"filter",
+		"Iterates over each entry of the target (map or array), and either keep or remove the entry based on the 'return' key being set to true(keep) or false(remove) in the arrow function",
+		builtinFilter,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The target array or map to iterate over",
+				MAP, ARRAY,
+			),
+			NewFunctionArg(
+				"filter_function",
+				`The filtering arrow function.
+The original entry value will either be kept (true) or discarded (false) based on the value of the "return" variable when the arrow function is finished.
+The orignal value can be accessed via the ".value" path from the named variable passed to the arrow function.
+For maps: The key name for an entry is accessible via the ".key" path from the named variable passed to the arrow function; you cannot reassign keys using this function.
+For arrays: The index number for an entry is accessible via the ".index" path from the named variable passed to the arrow function; you cannot reassign index numbers using this function.`,
+				ARROWFUNC,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The map or array containing the newly filtered values",
+				MAP, ARRAY,
+			),
+		),
+		WithTags(FUNCTION_TAG_HIGHER_ORDER),
+		WithExamples(
+			NewProgramExample(
+				`[1, "2"]`,
+				`//filter an array
+SET @out.result = filter(@in, e ~> {
+	SET return = int(e.value) % 2 == 0
+})`,
+				`{"result": ["2"]}`,
+			),
+			NewProgramExample(
+				`{"a": 1, "b": "2"}`,
+				`//filter a map
+SET @out.result = filter(@in, e ~> {
+	SET return = int(e.value) % 2 == 0
+})`,
+				`{"result": {"b": "2"}}`,
+			),
+		),
+	)
+}
+
+func builtinFilter(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	arrowFn, err := args[1].AsArrowFunction()
+	if err != nil {
+		msg := fmt.Sprintf("filter() second argument must be a valid ARROWFUNC. got type of %s", args[1].Type())
+		return ObjectError(msg)
+	}
+
+	switch args[0].Type() {
+	case string(MAP):
+		ret := newObjectMap()
+		for key, value := range args[0].All() {
+			input := make(map[string]interface{})
+			input["key"] = key
+			input["value"] = value.Clone()
+			subEnv := arrowFn.runObjects(input)
+			if arrowFn.HasError() {
+				return arrowFn.errObj
+			}
+			out, ok := subEnv["return"]
+			if !ok {
+				continue
+			}
+			if resBool, ok := out.(*objectBoolean); ok {
+				if resBool.value {
+					ret.set(key, value.inner.clone())
+				}
+			}
+		}
+		return &Object{inner: ret}
+	case string(ARRAY):
+		ret := &objectArray{entries: []object{}}
+		for idx, entry := range args[0].Elements() {
+			input := make(map[string]interface{})
+			input["index"] = int64(idx)
+			input["value"] = entry.Clone()
+			subEnv := arrowFn.runObjects(input)
+			if arrowFn.HasError() {
+				return arrowFn.GetError()
+			}
+			out, ok := subEnv["return"]
+			if !ok {
+				continue
+			}
+			if resBool, ok := out.(*objectBoolean); ok {
+				if resBool.value {
+					ret.entries = append(ret.entries, entry.inner.clone())
+				}
+			}
+		}
+		return &Object{inner: ret}
+	default:
+		msg := fmt.Sprintf("invalid argument for filter(): first argument must be an ARRAY or MAP. got type of %s", args[0].Type())
+		return ObjectError(msg)
+	}
+}
+func builtinReduceEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"reduce",
+		"Iterates over each entry of the target (map or array), and updates the accumulator based on the 'return' key being set in the arrow function",
+		builtinReduce,
+		WithArgs(
+			NewFunctionArg(
+				"target",
+				"The target array or map to iterate over",
+				MAP, ARRAY,
+			),
+			NewFunctionArg(
+				"accumulator",
+				"The value that will eventually be returned by the function after being updated each iteration",
+				BASIC...,
+			),
+			NewFunctionArg(
+				"reduce_function",
 				`The reducer arrow function.
 The accumulator will be updated based to the value of the "return" variable when the arrow function is finished.
 The accumulator can be accessed via the ".current" path from the named variable passed to the arrow function.
@@ -1166,313 +2456,1318 @@ For maps: The key name for an entry is accessible via the ".key" path from the n
 For arrays: The index number for an entry is accessible via the ".index" path from the named variable passed to the arrow function; you cannot reassign index numbers using this function.`,
 				ARROWFUNC,
 			),
-		),
-		WithReturn(
-			NewFunctionReturn(
-				"The map or array containing the newly filtered values",
-				BASIC...,
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The map or array containing the newly filtered values",
+				BASIC...,
+			),
+		),
+		WithTags(FUNCTION_TAG_HIGHER_ORDER),
+		WithExamples(
+			NewProgramExample(
+				`[1, "2", "buckle my shoe"]`,
+				`//reduce an array of numbers to sum its contents
+SET @out.result = reduce(@in, 0, e ~> {
+	SET cleaned_number = int(e.value) | catch(0) // these variables are ONLY set locally to the arrow function environment.
+	SET return = e.current + cleaned_number
+})`,
+				`{"result": 3}`,
+			),
+			NewProgramExample(
+				`{"a": 1, "b": "2"}`,
+				`//reduce a map to sum the values.
+SET @out.result = reduce(@in, NULL, e ~> {
+	IF e.current == NULL :: SET e.current = 0
+	SET cleaned_number = int(e.value) | catch(0)
+	SET return = e.current + cleaned_number
+})`,
+				`{"result": 3}`,
+			),
+		),
+	)
+}
+
+func builtinReduce(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(3, args); !ok {
+		return res
+	}
+	arrowFn, err := args[2].AsArrowFunction()
+	if err != nil {
+		msg := fmt.Sprintf("reduce() third argument must be a valid ARROWFUNC. got type of %s", args[2].Type())
+		return ObjectError(msg)
+	}
+
+	switch args[0].Type() {
+	case string(MAP):
+		ret := args[1].Clone()
+		for key, value := range args[0].All() {
+			input := make(map[string]interface{})
+			input["key"] = key
+			input["value"] = value.Clone()
+			input["current"] = ret
+			subEnv := arrowFn.runObjects(input)
+			if arrowFn.HasError() {
+				return arrowFn.GetError()
+			}
+			if out, ok := subEnv["return"]; ok {
+				ret = &Object{inner: out}
+			}
+		}
+		return ret
+	case string(ARRAY):
+		ret := args[1].Clone()
+		for idx, entry := range args[0].Elements() {
+			input := make(map[string]interface{})
+			input["value"] = entry.Clone()
+			input["index"] = int64(idx)
+			input["current"] = ret
+			subEnv := arrowFn.runObjects(input)
+			if arrowFn.HasError() {
+				return arrowFn.GetError()
+			}
+			if out, ok := subEnv["return"]; ok {
+				ret = &Object{inner: out}
+			}
+		}
+		return ret
+	default:
+		msg := fmt.Sprintf("invalid argument for reduce(): first argument must be an ARRAY or MAP. got type of %s", args[0].Type())
+		return ObjectError(msg)
+	}
+}
+func builtinNowEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"now",
+		"Gets the current time",
+		builtinNow,
+		WithReturn(
+			NewFunctionReturn(
+				"An object representing the current time",
+				TIME,
+			),
+		),
+		WithTags(FUNCTION_TAG_TIME),
+		WithCapabilities(FUNCTION_CAPABILITY_NONDETERMINISTIC, FUNCTION_CAPABILITY_CLOCK),
+		WithExamples(
+			NewProgramExample(
+				`null`,
+				`SET current_time = now()`,
+				`null`,
+			),
+		),
+	)
+}
+
+func builtinNow(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(0, args); !ok {
+		return res
+	}
+	return CastTime(ClockFromContext(ctx).Now().UTC())
+}
+func builtinParseTimeEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"parse_time",
+		"Parses a string into the desired time format",
+		builtinParseTime,
+		WithArgs(
+			NewFunctionArg(
+				"input_time",
+				`The time to parse into a time object. Can be a STRING, INTEGER, or FLOAT depending on the format string`,
+				STRING, INTEGER, FLOAT,
+			),
+			NewFunctionArg(
+				"format",
+				`The specified format of the input time. Can be any of the following.
+"rfc_3339": input time must be a STRING
+"rfc_3339_nano": input time must be a STRING
+"unix": input time must be an INTEGER or FLOAT representing UNIX time in seconds, or the equivalent string representation.
+"unix_milli": input time must be an INTEGER representing UNIX time in milliseconds, or the equivalent string representation.
+"unix_micro": input time must be an INTEGER representing UNIX time in microseconds, or the equivalent string representation.
+"unix_nano": input time must be an INTEGER representing UNIX time in nanoseconds, or the equivalent string representation.
+Arbitrary format strings reflect how the time equivalent of "Mon Jan 2 15:04:05 -0700 MST 2006" would be represented in your desired format.
+`,
+				STRING,
+			),
+			NewOptionalFunctionArg(
+				"tz",
+				`The IANA time zone name, such as "America/New_York", used for arbitrary formats that do not include a UTC offset. UNIX times are converted to this time zone`,
+				CastString("UTC"),
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The map or array containing the newly filtered values",
+				TIME,
+			),
+		),
+		WithTags(FUNCTION_TAG_TIME),
+		WithExamples(
+			NewProgramExample(
+				`{"float": 1759782264.0}`,
+				`SET @out = parse_time(@in.float, "unix")`,
+				`"2025-10-06T20:24:24Z"`,
+			),
+			NewProgramExample(
+				`{"int": 1759782264}`,
+				`SET @out = parse_time(@in.int, "unix")`,
+				`"2025-10-06T20:24:24Z"`,
+			),
+			NewProgramExample(
+				`{"string": "1759782264.0"}`,
+				`SET @out = parse_time(@in.string, "unix")`,
+				`"2025-10-06T20:24:24Z"`,
+			),
+			NewProgramExample(
+				`{"milli": "1257894000000"}`,
+				`SET @out = parse_time(@in.milli, "unix_milli")`,
+				`"2009-11-10T23:00:00Z"`,
+			),
+			NewProgramExample(
+				`{"micro": "1257894000000000"}`,
+				`SET @out = parse_time(@in.micro, "unix_micro")`,
+				`"2009-11-10T23:00:00Z"`,
+			),
+			NewProgramExample(
+				`{"nano": "1257894000000000000"}`,
+				`SET @out = parse_time(@in.nano, "unix_nano")`,
+				`"2009-11-10T23:00:00Z"`,
+			),
+			NewProgramExample(
+				`{"custom_yyyymmdd": "2009-11-10"}`,
+				`SET @out = parse_time(@in.custom_yyyymmdd, "2006-01-02")`,
+				`"2009-11-10T00:00:00Z"`,
+			),
+			NewProgramExample(
+				`{"local": "2009-11-10 08:30"}`,
+				`// parse a time without an offset in a specific time zone
+SET @out = parse_time(@in.local, format="2006-01-02 15:04", tz="America/New_York")`,
+				`"2009-11-10T08:30:00-05:00"`,
+			),
+		),
+	)
+}
+
+func builtinParseTime(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(3, args); !ok {
+		return res
+	}
+	a1 := args[0]
+	a2 := args[1]
+	fmtString, err := a2.AsString()
+	if err != nil {
+		msg := fmt.Sprintf("unable to convert item to TIME. invalid input type for second argument. got=%s", a2.Type())
+		return ObjectError(msg)
+	}
+	tzName, err := args[2].AsString()
+	if err != nil {
+		msg := fmt.Sprintf("unable to convert item to TIME. invalid input type for third argument. got=%s", args[2].Type())
+		return ObjectError(msg)
+	}
+	loc, err := time.LoadLocation(tzName)
+	if err != nil {
+		msg := fmt.Sprintf("unable to convert item to TIME. invalid time zone %q", tzName)
+		return ObjectError(msg)
+	}
+	ret := builtinParseTimeFormat(a1, fmtString, loc)
+	if !strings.HasPrefix(strings.ToLower(fmtString), "unix") {
+		return ret
+	}
+	t, err := ret.AsTime()
+	if err != nil {
+		return ret
+	}
+	return CastTime(t.In(loc))
+}
+
+// parses the input with the format string. arbitrary formats without a UTC offset are parsed in loc.
+func builtinParseTimeFormat(a1 *Object, fmtString string, loc *time.Location) *Object {
+	switch strings.ToLower(fmtString) {
+	case "rfc_3339":
+		inputString, err := a1.AsString()
+		if err != nil {
+			msg := fmt.Sprintf("unable to convert item to TIME. parsing the time format from rfc_3339 requires the first argument to be a STRING type. got=%s", a1.Type())
+			return ObjectError(msg)
+		}
+		t, err := time.Parse(time.RFC3339, inputString)
+		if err != nil {
+			msg := fmt.Sprintf("unable to convert item to TIME. invalid TIME string: %s", inputString)
+			return ObjectError(msg)
+		}
+		return CastTime(t)
+	case "rfc_3339_nano":
+		inputString, err := a1.AsString()
+		if err != nil {
+			msg := fmt.Sprintf("unable to convert item to TIME. parsing the time format from rfc_3339_nano requires the first argument to be a STRING type. got=%s", a1.Type())
+			return ObjectError(msg)
+		}
+		t, err := time.Parse(time.RFC3339Nano, inputString)
+		if err != nil {
+			msg := fmt.Sprintf("unable to convert item to TIME. invalid TIME string: %s", inputString)
+			return ObjectError(msg)
+		}
+		return CastTime(t)
+	case "unix":
+		arg1Any, err := a1.AsAny()
+		if err != nil {
+			return ObjectError("unable to convert item to TIME. invalid first argument")
+		}
+		switch a1.Type() {
+		case string(INTEGER), string(FLOAT), string(STRING):
+			return CastTime(arg1Any)
+		default:
+			msg := fmt.Sprintf("unable to convert item to TIME. parsing the time from unix seconds requires the first argument to be an INT, FLOAT, or STRING type: %s", a1.Type())
+			return ObjectError(msg)
+		}
+	case "unix_micro":
+		if argInt, err := a1.AsInt(); err == nil {
+			return CastTime(time.UnixMicro(argInt).UTC())
+		}
+		if argStr, err := a1.AsString(); err == nil {
+			asInt, err := strconv.ParseInt(argStr, 10, 64)
+			if err != nil {
+				msg := fmt.Sprintf("unable to convert item to TIME. invalid TIME string: %s", argStr)
+				return ObjectError(msg)
+			}
+			return CastTime(time.UnixMicro(asInt).UTC())
+		}
+		return ObjectError("unable to convert item to TIME. first argument must be an INTEGER or STRING when parsing from unix micro")
+	case "unix_milli":
+		if argInt, err := a1.AsInt(); err == nil {
+			return CastTime(time.UnixMilli(argInt).UTC())
+		}
+		if argStr, err := a1.AsString(); err == nil {
+			asInt, err := strconv.ParseInt(argStr, 10, 64)
+			if err != nil {
+				msg := fmt.Sprintf("unable to convert item to TIME. invalid TIME string: %s", argStr)
+				return ObjectError(msg)
+			}
+			return CastTime(time.UnixMilli(asInt).UTC())
+		}
+		return ObjectError("unable to convert item to TIME. first argument must be an INTEGER or STRING when parsing from unix milli")
+	case "unix_nano":
+		if argInt, err := a1.AsInt(); err == nil {
+			return CastTime(time.Unix(0, argInt).UTC())
+		}
+		if argStr, err := a1.AsString(); err == nil {
+			asInt, err := strconv.ParseInt(argStr, 10, 64)
+			if err != nil {
+				msg := fmt.Sprintf("unable to convert item to TIME. invalid TIME string: %s", argStr)
+				return ObjectError(msg)
+			}
+			return CastTime(time.Unix(0, asInt).UTC())
+		}
+		return ObjectError("unable to convert item to TIME. first argument must be an INTEGER or STRING when parsing from unix nano")
+	default:
+		inputString, err := a1.AsString()
+		if err != nil {
+			msg := fmt.Sprintf("unable to convert item to TIME. parsing an arbitrary time format requires the first argument to be a STRING tpye. got=%s", a1.Type())
+			return ObjectError(msg)
+		}
+		t, err := time.ParseInLocation(fmtString, inputString, loc)
+		if err != nil {
+			msg := fmt.Sprintf("unable to convert item to TIME. issue parsing time %s with format string %s: %s", inputString, fmtString, err.Error())
+			return ObjectError(msg)
+		}
+		return CastTime(t)
+	}
+}
+
+func builtinAddTimeEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"add_time",
+		"Adds a duration to a time. The same as time + duration",
+		builtinAddTime,
+		WithArgs(
+			NewFunctionArg(
+				"time",
+				"The time to add to",
+				TIME,
+			),
+			NewFunctionArg(
+				"duration",
+				`The duration to add, which can be negative to go back in time. Strings are parsed the same way as duration(), such as "15m"`,
+				DURATION, STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The time after adding the duration",
+				TIME,
+			),
+		),
+		WithTags(FUNCTION_TAG_TIME),
+		WithExamples(
+			NewProgramExample(
+				`{"created": "2025-10-06T20:24:24Z"}`,
+				`SET @out.expires = add_time(time(@in.created), "15m")`,
+				`{"expires": "2025-10-06T20:39:24Z"}`,
+			),
+		),
+	)
+}
+
+func builtinAddTime(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	t, err := args[0].AsTime()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	d, errObj := builtinDurationArg("add_time", args[1])
+	if errObj != nil {
+		return errObj
+	}
+	return CastTime(t.Add(d))
+}
+
+func builtinTimeDiffEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"time_diff",
+		"Gets the duration between two times. The same as end - start",
+		builtinTimeDiff,
+		WithArgs(
+			NewFunctionArg(
+				"end",
+				"The later time",
+				TIME,
+			),
+			NewFunctionArg(
+				"start",
+				"The earlier time",
+				TIME,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The duration from start to end, which is negative if start is after end",
+				DURATION,
+			),
+		),
+		WithTags(FUNCTION_TAG_TIME),
+		WithExamples(
+			NewProgramExample(
+				`{"start": "2025-10-06T20:00:00Z", "end": "2025-10-06T21:30:00Z"}`,
+				`SET @out.elapsed = time_diff(time(@in.end), time(@in.start))`,
+				`{"elapsed": "1h30m0s"}`,
+			),
+		),
+	)
+}
+
+func builtinTimeDiff(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	end, err := args[0].AsTime()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	start, err := args[1].AsTime()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	return CastDuration(end.Sub(start))
+}
+
+func builtinTruncateTimeEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"truncate_time",
+		"Rounds a time down to a multiple of a duration, such as the start of its hour or minute. Multiples are counted from the zero time in UTC, so a duration of \"24h\" gives midnight UTC",
+		builtinTruncateTime,
+		WithArgs(
+			NewFunctionArg(
+				"time",
+				"The time to round down",
+				TIME,
+			),
+			NewFunctionArg(
+				"duration",
+				`The positive duration to round down to. Strings are parsed the same way as duration(), such as "1h"`,
+				DURATION, STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The rounded down time",
+				TIME,
+			),
+		),
+		WithTags(FUNCTION_TAG_TIME),
+		WithExamples(
+			NewProgramExample(
+				`{"event_time": "2025-10-06T20:24:24Z"}`,
+				`SET @out.hour = truncate_time(time(@in.event_time), "1h")`,
+				`{"hour": "2025-10-06T20:00:00Z"}`,
+			),
+		),
+	)
+}
+
+func builtinTruncateTime(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	t, err := args[0].AsTime()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	d, errObj := builtinDurationArg("truncate_time", args[1])
+	if errObj != nil {
+		return errObj
+	}
+	if d <= 0 {
+		msg := fmt.Sprintf("truncate_time() duration must be positive. got=%s", d)
+		return ObjectError(msg)
+	}
+	return CastTime(t.Truncate(d))
+}
+
+func builtinDurationSecondsEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"duration_seconds",
+		"Gets the number of seconds in a duration",
+		builtinDurationSeconds,
+		WithArgs(
+			NewFunctionArg(
+				"duration",
+				"The duration to measure",
+				DURATION,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The number of seconds, including any fraction of a second",
+				FLOAT,
+			),
+		),
+		WithTags(FUNCTION_TAG_TIME),
+		WithExamples(
+			NewProgramExample(
+				`{"start": "2025-10-06T20:00:00Z", "end": "2025-10-06T20:01:30.5Z"}`,
+				`SET @out.seconds = duration_seconds(time(@in.end) - time(@in.start))`,
+				`{"seconds": 90.5}`,
+			),
+		),
+	)
+}
+
+func builtinDurationSeconds(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(1, args); !ok {
+		return res
+	}
+	d, err := args[0].AsDuration()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	return CastFloat(d.Seconds())
+}
+
+func builtinFormatTimeEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"format_time",
+		"Formats a time as a string or number. The reverse of parse_time",
+		builtinFormatTime,
+		WithArgs(
+			NewFunctionArg(
+				"time",
+				"The time to format",
+				TIME,
+			),
+			NewOptionalFunctionArg(
+				"format",
+				`The format of the output. Can be any of the following.
+"rfc_3339": a STRING, without fractions of a second
+"rfc_3339_nano": a STRING, with fractions of a second if there are any
+"unix": an INTEGER of UNIX time in seconds
+"unix_milli": an INTEGER of UNIX time in milliseconds
+"unix_micro": an INTEGER of UNIX time in microseconds
+"unix_nano": an INTEGER of UNIX time in nanoseconds
+Arbitrary format strings reflect how the time equivalent of "Mon Jan 2 15:04:05 -0700 MST 2006" would be represented in your desired format.
+`,
+				CastString("rfc_3339_nano"),
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The formatted time, as a STRING or an INTEGER depending on the format string",
+				STRING, INTEGER,
+			),
+		),
+		WithTags(FUNCTION_TAG_TIME),
+		WithExamples(
+			NewProgramExample(
+				`{"time": "2025-10-06T20:24:24.5Z"}`,
+				`SET @out = format_time(time(@in.time))`,
+				`"2025-10-06T20:24:24.5Z"`,
+			),
+			NewProgramExample(
+				`{"time": "2025-10-06T20:24:24.5Z"}`,
+				`SET @out = format_time(time(@in.time), "unix_milli")`,
+				`1759782264500`,
+			),
+			NewProgramExample(
+				`{"time": "2025-10-06T20:24:24Z"}`,
+				`SET @out = format_time(time(@in.time), "Jan 2, 2006 at 3:04PM")`,
+				`"Oct 6, 2025 at 8:24PM"`,
+			),
+		),
+	)
+}
+
+func builtinFormatTime(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	t, err := args[0].AsTime()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	fmtString, err := args[1].AsString()
+	if err != nil {
+		msg := fmt.Sprintf("format_time() format must be a STRING. got=%s", args[1].Type())
+		return ObjectError(msg)
+	}
+	switch strings.ToLower(fmtString) {
+	case "rfc_3339":
+		return CastString(t.Format(time.RFC3339))
+	case "rfc_3339_nano":
+		return CastString(t.Format(time.RFC3339Nano))
+	case "unix":
+		return CastInt(t.Unix())
+	case "unix_milli":
+		return CastInt(t.UnixMilli())
+	case "unix_micro":
+		return CastInt(t.UnixMicro())
+	case "unix_nano":
+		return CastInt(t.UnixNano())
+	default:
+		return CastString(t.Format(fmtString))
+	}
+}
+
+func builtinToTimezoneEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"to_timezone",
+		"Converts a time to a time zone. The time is the same instant, but its date parts, formatting, and start_of() use the new time zone",
+		builtinToTimezone,
+		WithArgs(
+			NewFunctionArg(
+				"time",
+				"The time to convert",
+				TIME,
+			),
+			NewFunctionArg(
+				"tz",
+				`The IANA time zone name, such as "America/New_York", or "UTC"`,
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The time in the time zone",
+				TIME,
+			),
+		),
+		WithTags(FUNCTION_TAG_TIME),
+		WithExamples(
+			NewProgramExample(
+				`{"time": "2025-10-06T20:24:24Z"}`,
+				`SET @out = to_timezone(time(@in.time), "America/New_York")`,
+				`"2025-10-06T16:24:24-04:00"`,
+			),
+		),
+	)
+}
+
+func builtinToTimezone(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	t, err := args[0].AsTime()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	tzName, err := args[1].AsString()
+	if err != nil {
+		msg := fmt.Sprintf("to_timezone() time zone must be a STRING. got=%s", args[1].Type())
+		return ObjectError(msg)
+	}
+	loc, err := time.LoadLocation(tzName)
+	if err != nil {
+		msg := fmt.Sprintf("to_timezone() invalid time zone %q", tzName)
+		return ObjectError(msg)
+	}
+	return CastTime(t.In(loc))
+}
+
+func builtinStartOfEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"start_of",
+		"Gets the start of the minute, hour, day, week, month, or year that a time is in, using the time's time zone",
+		builtinStartOf,
+		WithArgs(
+			NewFunctionArg(
+				"time",
+				"The time to get the start of",
+				TIME,
+			),
+			NewFunctionArg(
+				"unit",
+				`One of "minute", "hour", "day", "week", "month", or "year". Weeks start on Monday`,
+				STRING,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The first instant of the unit that contains the time",
+				TIME,
+			),
+		),
+		WithTags(FUNCTION_TAG_TIME),
+		WithExamples(
+			NewProgramExample(
+				`{"time": "2025-10-08T20:24:24Z"}`,
+				`SET @out.day = start_of(time(@in.time), "day")
+SET @out.week = start_of(time(@in.time), "week")
+SET @out.month = start_of(time(@in.time), "month")`,
+				`{"day": "2025-10-08T00:00:00Z", "week": "2025-10-06T00:00:00Z", "month": "2025-10-01T00:00:00Z"}`,
+			),
+			NewProgramExample(
+				`{"time": "2025-10-08T02:00:00Z"}`,
+				`//the start of the day in New York, which is still October 7th there
+SET @out = start_of(to_timezone(time(@in.time), "America/New_York"), "day")`,
+				`"2025-10-07T00:00:00-04:00"`,
+			),
+		),
+	)
+}
+
+func builtinStartOf(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	t, err := args[0].AsTime()
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	unit, err := args[1].AsString()
+	if err != nil {
+		msg := fmt.Sprintf("start_of() unit must be a STRING. got=%s", args[1].Type())
+		return ObjectError(msg)
+	}
+	year, month, day := t.Date()
+	switch strings.ToLower(unit) {
+	case "minute":
+		return CastTime(time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, t.Location()))
+	case "hour":
+		return CastTime(time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location()))
+	case "day":
+		return CastTime(time.Date(year, month, day, 0, 0, 0, 0, t.Location()))
+	case "week":
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return CastTime(time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, t.Location()))
+	case "month":
+		return CastTime(time.Date(year, month, 1, 0, 0, 0, 0, t.Location()))
+	case "year":
+		return CastTime(time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location()))
+	default:
+		msg := fmt.Sprintf("start_of() unit must be one of minute, hour, day, week, month, or year. got=%q", unit)
+		return ObjectError(msg)
+	}
+}
+
+// creates the entry of a function that gets a single part of a time, such as its year
+func builtinTimePartEntry(name string, description string, returnDescription string, part func(t time.Time) int, example ProgramExample) *FunctionEntry {
+	fn := func(ctx context.Context, args ...*Object) *Object {
+		if res, ok := IsArgCountEqual(1, args); !ok {
+			return res
+		}
+		t, err := args[0].AsTime()
+		if err != nil {
+			return ObjectError(err.Error())
+		}
+		return CastInt(part(t))
+	}
+	return NewFunctionEntry(
+		name,
+		description+", using the time's time zone",
+		fn,
+		WithArgs(
+			NewFunctionArg(
+				"time",
+				"The time to read from",
+				TIME,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				returnDescription,
+				INTEGER,
+			),
+		),
+		WithTags(FUNCTION_TAG_TIME),
+		WithExamples(example),
+	)
+}
+
+func builtinYearEntry() *FunctionEntry {
+	return builtinTimePartEntry("year", "Gets the year of a time", "The year, such as 2025",
+		func(t time.Time) int { return t.Year() },
+		NewProgramExample(`{"time": "2025-10-06T20:24:24Z"}`, `SET @out = year(time(@in.time))`, `2025`),
+	)
+}
+
+func builtinMonthEntry() *FunctionEntry {
+	return builtinTimePartEntry("month", "Gets the month of a time", "The month, from 1 for January to 12 for December",
+		func(t time.Time) int { return int(t.Month()) },
+		NewProgramExample(`{"time": "2025-10-06T20:24:24Z"}`, `SET @out = month(time(@in.time))`, `10`),
+	)
+}
+
+func builtinDayEntry() *FunctionEntry {
+	return builtinTimePartEntry("day", "Gets the day of the month of a time", "The day of the month, from 1 to 31",
+		func(t time.Time) int { return t.Day() },
+		NewProgramExample(`{"time": "2025-10-06T20:24:24Z"}`, `SET @out = day(time(@in.time))`, `6`),
+	)
+}
+
+func builtinHourEntry() *FunctionEntry {
+	return builtinTimePartEntry("hour", "Gets the hour of a time", "The hour, from 0 to 23",
+		func(t time.Time) int { return t.Hour() },
+		NewProgramExample(`{"time": "2025-10-06T20:24:24Z"}`, `SET @out = hour(time(@in.time))`, `20`),
+	)
+}
+
+func builtinMinuteEntry() *FunctionEntry {
+	return builtinTimePartEntry("minute", "Gets the minute of a time", "The minute, from 0 to 59",
+		func(t time.Time) int { return t.Minute() },
+		NewProgramExample(`{"time": "2025-10-06T20:24:24Z"}`, `SET @out = minute(time(@in.time))`, `24`),
+	)
+}
+
+func builtinSecondEntry() *FunctionEntry {
+	return builtinTimePartEntry("second", "Gets the second of a time", "The second, from 0 to 59",
+		func(t time.Time) int { return t.Second() },
+		NewProgramExample(`{"time": "2025-10-06T20:24:24Z"}`, `SET @out = second(time(@in.time))`, `24`),
+	)
+}
+
+func builtinWeekdayEntry() *FunctionEntry {
+	return builtinTimePartEntry("weekday", "Gets the day of the week of a time", "The day of the week, from 1 for Monday to 7 for Sunday, like ISO 8601",
+		func(t time.Time) int { return (int(t.Weekday())+6)%7 + 1 },
+		NewProgramExample(`{"time": "2025-10-05T20:24:24Z"}`, `//October 5th, 2025 was a Sunday
+SET @out = weekday(time(@in.time))`, `7`),
+	)
+}
+
+func builtinISOWeekEntry() *FunctionEntry {
+	return builtinTimePartEntry("iso_week", "Gets the ISO 8601 week number of a time", "The week number, from 1 to 53. Weeks start on Monday, and week 1 is the week with the year's first Thursday, so days at the start or end of a year can belong to a week of the previous or next year",
+		func(t time.Time) int {
+			_, week := t.ISOWeek()
+			return week
+		},
+		NewProgramExample(`{"time": "2025-10-06T20:24:24Z"}`, `SET @out = iso_week(time(@in.time))`, `41`),
+	)
+}
+
+func builtinDayOfYearEntry() *FunctionEntry {
+	return builtinTimePartEntry("day_of_year", "Gets the day of the year of a time", "The day of the year, from 1 to 365, or 366 in leap years",
+		func(t time.Time) int { return t.YearDay() },
+		NewProgramExample(`{"time": "2025-10-06T20:24:24Z"}`, `SET @out = day_of_year(time(@in.time))`, `279`),
+	)
+}
+
+// reads a DURATION argument, or parses a STRING argument as one
+func builtinDurationArg(name string, arg *Object) (time.Duration, *Object) {
+	if s, err := arg.AsString(); err == nil {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			msg := fmt.Sprintf("%s() invalid duration %q", name, s)
+			return 0, ObjectError(msg)
+		}
+		return d, nil
+	}
+	d, err := arg.AsDuration()
+	if err != nil {
+		return 0, ObjectError(err.Error())
+	}
+	return d, nil
+}
+
+func builtinLookupEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"lookup",
+		"Gets the value stored under a key in a lookup table provided by the host. Returns NULL if the key does not exist in the table",
+		builtinLookup,
+		WithArgs(
+			NewFunctionArg(
+				"table",
+				"The name of the lookup table",
+				STRING,
+			),
+			NewFunctionArg(
+				"key",
+				"The key to look up. Integer keys are converted to their string form",
+				STRING, INTEGER,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The value stored under the key, or NULL if the key does not exist",
+				BASIC_WITHOUT_ERROR...,
+			),
+		),
+		WithTags(FUNCTION_TAG_LOOKUPS),
+		WithCapabilities(FUNCTION_CAPABILITY_IO),
+		WithExamples(
+			NewProgramExample(
+				`{"country": "DE"}`,
+				`SET @out.country = lookup("countries", @in.country)`,
+				`{"country": {"name": "Germany", "region": "EU"}}`,
+			),
+			NewProgramExample(
+				`{"user": "carol"}`,
+				`//missing keys return null
+SET @out.team = lookup("teams", @in.user)`,
+				`{"team": null}`,
+			),
+		),
+	)
+}
+
+func builtinLookup(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	ret, _, errObj := builtinLookupValue(ctx, "lookup", args[0], args[1])
+	if errObj != nil {
+		return errObj
+	}
+	return ret
+}
+
+func builtinLookupDefaultEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"lookup_default",
+		"Gets the value stored under a key in a lookup table provided by the host. Returns the fallback if the key does not exist in the table",
+		builtinLookupDefault,
+		WithArgs(
+			NewFunctionArg(
+				"table",
+				"The name of the lookup table",
+				STRING,
+			),
+			NewFunctionArg(
+				"key",
+				"The key to look up. Integer keys are converted to their string form",
+				STRING, INTEGER,
+			),
+			NewFunctionArg(
+				"fallback",
+				"The value to return if the key does not exist",
+				BASIC_WITHOUT_ERROR...,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The value stored under the key, or the fallback if the key does not exist",
+				BASIC_WITHOUT_ERROR...,
+			),
+		),
+		WithTags(FUNCTION_TAG_LOOKUPS),
+		WithCapabilities(FUNCTION_CAPABILITY_IO),
+		WithExamples(
+			NewProgramExample(
+				`{"user": "bob"}`,
+				`SET @out.team = lookup_default("teams", @in.user, "unassigned")`,
+				`{"team": "security"}`,
+			),
+			NewProgramExample(
+				`{"user": "carol"}`,
+				`SET @out.team = lookup_default("teams", @in.user, "unassigned")`,
+				`{"team": "unassigned"}`,
+			),
+		),
+	)
+}
+
+func builtinLookupDefault(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(3, args); !ok {
+		return res
+	}
+	ret, found, errObj := builtinLookupValue(ctx, "lookup_default", args[0], args[1])
+	if errObj != nil {
+		return errObj
+	}
+	if !found {
+		return args[2]
+	}
+	return ret
+}
+
+func builtinLookupManyEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"lookup_many",
+		"Gets the values stored under each of a list of keys in a lookup table provided by the host",
+		builtinLookupMany,
+		WithArgs(
+			NewFunctionArg(
+				"table",
+				"The name of the lookup table",
+				STRING,
+			),
+			NewFunctionArg(
+				"keys",
+				"The keys to look up. Each key must be a string or an integer",
+				ArrayOf(STRING, INTEGER),
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"An array of the values stored under each key, in the same order as the keys. Keys that do not exist produce NULL",
+				ARRAY,
+			),
+		),
+		WithTags(FUNCTION_TAG_LOOKUPS),
+		WithCapabilities(FUNCTION_CAPABILITY_IO),
+		WithExamples(
+			NewProgramExample(
+				`{"countries": ["US", "JP", "XX"]}`,
+				`SET @out.regions = map(lookup_many("countries", @in.countries), e ~> {
+	SET return = e.value.region
+})`,
+				`{"regions": ["NA", "APAC", null]}`,
+			),
+		),
+	)
+}
+
+func builtinLookupMany(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
+		return res
+	}
+	keys, ok := args[1].inner.(*objectArray)
+	if !ok {
+		return ObjectError(fmt.Sprintf("lookup_many() keys must be an array. got=%s", args[1].Type()))
+	}
+	ret := &objectArray{entries: []object{}}
+	for _, key := range keys.entries {
+		val, _, errObj := builtinLookupValue(ctx, "lookup_many", args[0], &Object{inner: key})
+		if errObj != nil {
+			return errObj
+		}
+		ret.entries = append(ret.entries, val.inner)
+	}
+	return &Object{inner: ret}
+}
+
+// looks up a key in the run's lookup cache, returning an error object if the lookup fails
+func builtinLookupValue(ctx context.Context, fnName string, tableArg *Object, keyArg *Object) (*Object, bool, *Object) {
+	table, err := tableArg.AsString()
+	if err != nil {
+		return nil, false, ObjectError(err.Error())
+	}
+	key, errObj := builtinKeyString(fnName, keyArg)
+	if errObj != nil {
+		return nil, false, errObj
+	}
+	cache, ok := lookupCacheFromContext(ctx)
+	if !ok {
+		return nil, false, ObjectError(fmt.Sprintf("function %s() requires a lookup provider, but none is configured", fnName))
+	}
+	val, found, err := cache.get(ctx, table, key)
+	if err != nil {
+		return nil, false, ObjectError(err.Error())
+	}
+	return &Object{inner: val}, found, nil
+}
+
+func builtinStateGetEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"get",
+		"Gets a value from the state store, which persists values across program runs",
+		builtinStateGet,
+		WithArgs(
+			NewFunctionArg(
+				"key",
+				"The key of the value to get",
+				STRING, INTEGER,
+			),
+		),
+		WithReturn(
+			NewFunctionReturn(
+				"The value stored under the key, or NULL if the key does not exist or has expired",
+				BASIC_WITHOUT_ERROR...,
+			),
+		),
+		WithTags(FUNCTION_TAG_STATE),
+		WithCapabilities(FUNCTION_CAPABILITY_IO, FUNCTION_CAPABILITY_NONDETERMINISTIC),
+		WithExamples(
+			NewProgramExample(
+				`null`,
+				`state.put("last_user", "alice")
+SET @out.last_user = state.get("last_user")
+SET @out.missing = state.get("nope")`,
+				`{"last_user": "alice", "missing": null}`,
+			),
+		),
+	)
+}
+
+func builtinStateGet(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(1, args); !ok {
+		return res
+	}
+	store, errObj := builtinStateStore(ctx, "state.get")
+	if errObj != nil {
+		return errObj
+	}
+	key, errObj := builtinKeyString("state.get", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	val, ok, err := store.Get(ctx, key)
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	if !ok {
+		return ObjectNull
+	}
+	return &Object{inner: convertAnyToObject(val, false)}
+}
+
+func builtinStatePutEntry() *FunctionEntry {
+	return NewFunctionEntry(
+		"put",
+		"Stores a value in the state store, which persists values across program runs",
+		builtinStatePut,
+		WithArgs(
+			NewFunctionArg(
+				"key",
+				"The key to store the value under",
+				STRING, INTEGER,
+			),
+			NewFunctionArg(
+				"value",
+				"The value to store",
+				BASIC_WITHOUT_ERROR...,
+			),
+			NewOptionalFunctionArg(
+				"ttl",
+				"The number of seconds after which the value expires. The value never expires if this is left out or zero",
+				CastInt(0),
+				INTEGER,
 			),
 		),
-		WithTags(FUNCTION_TAG_HIGHER_ORDER),
+		WithTags(FUNCTION_TAG_STATE),
+		WithCapabilities(FUNCTION_CAPABILITY_IO, FUNCTION_CAPABILITY_NONDETERMINISTIC),
 		WithExamples(
 			NewProgramExample(
-				`[1, "2", "buckle my shoe"]`,
-				`//reduce an array of numbers to sum its contents
-SET @out.result = reduce(@in, 0, e ~> {
-	SET cleaned_number = int(e.value) | catch(0) // these variables are ONLY set locally to the arrow function environment.
-	SET return = e.current + cleaned_number
-})`,
-				`{"result": 3}`,
-			),
-			NewProgramExample(
-				`{"a": 1, "b": "2"}`,
-				`//reduce a map to sum the values.
-SET @out.result = reduce(@in, NULL, e ~> {
-	IF e.current == NULL :: SET e.current = 0
-	SET cleaned_number = int(e.value) | catch(0)
-	SET return = e.current + cleaned_number
-})`,
-				`{"result": 3}`,
+				`{"user": "bob"}`,
+				`//remember the last user for an hour
+state.put("last_user", @in.user, 3600)
+SET @out.last_user = state.get("last_user")`,
+				`{"last_user": "bob"}`,
 			),
 		),
 	)
 }
 
-func builtinReduce(ctx context.Context, args ...*Object) *Object {
+func builtinStatePut(ctx context.Context, args ...*Object) *Object {
 	if res, ok := IsArgCountEqual(3, args); !ok {
 		return res
 	}
-	arrowFn, err := args[2].AsArrowFunction()
-	if err != nil {
-		msg := fmt.Sprintf("reduce() third argument must be a valid ARROWFUNC. got type of %s", args[2].Type())
-		return ObjectError(msg)
+	store, errObj := builtinStateStore(ctx, "state.put")
+	if errObj != nil {
+		return errObj
 	}
-
-	acc, err := args[1].AsAny()
+	key, errObj := builtinKeyString("state.put", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	ttl, errObj := builtinStateTTL("state.put", args[2])
+	if errObj != nil {
+		return errObj
+	}
+	val, err := convertObjectToNative(args[1].inner)
 	if err != nil {
 		return ObjectError(err.Error())
 	}
-
-	switch args[0].Type() {
-	case string(MAP):
-		in, err := args[0].AsMap()
-		if err != nil {
-			msg := fmt.Sprintf("reduce() input data argument issue. type is not compatible with MAP operations: %s", args[0].Type())
-			return ObjectError(msg)
-		}
-		ret := acc
-		keyList := []string{}
-		for k := range in {
-			keyList = append(keyList, k)
-		}
-		slices.Sort(keyList)
-		for _, key := range keyList {
-			value := in[key]
-			input := make(map[string]interface{})
-			input["key"] = key
-			input["value"] = value
-			input["current"] = ret
-			subEnv := arrowFn.Run(input)
-			if arrowFn.HasError() {
-				return arrowFn.GetError()
-			}
-			m, ok := subEnv.(map[string]interface{})
-			if !ok {
-				return ObjectError("reduce() unable to extract return value from arrow function")
-			}
-			if out, ok := m["return"]; ok {
-				ret = out
-			}
-		}
-		return CastAuto(ret)
-	case string(ARRAY):
-		in, err := args[0].AsArray()
-		if err != nil {
-			msg := fmt.Sprintf("reduce() input data argument issue. type is not compatible with array operation: %s", args[0].Type())
-			return ObjectError(msg)
-		}
-		ret := acc
-		for idx, entry := range in {
-			input := make(map[string]interface{})
-			input["value"] = entry
-			input["index"] = int64(idx)
-			input["current"] = ret
-			subEnv := arrowFn.Run(input)
-			if arrowFn.HasError() {
-				return arrowFn.GetError()
-			}
-			m, ok := subEnv.(map[string]interface{})
-			if !ok {
-				return ObjectError("reduce() unable to extract return value from arrow function")
-			}
-			if out, ok := m["return"]; ok {
-				ret = out
-			}
-		}
-		return CastAuto(ret)
-	default:
-		msg := fmt.Sprintf("invalid argument for reduce(): first argument must be an ARRAY or MAP. got type of %s", args[0].Type())
-		return ObjectError(msg)
+	if err := store.Set(ctx, key, val, ttl); err != nil {
+		return ObjectError(err.Error())
 	}
+	return ObjectNull
 }
-func builtinNowEntry() *FunctionEntry {
+
+func builtinStateIncrEntry() *FunctionEntry {
 	return NewFunctionEntry(
-		"now",
-		"Gets the current time",
-		builtinNow,
+		"incr",
+		"Adds one to a counter in the state store, which persists values across program runs. Counters that do not exist start at zero",
+		builtinStateIncr,
+		WithArgs(
+			NewFunctionArg(
+				"key",
+				"The key of the counter",
+				STRING, INTEGER,
+			),
+			NewOptionalFunctionArg(
+				"ttl",
+				"The number of seconds after which the counter expires. The counter never expires if this is left out or zero. The ttl only applies when the counter is created",
+				CastInt(0),
+				INTEGER,
+			),
+		),
 		WithReturn(
 			NewFunctionReturn(
-				"An object representing the current time",
-				TIME,
+				"The value of the counter after it is incremented",
+				INTEGER,
 			),
 		),
-		WithTags(FUNCTION_TAG_TIME),
+		WithTags(FUNCTION_TAG_STATE),
+		WithCapabilities(FUNCTION_CAPABILITY_IO, FUNCTION_CAPABILITY_NONDETERMINISTIC),
 		WithExamples(
 			NewProgramExample(
-				`null`,
-				`SET current_time = now()`,
-				`null`,
+				`{"user": "alice"}`,
+				`//count logins per user, resetting every 5 minutes
+SET first = state.incr('logins:${@in.user}', 300)
+SET @out.logins = state.incr('logins:${@in.user}', 300)`,
+				`{"logins": 2}`,
 			),
 		),
 	)
 }
 
-func builtinNow(ctx context.Context, args ...*Object) *Object {
-	if res, ok := IsArgCountEqual(0, args); !ok {
+func builtinStateIncr(ctx context.Context, args ...*Object) *Object {
+	if res, ok := IsArgCountEqual(2, args); !ok {
 		return res
 	}
-	return CastTime(time.Now().UTC())
+	store, errObj := builtinStateStore(ctx, "state.incr")
+	if errObj != nil {
+		return errObj
+	}
+	key, errObj := builtinKeyString("state.incr", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	ttl, errObj := builtinStateTTL("state.incr", args[1])
+	if errObj != nil {
+		return errObj
+	}
+	count, err := store.Incr(ctx, key, 1, ttl)
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	return CastInt(count)
 }
-func builtinParseTimeEntry() *FunctionEntry {
+
+func builtinStateSeenEntry() *FunctionEntry {
 	return NewFunctionEntry(
-		"parse_time",
-		"Parses a string into the desired time format",
-		builtinParseTime,
+		"seen",
+		"Checks whether a key has been seen before, and marks it as seen. Useful for deduplicating records across program runs",
+		builtinStateSeen,
 		WithArgs(
 			NewFunctionArg(
-				"input_time",
-				`The time to parse into a time object. Can be a STRING, INTEGER, or FLOAT depending on the format string`,
-				STRING, INTEGER, FLOAT,
-			),
-			NewFunctionArg(
-				"format_string",
-				`The specified format of the input time. Can be any of the following.
-"rfc_3339": input time must be a STRING
-"rfc_3339_nano": input time must be a STRING
-"unix": input time must be an INTEGER or FLOAT representing UNIX time in seconds, or the equivalent string representation.
-"unix_milli": input time must be an INTEGER representing UNIX time in milliseconds, or the equivalent string representation.
-"unix_micro": input time must be an INTEGER representing UNIX time in microseconds, or the equivalent string representation.
-"unix_nano": input time must be an INTEGER representing UNIX time in nanoseconds, or the equivalent string representation.
-Arbitrary format strings reflect how the time equivalent of "Mon Jan 2 15:04:05 -0700 MST 2006" would be represented in your desired format.
-`,
-				STRING,
+				"key",
+				"The key to check",
+				STRING, INTEGER,
+			),
+			NewOptionalFunctionArg(
+				"ttl",
+				"The number of seconds after which the key is forgotten. The key is never forgotten if this is left out or zero",
+				CastInt(0),
+				INTEGER,
 			),
 		),
 		WithReturn(
 			NewFunctionReturn(
-				"The map or array containing the newly filtered values",
-				TIME,
+				"true if the key was seen by an earlier call, otherwise false",
+				BOOLEAN,
 			),
 		),
-		WithTags(FUNCTION_TAG_TIME),
+		WithTags(FUNCTION_TAG_STATE),
+		WithCapabilities(FUNCTION_CAPABILITY_IO, FUNCTION_CAPABILITY_NONDETERMINISTIC),
 		WithExamples(
 			NewProgramExample(
-				`{"float": 1759782264.0}`,
-				`SET @out = parse_time(@in.float, "unix")`,
-				`"2025-10-06T20:24:24Z"`,
-			),
-			NewProgramExample(
-				`{"int": 1759782264}`,
-				`SET @out = parse_time(@in.int, "unix")`,
-				`"2025-10-06T20:24:24Z"`,
-			),
-			NewProgramExample(
-				`{"string": "1759782264.0"}`,
-				`SET @out = parse_time(@in.string, "unix")`,
-				`"2025-10-06T20:24:24Z"`,
-			),
-			NewProgramExample(
-				`{"milli": "1257894000000"}`,
-				`SET @out = parse_time(@in.milli, "unix_milli")`,
-				`"2009-11-10T23:00:00Z"`,
-			),
-			NewProgramExample(
-				`{"micro": "1257894000000000"}`,
-				`SET @out = parse_time(@in.micro, "unix_micro")`,
-				`"2009-11-10T23:00:00Z"`,
-			),
-			NewProgramExample(
-				`{"nano": "1257894000000000000"}`,
-				`SET @out = parse_time(@in.nano, "unix_nano")`,
-				`"2009-11-10T23:00:00Z"`,
+				`{"event_id": "abc-123"}`,
+				`SET @out.first = state.seen(@in.event_id, 3600)
+SET @out.second = state.seen(@in.event_id, 3600)`,
+				`{"first": false, "second": true}`,
 			),
 			NewProgramExample(
-				`{"custom_yyyymmdd": "2009-11-10"}`,
-				`SET @out = parse_time(@in.custom_yyyymmdd, "2006-01-02")`,
-				`"2009-11-10T00:00:00Z"`,
+				`{"event_id": "abc-123"}`,
+				`//drop duplicate events
+IF state.seen(@in.event_id) :: { drop("duplicate") }
+SET @out = @in`,
+				`{"event_id": "abc-123"}`,
 			),
 		),
 	)
 }
 
-func builtinParseTime(ctx context.Context, args ...*Object) *Object {
+func builtinStateSeen(ctx context.Context, args ...*Object) *Object {
 	if res, ok := IsArgCountEqual(2, args); !ok {
 		return res
 	}
-	a1 := args[0]
-	a2 := args[1]
-	fmtString, err := a2.AsString()
+	store, errObj := builtinStateStore(ctx, "state.seen")
+	if errObj != nil {
+		return errObj
+	}
+	key, errObj := builtinKeyString("state.seen", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	ttl, errObj := builtinStateTTL("state.seen", args[1])
+	if errObj != nil {
+		return errObj
+	}
+	// incrementing is atomic, so exactly one caller sees the key for the first time
+	count, err := store.Incr(ctx, key, 1, ttl)
 	if err != nil {
-		msg := fmt.Sprintf("unable to convert item to TIME. invalid input type for second argument. got=%s", a2.Type())
-		return ObjectError(msg)
+		return ObjectError(err.Error())
 	}
-	switch strings.ToLower(fmtString) {
-	case "rfc_3339":
-		inputString, err := a1.AsString()
-		if err != nil {
-			msg := fmt.Sprintf("unable to convert item to TIME. parsing the time format from rfc_3339 requires the first argument to be a STRING type. got=%s", a1.Type())
-			return ObjectError(msg)
-		}
-		t, err := time.Parse(time.RFC3339, inputString)
-		if err != nil {
-			msg := fmt.Sprintf("unable to convert item to TIME. invalid TIME string: %s", inputString)
-			return ObjectError(msg)
-		}
-		return CastTime(t)
-	case "rfc_3339_nano":
-		inputString, err := a1.AsString()
-		if err != nil {
-			msg := fmt.Sprintf("unable to convert item to TIME. parsing the time format from rfc_3339_nano requires the first argument to be a STRING type. got=%s", a1.Type())
-			return ObjectError(msg)
-		}
-		t, err := time.Parse(time.RFC3339Nano, inputString)
+	return CastBool(count > 1)
+}
+
+func builtinStateStore(ctx context.Context, fnName string) (StateStore, *Object) {
+	store, ok := stateStoreFromContext(ctx)
+	if !ok {
+		return nil, ObjectError(fmt.Sprintf("function %s() requires a state store, but none is configured", fnName))
+	}
+	return store, nil
+}
+
+// converts a string or integer key argument to its string form
+func builtinKeyString(fnName string, keyArg *Object) (string, *Object) {
+	switch keyArg.Type() {
+	case string(STRING):
+		key, err := keyArg.AsString()
 		if err != nil {
-			msg := fmt.Sprintf("unable to convert item to TIME. invalid TIME string: %s", inputString)
-			return ObjectError(msg)
+			return "", ObjectError(err.Error())
 		}
-		return CastTime(t)
-	case "unix":
-		arg1Any, err := a1.AsAny()
+		return key, nil
+	case string(INTEGER):
+		i, err := keyArg.AsInt()
 		if err != nil {
-			return ObjectError("unable to convert item to TIME. invalid first argument")
-		}
-		switch a1.Type() {
-		case string(INTEGER), string(FLOAT), string(STRING):
-			return CastTime(arg1Any)
-		default:
-			msg := fmt.Sprintf("unable to convert item to TIME. parsing the time from unix seconds requires the first argument to be an INT, FLOAT, or STRING type: %s", a1.Type())
-			return ObjectError(msg)
-		}
-	case "unix_micro":
-		if argInt, err := a1.AsInt(); err == nil {
-			return CastTime(time.UnixMicro(argInt).UTC())
-		}
-		if argStr, err := a1.AsString(); err == nil {
-			asInt, err := strconv.ParseInt(argStr, 10, 64)
-			if err != nil {
-				msg := fmt.Sprintf("unable to convert item to TIME. invalid TIME string: %s", argStr)
-				return ObjectError(msg)
-			}
-			return CastTime(time.UnixMicro(asInt).UTC())
-		}
-		return ObjectError("unable to convert item to TIME. first argument must be an INTEGER or STRING when parsing from unix micro")
-	case "unix_milli":
-		if argInt, err := a1.AsInt(); err == nil {
-			return CastTime(time.UnixMilli(argInt).UTC())
-		}
-		if argStr, err := a1.AsString(); err == nil {
-			asInt, err := strconv.ParseInt(argStr, 10, 64)
-			if err != nil {
-				msg := fmt.Sprintf("unable to convert item to TIME. invalid TIME string: %s", argStr)
-				return ObjectError(msg)
-			}
-			return CastTime(time.UnixMilli(asInt).UTC())
-		}
-		return ObjectError("unable to convert item to TIME. first argument must be an INTEGER or STRING when parsing from unix milli")
-	case "unix_nano":
-		if argInt, err := a1.AsInt(); err == nil {
-			return CastTime(time.Unix(0, argInt).UTC())
-		}
-		if argStr, err := a1.AsString(); err == nil {
-			asInt, err := strconv.ParseInt(argStr, 10, 64)
-			if err != nil {
-				msg := fmt.Sprintf("unable to convert item to TIME. invalid TIME string: %s", argStr)
-				return ObjectError(msg)
-			}
-			return CastTime(time.Unix(0, asInt).UTC())
+			return "", ObjectError(err.Error())
 		}
-		return ObjectError("unable to convert item to TIME. first argument must be an INTEGER or STRING when parsing from unix nano")
+		return strconv.FormatInt(i, 10), nil
 	default:
-		inputString, err := a1.AsString()
-		if err != nil {
-			msg := fmt.Sprintf("unable to convert item to TIME. parsing an arbitrary time format requires the first argument to be a STRING tpye. got=%s", a1.Type())
-			return ObjectError(msg)
-		}
-		t, err := time.Parse(fmtString, inputString)
-		if err != nil {
-			msg := fmt.Sprintf("unable to convert item to TIME. issue parsing time %s with format string %s: %s", inputString, fmtString, err.Error())
-			return ObjectError(msg)
-		}
-		return CastTime(t)
+		return "", ObjectError(fmt.Sprintf("function %s() keys must be strings or integers. got=%s", fnName, keyArg.Type()))
+	}
+}
+
+// reads the optional ttl argument of the state functions as a number of seconds
+func builtinStateTTL(fnName string, ttlArg *Object) (time.Duration, *Object) {
+	seconds, err := ttlArg.AsInt()
+	if err != nil {
+		return 0, ObjectError(err.Error())
+	}
+	if seconds < 0 {
+		return 0, ObjectError(fmt.Sprintf("function %s() ttl cannot be negative. got=%d", fnName, seconds))
 	}
+	return time.Duration(seconds) * time.Second, nil
 }
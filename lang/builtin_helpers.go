@@ -4,30 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
-	"slices"
 )
 
 // iterate through all namespaces in a function store, and run each of their registered functions to ensure they produce the expected result.
 func RunFunctionStoreExamples(fs *FunctionStore) error {
-	nsList := []string{}
-	for _, ns := range fs.Namespaces {
-		nsList = append(nsList, ns.Name)
-	}
-	slices.Sort(nsList)
-	for _, nsName := range nsList {
-		ns := fs.Namespaces[nsName]
-		fnList := []string{}
-		for _, fn := range ns.Functions {
-			fnList = append(fnList, fn.Name)
-		}
-		slices.Sort(fnList)
-
-		for _, fnName := range fnList {
-			fn := ns.Functions[fnName]
-			for idx, exEntry := range fn.Examples {
-				err := testBuiltinFunctionEntry(fs, exEntry.In, exEntry.Program, exEntry.Out)
-				if err != nil {
-					return fmt.Errorf("error running example #%d with %s.%s: %w", idx+1, ns.Name, fn.Name, err)
+	for _, nsName := range fs.NamespaceNames() {
+		for _, primary := range fs.Functions(nsName) {
+			for _, fn := range primary.Overloads() {
+				for idx, exEntry := range fn.Examples {
+					err := testBuiltinFunctionEntry(fs, exEntry.In, exEntry.Program, exEntry.Out)
+					if err != nil {
+						return fmt.Errorf("error running example #%d with %s.%s: %w", idx+1, nsName, fn.Name, err)
+					}
 				}
 			}
 		}
@@ -55,10 +43,15 @@ func testBuiltinFunctionEntry(fstore *FunctionStore, in string, programContents
 		return err
 	}
 
-	gotJSON, err := runnableProgram.Run([]byte(in))
+	opts := []newEnvArg{WithStateStore(NewMemoryStateStore())}
+	if fstore.lookups == nil {
+		opts = append(opts, WithLookupProvider(exampleLookupProvider()))
+	}
+	res, err := runnableProgram.RunResult([]byte(in), opts...)
 	if err != nil {
 		return err
 	}
+	gotJSON := res.Output
 
 	var got interface{}
 	err = json.Unmarshal([]byte(gotJSON), &got)
@@ -0,0 +1,209 @@
+package lang
+
+import (
+	"fmt"
+	"slices"
+)
+
+// describes a sensitive behavior of a function, so that hosts can deny it to untrusted programs
+type FunctionCapability string
+
+const (
+	FUNCTION_CAPABILITY_NONDETERMINISTIC FunctionCapability = "nondeterministic" // the result can differ between runs with the same input, such as the current time
+	FUNCTION_CAPABILITY_IO               FunctionCapability = "io"               // the function reads or writes data outside of the program, such as lookup tables or state stores
+	FUNCTION_CAPABILITY_CLOCK            FunctionCapability = "clock"            // the function reads the current time from the run's Clock
+	FUNCTION_CAPABILITY_EXPENSIVE        FunctionCapability = "expensive"        // the function is costly to run
+)
+
+func WithCapabilities(capabilities ...FunctionCapability) functionEntryOpt {
+	return func(fe *FunctionEntry) {
+		fe.Capabilities = capabilities
+	}
+}
+
+// restricts the functions a program may call. the policy is checked when the program is compiled,
+// and covers INCLUDEd modules and the bodies of the Morph functions that the program calls.
+// the zero value allows every function.
+type FunctionPolicy struct {
+	// if non-nil, only these functions may be called. entries are a function name for the "std" namespace,
+	// a "namespace.name" pair, or "namespace.*" to allow every function in a namespace.
+	AllowedFunctions []string
+	// functions with any of these capabilities may not be called
+	DeniedCapabilities []FunctionCapability
+	// if true, the host fixes the run's Clock, so functions with FUNCTION_CAPABILITY_CLOCK, such as now(),
+	// are not denied for being FUNCTION_CAPABILITY_NONDETERMINISTIC
+	FixedClock bool
+}
+
+// rejects programs that call functions the policy does not allow
+func WithFunctionPolicy(policy FunctionPolicy) newProgramArg {
+	return func(pc *programConfig) {
+		pc.policy = policy
+	}
+}
+
+func (policy FunctionPolicy) check(prog *program, fstore *FunctionStore) error {
+	if policy.AllowedFunctions == nil && len(policy.DeniedCapabilities) == 0 {
+		return nil
+	}
+	return policy.checkCalls(prog, fstore, map[*program]bool{})
+}
+
+// checks the calls made by prog, and by the bodies of any Morph functions it calls.
+// checked holds the bodies that were already visited, so that recursive functions are only checked once.
+func (policy FunctionPolicy) checkCalls(prog *program, fstore *FunctionStore, checked map[*program]bool) error {
+	checked[prog] = true
+	calls := []functionCall{}
+	collectFunctionCalls(prog, &calls)
+	for _, call := range calls {
+		if !policy.allows(call) {
+			return fmt.Errorf("%s: function %s() is not allowed", call.lineCol, call.fullName())
+		}
+		fe, err := fstore.get(call.namespace, call.name)
+		if err != nil {
+			continue // unknown functions are reported when the program runs
+		}
+		for _, overload := range fe.Overloads() {
+			for _, capability := range overload.Capabilities {
+				if capability == FUNCTION_CAPABILITY_NONDETERMINISTIC && policy.FixedClock && slices.Contains(overload.Capabilities, FUNCTION_CAPABILITY_CLOCK) {
+					continue
+				}
+				if slices.Contains(policy.DeniedCapabilities, capability) {
+					return fmt.Errorf("%s: function %s() is not allowed: it has the denied capability %q", call.lineCol, call.fullName(), capability)
+				}
+			}
+			if overload.body == nil || checked[overload.body] {
+				continue
+			}
+			if err := policy.checkCalls(overload.body, fstore, checked); err != nil {
+				return fmt.Errorf("%s: function %s() is not allowed: %w", call.lineCol, call.fullName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func (policy FunctionPolicy) allows(call functionCall) bool {
+	if policy.AllowedFunctions == nil {
+		return true
+	}
+	for _, allowed := range policy.AllowedFunctions {
+		switch {
+		case allowed == call.fullName(),
+			allowed == call.namespace+".*",
+			call.namespace == "std" && allowed == call.name:
+			return true
+		}
+	}
+	return false
+}
+
+// a function called by a program
+type functionCall struct {
+	namespace string
+	name      string
+	lineCol   string
+	expr      *callExpression
+}
+
+func (fc functionCall) fullName() string {
+	return fmt.Sprintf("%s.%s", fc.namespace, fc.name)
+}
+
+// walks the syntax tree and collects every function call, including calls inside arrow functions
+func collectFunctionCalls(n node, calls *[]functionCall) {
+	switch v := n.(type) {
+	case *program:
+		for _, stmt := range v.statements {
+			collectFunctionCalls(stmt, calls)
+		}
+	case *setStatement:
+		collectFunctionCalls(v.target, calls)
+		collectFunctionCalls(v.value, calls)
+	case *delStatement:
+		collectFunctionCalls(v.target, calls)
+	case *paramStatement:
+		if v.value != nil {
+			collectFunctionCalls(v.value, calls)
+		}
+	case *ifStatement:
+		collectFunctionCalls(v.condition, calls)
+		for _, stmt := range v.consequence {
+			collectFunctionCalls(stmt, calls)
+		}
+	case *expressionStatement:
+		if v.expression != nil {
+			collectFunctionCalls(v.expression, calls)
+		}
+	case *prefixExpression:
+		collectFunctionCalls(v.right, calls)
+	case *infixExpression:
+		collectFunctionCalls(v.left, calls)
+		collectFunctionCalls(v.right, calls)
+	case *mapLiteral:
+		for _, value := range v.pairs {
+			collectFunctionCalls(value, calls)
+		}
+	case *arrayLiteral:
+		for _, entry := range v.entries {
+			collectFunctionCalls(entry, calls)
+		}
+	case *indexExpression:
+		collectFunctionCalls(v.left, calls)
+		collectFunctionCalls(v.index, calls)
+	case *pathExpression:
+		collectFunctionCalls(v.left, calls)
+		collectFunctionCalls(v.attribute, calls)
+	case *templateExpression:
+		for _, part := range v.parts {
+			collectFunctionCalls(part, calls)
+		}
+	case *callExpression:
+		if call, ok := newFunctionCall(v); ok {
+			*calls = append(*calls, call)
+		}
+		for _, arg := range v.arguments {
+			collectFunctionCalls(arg, calls)
+		}
+		for _, arg := range v.namedArguments {
+			collectFunctionCalls(arg.value, calls)
+		}
+	case *hasExpression:
+		collectFunctionCalls(v.target, calls)
+	case *arrowFunctionExpression:
+		for _, stmt := range v.block {
+			collectFunctionCalls(stmt, calls)
+		}
+	}
+}
+
+// resolves the namespace and name of a call, the same way the evaluator does
+func newFunctionCall(c *callExpression) (functionCall, bool) {
+	ret := functionCall{namespace: "std", lineCol: c.name.token().lineCol, expr: c}
+	switch name := c.name.(type) {
+	case *identifierExpression:
+		ret.name = name.value
+		return ret, true
+	case *pathExpression:
+		ns, nsOK := name.left.(*identifierExpression)
+		fn, fnOK := name.attribute.(*identifierExpression)
+		if !nsOK || !fnOK {
+			return ret, false
+		}
+		ret.namespace = ns.value
+		ret.name = fn.value
+		return ret, true
+	}
+	return ret, false
+}
+
+// returns a copy of the store that only contains the listed namespaces. use it to expose a limited set of functions to untrusted programs.
+func (fs *FunctionStore) Restrict(namespaces ...string) *FunctionStore {
+	ret := fs.Clone()
+	for name := range ret.namespaces {
+		if !slices.Contains(namespaces, name) {
+			delete(ret.namespaces, name)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,74 @@
+package lang
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCapabilityCollectFunctionCalls(t *testing.T) {
+	input := `SET x = len(@in.list) + max(1, 2)
+IF contains(@in.list, "a") :: {
+	SET y = map(@in.list, e ~> { SET return = string(e.value) | helpers.wrap() })
+}
+SET @out = 'now: ${now()}'`
+	program := setupParserTest(t, input)
+	calls := []functionCall{}
+	collectFunctionCalls(program, &calls)
+	got := []string{}
+	for _, call := range calls {
+		got = append(got, call.fullName())
+	}
+	slices.Sort(got)
+	want := []string{"helpers.wrap", "std.contains", "std.len", "std.map", "std.max", "std.now", "std.string"}
+	if !slices.Equal(want, got) {
+		t.Errorf("wrong function calls.\n\twant=%v\n\tgot=%v", want, got)
+	}
+}
+
+func TestCapabilityFunctionPolicy(t *testing.T) {
+	tests := []struct {
+		program string
+		policy  FunctionPolicy
+		wantErr string
+	}{
+		{`SET @out = len("abc")`, FunctionPolicy{AllowedFunctions: []string{"len"}}, ""},
+		{`SET @out = len("abc")`, FunctionPolicy{AllowedFunctions: []string{"std.*"}}, ""},
+		{`SET @out = len("abc")`, FunctionPolicy{AllowedFunctions: []string{}}, "1:12: function std.len() is not allowed"},
+		{`SET @out = map([1], e ~> { SET return = now() })`, FunctionPolicy{AllowedFunctions: []string{"map"}}, "1:41: function std.now() is not allowed"},
+		{`SET @out = state.incr("a")`, FunctionPolicy{AllowedFunctions: []string{"std.*", "state.get"}}, "function state.incr() is not allowed"},
+		{`SET @out = now()`, FunctionPolicy{DeniedCapabilities: []FunctionCapability{FUNCTION_CAPABILITY_CLOCK}}, `it has the denied capability "clock"`},
+		{`SET @out = state.incr("a")`, FunctionPolicy{DeniedCapabilities: []FunctionCapability{FUNCTION_CAPABILITY_NONDETERMINISTIC}}, `it has the denied capability "nondeterministic"`},
+		{`SET @out = now()`, FunctionPolicy{DeniedCapabilities: []FunctionCapability{FUNCTION_CAPABILITY_NONDETERMINISTIC}}, `function std.now() is not allowed: it has the denied capability "nondeterministic"`},
+		{`SET @out = now()`, FunctionPolicy{DeniedCapabilities: []FunctionCapability{FUNCTION_CAPABILITY_NONDETERMINISTIC}, FixedClock: true}, ""},
+		{`SET @out = state.incr("a")`, FunctionPolicy{DeniedCapabilities: []FunctionCapability{FUNCTION_CAPABILITY_NONDETERMINISTIC}, FixedClock: true}, `it has the denied capability "nondeterministic"`},
+		{`SET @out = lookup("a", "b")`, FunctionPolicy{DeniedCapabilities: []FunctionCapability{FUNCTION_CAPABILITY_IO}}, `function std.lookup() is not allowed`},
+		{`SET @out = len("abc")`, FunctionPolicy{DeniedCapabilities: []FunctionCapability{FUNCTION_CAPABILITY_IO}}, ""},
+	}
+	for _, tt := range tests {
+		_, err := NewProgram(tt.program, DefaultFunctionStore(), WithFunctionPolicy(tt.policy))
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", tt.program, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error to contain %q. got=%v", tt.program, tt.wantErr, err)
+		}
+	}
+}
+
+func TestCapabilityRestrict(t *testing.T) {
+	fstore := DefaultFunctionStore().Restrict("std")
+	if names := fstore.NamespaceNames(); !slices.Equal(names, []string{"std"}) {
+		t.Errorf("expected only the std namespace. got=%v", names)
+	}
+	program, err := NewProgram(`SET @out = state.incr("a")`, fstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := program.Run([]byte(`null`)); err == nil || !strings.Contains(err.Error(), `function namespace "state" does not exist`) {
+		t.Errorf("expected restricted namespace error. got=%v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package lang
+
+import (
+	"context"
+	"time"
+)
+
+// the source of the current time for now() and other time-relative functions.
+// hosts can provide their own clock to make program output reproducible in tests and replays.
+type Clock interface {
+	Now() time.Time
+}
+
+// a Clock that reads the system wall clock. it is used when no clock is set.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// a Clock that always returns the same time
+type FixedClock struct {
+	Time time.Time
+}
+
+func (fc FixedClock) Now() time.Time {
+	return fc.Time
+}
+
+type clockKey struct{}
+
+// returns the clock for the run, falling back to the system clock. custom functions that depend on the current time should read it from here, like now() does.
+func ClockFromContext(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock
+	}
+	return SystemClock{}
+}
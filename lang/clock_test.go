@@ -0,0 +1,45 @@
+package lang
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClockNow(t *testing.T) {
+	program, err := NewProgram(`SET @out = string(now())`, DefaultFunctionStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixed := time.Date(2024, 12, 31, 23, 59, 0, 0, time.FixedZone("EST", -5*60*60))
+	res, err := program.RunResult([]byte(`null`), WithClock(FixedClock{Time: fixed}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"2025-01-01T04:59:00Z"`; string(res.Output) != want {
+		t.Errorf("wrong time from clock. want=%s got=%s", want, res.Output)
+	}
+	// a nil clock falls back to the system clock
+	if _, err := program.RunResult([]byte(`null`), WithClock(nil)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClockFromContext(t *testing.T) {
+	fstore := NewFunctionStore()
+	fstore.Register(NewFunctionEntry("stamp", "", func(ctx context.Context, args ...*Object) *Object {
+		return CastTime(ClockFromContext(ctx).Now())
+	}, WithCapabilities(FUNCTION_CAPABILITY_NONDETERMINISTIC, FUNCTION_CAPABILITY_CLOCK)))
+	program, err := NewProgram(`SET @out = stamp()`, fstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixed := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	res, err := program.RunResult([]byte(`null`), WithClock(FixedClock{Time: fixed}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"2025-03-01T12:00:00Z"`; string(res.Output) != want {
+		t.Errorf("wrong time from a custom function. want=%s got=%s", want, res.Output)
+	}
+}
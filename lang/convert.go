@@ -1,32 +1,86 @@
 package lang
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // helpers for accessing objects from arbitrary types
 
-func convertBytesToObject(bytes []byte) object {
+// decodes JSON into an object, keeping the keys of each map in the order they appear in the data
+func convertBytesToObject(data []byte) object {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	obj, err := decodeJSONValue(dec)
+	if err == nil {
+		if _, trailingErr := dec.Token(); trailingErr == io.EOF {
+			return obj
+		}
+	}
+	// the decoder describes problems like trailing data differently from json.Unmarshal, so report the error json.Unmarshal gives
 	var raw interface{}
-	err := json.Unmarshal(bytes, &raw)
-	if err != nil {
-		return newObjectErrWithoutLC(fmt.Sprintf("invalid json: %s", err.Error()))
+	if unmarshalErr := json.Unmarshal(data, &raw); unmarshalErr != nil {
+		err = unmarshalErr
 	}
-	obj := convertAnyToObjectJSON(raw)
-	if isObjectErr(obj) {
-		return obj
+	if err == nil {
+		err = fmt.Errorf("invalid character after top-level value")
 	}
-	return obj
+	return newObjectErrWithoutLC(fmt.Sprintf("invalid json: %s", err.Error()))
 }
 
-func convertAnyToObjectJSON(rawData interface{}) object {
-	switch v := rawData.(type) {
-	case float64:
-		return convertNumberToObjectJSON(v)
+// decodes the next value from the decoder
+func decodeJSONValue(dec *json.Decoder) (object, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '[' {
+			ret := &objectArray{entries: []object{}}
+			for dec.More() {
+				entry, err := decodeJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				ret.entries = append(ret.entries, entry)
+			}
+			_, err := dec.Token()
+			return ret, err
+		}
+		ret := newObjectMap()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			entry, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			ret.set(keyTok.(string), entry)
+		}
+		_, err := dec.Token()
+		return ret, err
+	case json.Number:
+		num, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return convertNumberToObjectJSON(num), nil
 	default:
-		return convertAnyToObject(v, true)
+		return convertAnyToObject(v, true), nil
 	}
 }
 
@@ -43,26 +97,228 @@ func convertAnyToObject(rawData interface{}, isJSON bool) object {
 		return &objectString{value: v}
 	case time.Time:
 		return &objectTime{value: v}
+	case time.Duration:
+		return &objectDuration{value: v}
 	case map[string]interface{}:
 		return convertMapToObject(v, isJSON)
 	case []interface{}:
 		return convertArrayToObject(v, isJSON)
 	default:
-		msg := fmt.Sprintf("unable to read data into object: %+v", v)
-		return newObjectErrWithoutLC(msg)
+		return convertValueToObject(reflect.ValueOf(v), isJSON, 0)
 	}
 }
 
-func convertMapToObject(m map[string]interface{}, isJSON bool) object {
-	ret := &objectMap{
-		kvPairs: make(map[string]object),
+// deep enough for any reasonable data, while still catching cyclic pointers
+const maxConvertDepth = 1000
+
+var (
+	goJSONMarshalerType = reflect.TypeFor[json.Marshaler]()
+	goTextMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// converts arbitrary Go values using reflection, following the same rules as encoding/json:
+// struct fields honor json tags and omitempty, and json.Marshaler and encoding.TextMarshaler implementations are used when present.
+func convertValueToObject(v reflect.Value, isJSON bool, depth int) object {
+	if !v.IsValid() {
+		return obj_global_null
+	}
+	if depth > maxConvertDepth {
+		return newObjectErrWithoutLC(fmt.Sprintf("unable to read data into object: value of type %s is too deeply nested or cyclic", v.Type()))
+	}
+	t := v.Type()
+	switch {
+	case t == goObjectType:
+		if v.IsNil() {
+			return obj_global_null
+		}
+		return v.Interface().(*Object).inner
+	case t == goTimeType:
+		return &objectTime{value: v.Interface().(time.Time)}
+	case t == goDurationType:
+		return &objectDuration{value: time.Duration(v.Int())}
+	}
+	if (t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface) && v.IsNil() {
+		return obj_global_null
+	}
+	if v.CanInterface() && t.Implements(goJSONMarshalerType) {
+		b, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return newObjectErrWithoutLC(fmt.Sprintf("unable to read data into object: %s", err.Error()))
+		}
+		return convertBytesToObject(b)
+	}
+	if v.CanInterface() && t.Implements(goTextMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return newObjectErrWithoutLC(fmt.Sprintf("unable to read data into object: %s", err.Error()))
+		}
+		return &objectString{value: string(b)}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &objectString{value: v.String()}
+	case reflect.Bool:
+		return objectFromBoolean(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &objectInteger{value: v.Int()}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return newObjectErrWithoutLC(fmt.Sprintf("unable to read data into object: integer %d overflows INTEGER", v.Uint()))
+		}
+		return &objectInteger{value: int64(v.Uint())}
+	case reflect.Float32, reflect.Float64:
+		if isJSON {
+			return convertNumberToObjectJSON(v.Float())
+		}
+		return &objectFloat{value: v.Float()}
+	case reflect.Interface, reflect.Pointer:
+		return convertValueToObject(v.Elem(), isJSON, depth+1)
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && v.IsNil() {
+			return obj_global_null
+		}
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// byte slices are base64 encoded, like encoding/json does
+			return &objectString{value: base64.StdEncoding.EncodeToString(v.Bytes())}
+		}
+		ret := &objectArray{entries: []object{}}
+		for idx := range v.Len() {
+			entry := convertValueToObject(v.Index(idx), isJSON, depth+1)
+			if isObjectErr(entry) {
+				return entry
+			}
+			ret.entries = append(ret.entries, entry)
+		}
+		return ret
+	case reflect.Map:
+		if v.IsNil() {
+			return obj_global_null
+		}
+		entries := map[string]object{}
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := convertMapKeyToString(iter.Key())
+			if err != nil {
+				return newObjectErrWithoutLC(fmt.Sprintf("unable to read data into object: %s", err.Error()))
+			}
+			entry := convertValueToObject(iter.Value(), isJSON, depth+1)
+			if isObjectErr(entry) {
+				return entry
+			}
+			entries[key] = entry
+		}
+		ret := newObjectMap()
+		for _, key := range sortedMapKeys(entries) {
+			ret.set(key, entries[key])
+		}
+		return ret
+	case reflect.Struct:
+		ret := newObjectMap()
+		if errObj := convertStructFields(v, ret, isJSON, depth); errObj != nil {
+			return errObj
+		}
+		return ret
+	}
+	return newObjectErrWithoutLC(fmt.Sprintf("unable to read data into object: unsupported type %s", t))
+}
+
+// converts map keys the way encoding/json does: strings as is, integers in base 10, and text marshalers by their text
+func convertMapKeyToString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if k.CanInterface() && k.Type().Implements(goTextMarshalerType) {
+		b, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported map key type %s", k.Type())
+}
+
+// adds the exported fields of the struct to ret, in declaration order. fields of embedded structs are promoted,
+// unless a field of the outer struct has the same name.
+func convertStructFields(v reflect.Value, ret *objectMap, isJSON bool, depth int) object {
+	t := v.Type()
+	promoted := newObjectMap()
+	for idx := range t.NumField() {
+		field := t.Field(idx)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldValue := v.Field(idx)
+		if field.Anonymous && len(name) == 0 {
+			embedded := fieldValue
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if errObj := convertStructFields(embedded, promoted, isJSON, depth+1); errObj != nil {
+					return errObj
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+		if slices.Contains(strings.Split(opts, ","), "omitempty") && isEmptyValue(fieldValue) {
+			continue
+		}
+		entry := convertValueToObject(fieldValue, isJSON, depth+1)
+		if isObjectErr(entry) {
+			return entry
+		}
+		ret.set(name, entry)
+	}
+	for _, name := range promoted.keys {
+		if _, ok := ret.kvPairs[name]; !ok {
+			ret.set(name, promoted.kvPairs[name])
+		}
 	}
-	for k, v := range m {
-		objToAdd := convertAnyToObject(v, isJSON)
+	return nil
+}
+
+// reports whether the value is empty for the purposes of omitempty, matching encoding/json
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// go maps have no order, so keys are sorted to keep the output deterministic
+func sortedMapKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
+
+func convertMapToObject(m map[string]interface{}, isJSON bool) object {
+	ret := newObjectMap()
+	for _, k := range sortedMapKeys(m) {
+		objToAdd := convertAnyToObject(m[k], isJSON)
 		if isObjectErr(objToAdd) {
 			return objToAdd
 		}
-		ret.kvPairs[k] = objToAdd
+		ret.set(k, objToAdd)
 	}
 	return ret
 }
@@ -114,6 +370,65 @@ func convertNumberToObjectJSON(num float64) object {
 
 // obj -> type helpers
 
+// converts objects to their JSON encoded form, writing map keys in insertion order
+func convertObjectToJSON(o object) ([]byte, error) {
+	return convertObjectToJSONOrdered(o, false)
+}
+
+// converts objects to their JSON encoded form, writing map keys in insertion order, or sorted if sortKeys is set
+func convertObjectToJSONOrdered(o object, sortKeys bool) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := encodeObjectJSON(buf, o, sortKeys); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeObjectJSON(buf *bytes.Buffer, o object, sortKeys bool) error {
+	switch v := o.(type) {
+	case *objectMap:
+		buf.WriteByte('{')
+		for idx, key := range v.orderedKeys(sortKeys) {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			keyBytes, err := json.Marshal(key)
+			if err != nil {
+				return err
+			}
+			buf.Write(keyBytes)
+			buf.WriteByte(':')
+			if err := encodeObjectJSON(buf, v.kvPairs[key], sortKeys); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case *objectArray:
+		buf.WriteByte('[')
+		for idx, entry := range v.entries {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeObjectJSON(buf, entry, sortKeys); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+	native, err := convertObjectToNative(o)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(native)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
 // converts objects to their go-native type. needs to be asserted to use properly after calling this func
 func convertObjectToNative(o object) (interface{}, error) {
 	switch v := o.(type) {
@@ -133,6 +448,10 @@ func convertObjectToNative(o object) (interface{}, error) {
 		return v.value, nil
 	case *objectTime:
 		return v.value, nil
+	case *objectDuration:
+		return v.value.String(), nil // durations are encoded in the format accepted by duration(), such as "1h30m0s"
+	case *objectCustom:
+		return v.toNative()
 	case *objectError:
 		return nil, objectToError(o)
 	default:
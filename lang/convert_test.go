@@ -1,6 +1,9 @@
 package lang
 
 import (
+	"encoding/json"
+	"net/netip"
+	"strings"
 	"testing"
 	"time"
 )
@@ -23,6 +26,83 @@ func TestConvertObjectFromBytes(t *testing.T) {
 	testConvertObject(t, obj, want)
 }
 
+type testConvertAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type testConvertUser struct {
+	testConvertAddress
+	Name     string          `json:"name"`
+	Age      uint8           `json:"age"`
+	Email    *string         `json:"email"`
+	Nickname string          `json:"nickname,omitempty"`
+	Password string          `json:"-"`
+	IP       netip.Addr      `json:"ip"`
+	Extra    json.RawMessage `json:"extra"`
+	Scores   map[int]float32 `json:"scores"`
+	Tags     []string
+	internal string
+}
+
+func TestConvertReflect(t *testing.T) {
+	user := &testConvertUser{
+		testConvertAddress: testConvertAddress{City: "Boston"},
+		Name:               "ada",
+		Age:                36,
+		Password:           "secret",
+		IP:                 netip.MustParseAddr("10.0.0.1"),
+		Extra:              json.RawMessage(`{"nested": [1, 2.5]}`),
+		Scores:             map[int]float32{1: 0.5},
+		Tags:               []string{"a", "b"},
+		internal:           "hidden",
+	}
+	tests := []struct {
+		input interface{}
+		want  interface{}
+	}{
+		{[]string{"a", "b"}, []interface{}{"a", "b"}},
+		{map[string]int{"x": 1}, map[string]interface{}{"x": 1}},
+		{int8(-3), -3},
+		{uint64(7), 7},
+		{(*testConvertUser)(nil), nil},
+		{[]byte("hi"), "aGk="},
+		{json.RawMessage(`[true, null]`), []interface{}{true, nil}},
+		{user, map[string]interface{}{
+			"city":   "Boston",
+			"name":   "ada",
+			"age":    36,
+			"email":  nil,
+			"ip":     "10.0.0.1",
+			"extra":  map[string]interface{}{"nested": []interface{}{1, 2.5}},
+			"scores": map[string]interface{}{"1": 0.5},
+			"Tags":   []interface{}{"a", "b"},
+		}},
+	}
+	for _, tt := range tests {
+		obj := convertAnyToObject(tt.input, false)
+		if isObjectErr(obj) {
+			t.Errorf("%T: %v", tt.input, objectToError(obj))
+			continue
+		}
+		testConvertObject(t, obj, tt.want)
+	}
+	if obj, ok := convertAnyToObject(user, false).(*objectMap); !ok || len(obj.kvPairs) != 8 {
+		t.Errorf("expected omitted, ignored, and unexported fields to be skipped. got=%s", obj.inspect())
+	}
+
+	errTests := []interface{}{
+		uint64(1 << 63),
+		map[float64]string{1.5: "x"},
+		make(chan int),
+	}
+	for _, input := range errTests {
+		if obj := convertAnyToObject(input, false); !isObjectErr(obj) {
+			t.Errorf("%T: expected conversion error. got=%s", input, obj.inspect())
+		}
+	}
+}
+
 func testConvertObject(t *testing.T, data object, want interface{}) bool {
 	switch v := want.(type) {
 	case nil:
@@ -167,3 +247,43 @@ func testConvertObjectMap(t *testing.T, data object, want map[string]interface{}
 
 	return true
 }
+
+func TestConvertKeyOrder(t *testing.T) {
+	data := `{"b":1,"a":{"d":[1,2.5,"x"],"c":null},"e":true}`
+	obj := convertBytesToObject([]byte(data))
+	if isObjectErr(obj) {
+		t.Fatal(objectToError(obj))
+	}
+	got, err := convertObjectToJSON(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != data {
+		t.Errorf("wrong key order. want=%s got=%s", data, got)
+	}
+	got, err = convertObjectToJSONOrdered(obj, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"a":{"c":null,"d":[1,2.5,"x"]},"b":1,"e":true}`
+	if string(got) != want {
+		t.Errorf("wrong sorted keys. want=%s got=%s", want, got)
+	}
+
+	// go maps have no order of their own, so their keys are sorted
+	obj = convertAnyToObject(map[string]interface{}{"z": 1, "y": 2, "x": 3}, false)
+	got, err = convertObjectToJSON(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"x":3,"y":2,"z":1}` {
+		t.Errorf("wrong key order for go map. got=%s", got)
+	}
+
+	for _, invalid := range []string{``, `{"a": 1} {"b": 2}`, `{"a": }`, `[1, 2`, `1e400`} {
+		obj := convertBytesToObject([]byte(invalid))
+		if !isObjectErr(obj) || !strings.HasPrefix(obj.inspect(), "invalid json: ") {
+			t.Errorf("%q: expected invalid json error. got=%s", invalid, obj.inspect())
+		}
+	}
+}
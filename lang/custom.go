@@ -0,0 +1,180 @@
+package lang
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+)
+
+// describes a host-defined type whose values pass through programs as opaque objects.
+// programs can assign, compare, and return custom values, but only functions written by the host can look inside them.
+type CustomType[T any] struct {
+	name     string
+	behavior CustomTypeBehavior[T]
+}
+
+// controls how values of a custom type behave inside programs. any nil field falls back to the default described on it.
+type CustomTypeBehavior[T any] struct {
+	IsTruthy    func(value T) bool            // used by conditionals and the && / || operators. defaults to always true
+	Equal       func(a, b T) bool             // used by the == and != operators. defaults to reflect.DeepEqual
+	Clone       func(value T) T               // used when values are copied between variables. defaults to returning the value as is, which suits immutable values
+	Inspect     func(value T) string          // used for debug output. defaults to fmt.Sprint
+	MarshalJSON func(value T) ([]byte, error) // used when a value ends up in @out or is passed out of a program. defaults to json.Marshal
+}
+
+var customTypeNameRegex = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// creates a custom type. the name must be upper case, like the built in types, and cannot be the name of a built in type.
+// the name can be used in FunctionArg.Types and FunctionReturn.Types through Type(). register the type with FunctionStore.RegisterType
+// on each store whose functions use it, so that the store can reject a different type with the same name.
+func NewCustomType[T any](name string, behavior CustomTypeBehavior[T]) (*CustomType[T], error) {
+	if !customTypeNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid custom type name %q: names must be upper case letters, digits, and underscores, starting with a letter", name)
+	}
+	if slices.Contains(ANY, PublicType(name)) || slices.Contains([]PublicType{"ANY", "BASIC", PublicType(t_terminate)}, PublicType(name)) {
+		return nil, fmt.Errorf("invalid custom type name %q: the name is reserved for a built in type", name)
+	}
+	return &CustomType[T]{name: name, behavior: behavior}, nil
+}
+
+// returns the type's name, for use in function signatures
+func (ct *CustomType[T]) Type() PublicType {
+	return PublicType(ct.name)
+}
+
+// a custom type of any Go type, such as *CustomType[netip.Addr]
+type AnyCustomType interface {
+	Type() PublicType
+	customKind
+}
+
+// registers a custom type with the store. arguments are checked by type name,
+// so registering a different type with the same name as a type already in the store returns an error.
+func (fs *FunctionStore) RegisterType(ct AnyCustomType) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.frozen {
+		return errFunctionStoreFrozen
+	}
+	if err := fs.checkType(ct); err != nil {
+		return err
+	}
+	fs.customTypes[ct.typeName()] = ct
+	return nil
+}
+
+// callers must hold the lock
+func (fs *FunctionStore) checkType(ct customKind) error {
+	if existing, ok := fs.customTypes[ct.typeName()]; ok && existing != ct {
+		return fmt.Errorf("invalid custom type name %q: a different custom type with the name is already registered", ct.typeName())
+	}
+	return nil
+}
+
+// wraps the value as an object of the custom type
+func CastCustom[T any](ct *CustomType[T], value T) *Object {
+	return &Object{inner: &objectCustom{kind: ct, value: value}}
+}
+
+// unwraps a value of a custom type. fails if the object is not a custom value holding a T.
+func AsCustom[T any](o *Object) (T, error) {
+	var zero T
+	c, ok := o.inner.(*objectCustom)
+	if !ok {
+		return zero, fmt.Errorf("unable to convert object to custom type: underlying structure is not a custom type. got=%s", o.inner.getType())
+	}
+	value, ok := c.value.(T)
+	if !ok {
+		return zero, fmt.Errorf("unable to convert object of custom type %s: wanted Go type %T, got %T", c.kind.typeName(), zero, c.value)
+	}
+	return value, nil
+}
+
+// the type-erased view of a CustomType used by objectCustom
+type customKind interface {
+	typeName() string
+	truthy(value any) bool
+	equal(a, b any) bool
+	cloneValue(value any) any
+	inspectValue(value any) string
+	marshalValue(value any) ([]byte, error)
+}
+
+func (ct *CustomType[T]) typeName() string { return ct.name }
+
+func (ct *CustomType[T]) truthy(value any) bool {
+	if ct.behavior.IsTruthy == nil {
+		return true
+	}
+	return ct.behavior.IsTruthy(value.(T))
+}
+
+func (ct *CustomType[T]) equal(a, b any) bool {
+	aVal, aOk := a.(T)
+	bVal, bOk := b.(T)
+	if !aOk || !bOk {
+		return false
+	}
+	if ct.behavior.Equal == nil {
+		return reflect.DeepEqual(aVal, bVal)
+	}
+	return ct.behavior.Equal(aVal, bVal)
+}
+
+func (ct *CustomType[T]) cloneValue(value any) any {
+	if ct.behavior.Clone == nil {
+		return value
+	}
+	return ct.behavior.Clone(value.(T))
+}
+
+func (ct *CustomType[T]) inspectValue(value any) string {
+	if ct.behavior.Inspect == nil {
+		return fmt.Sprint(value)
+	}
+	return ct.behavior.Inspect(value.(T))
+}
+
+func (ct *CustomType[T]) marshalValue(value any) ([]byte, error) {
+	if ct.behavior.MarshalJSON == nil {
+		return json.Marshal(value)
+	}
+	return ct.behavior.MarshalJSON(value.(T))
+}
+
+type objectCustom struct {
+	kind  customKind
+	value any
+}
+
+func (c *objectCustom) getType() objectType { return objectType(c.kind.typeName()) }
+func (c *objectCustom) inspect() string     { return c.kind.inspectValue(c.value) }
+func (c *objectCustom) clone() object {
+	return &objectCustom{kind: c.kind, value: c.kind.cloneValue(c.value)}
+}
+func (c *objectCustom) isTruthy() bool { return c.kind.truthy(c.value) }
+
+// reports whether both values are of the same custom type and equal according to it
+func (c *objectCustom) equals(other object) bool {
+	o, ok := other.(*objectCustom)
+	if !ok || o.kind != c.kind {
+		return false
+	}
+	return c.kind.equal(c.value, o.value)
+}
+
+// converts the value to plain Go data by round tripping through its JSON encoding,
+// so that custom values leaving a program look the same as they do in @out
+func (c *objectCustom) toNative() (interface{}, error) {
+	b, err := c.kind.marshalValue(c.value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode value of custom type %s: %w", c.kind.typeName(), err)
+	}
+	var ret interface{}
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return nil, fmt.Errorf("unable to encode value of custom type %s: %w", c.kind.typeName(), err)
+	}
+	return ret, nil
+}
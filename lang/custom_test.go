@@ -0,0 +1,207 @@
+package lang
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func testCustomIPType(t *testing.T) *CustomType[netip.Addr] {
+	ipType, err := NewCustomType("IP", CustomTypeBehavior[netip.Addr]{
+		IsTruthy: func(value netip.Addr) bool { return !value.IsUnspecified() },
+		Equal:    func(a, b netip.Addr) bool { return a == b },
+		Inspect:  func(value netip.Addr) string { return value.String() },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ipType
+}
+
+func testCustomIPStore(t *testing.T) (*FunctionStore, *CustomType[netip.Addr]) {
+	ipType := testCustomIPType(t)
+	fstore := NewFunctionStore()
+	if err := fstore.RegisterType(ipType); err != nil {
+		t.Fatal(err)
+	}
+	fstore.Register(NewFunctionEntry("ip", "parses an IP address", func(ctx context.Context, args ...*Object) *Object {
+		str, _ := args[0].AsString()
+		addr, err := netip.ParseAddr(str)
+		if err != nil {
+			return ObjectError(err.Error())
+		}
+		return CastCustom(ipType, addr)
+	}, WithArgs(
+		NewFunctionArg("addr", "", STRING),
+	), WithReturn(
+		NewFunctionReturn("", ipType.Type()),
+	)))
+	fstore.Register(NewFunctionEntry("is_private", "reports whether the IP address is private", func(ctx context.Context, args ...*Object) *Object {
+		addr, err := AsCustom[netip.Addr](args[0])
+		if err != nil {
+			return ObjectError(err.Error())
+		}
+		return CastBool(addr.IsPrivate())
+	}, WithArgs(
+		NewFunctionArg("addr", "", ipType.Type()),
+	), WithReturn(
+		NewFunctionReturn("", BOOLEAN),
+	)))
+	return fstore, ipType
+}
+
+func TestCustomType(t *testing.T) {
+	fstore, _ := testCustomIPStore(t)
+	fe, _ := fstore.get("std", "is_private")
+	if want := "std.is_private(addr:IP) BOOLEAN"; fe.Signature() != want {
+		t.Errorf("wrong signature. want=%s got=%s", want, fe.Signature())
+	}
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = ip(@in.a)`, `"10.0.0.1"`},
+		{`SET @out = [ip(@in.a), ip(@in.b)]`, `["10.0.0.1","8.8.8.8"]`},
+		{`SET @out = is_private(ip(@in.a))`, `true`},
+		{`SET @out = ip(@in.a) == ip("10.0.0.1")`, `true`},
+		{`SET @out = ip(@in.a) != ip(@in.b)`, `true`},
+		{`SET @out = ip(@in.a) == @in.a`, `false`},
+		{`SET @out = ip(@in.a) && true`, `true`},
+		{`SET @out = ip("0.0.0.0") || false`, `false`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, `{"a": "10.0.0.1", "b": "8.8.8.8"}`, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %v", tt.program, err)
+		}
+	}
+
+	program, err := NewProgram(`SET @out = is_private(@in.a)`, fstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = program.Run([]byte(`{"a": "10.0.0.1"}`))
+	if err == nil || !strings.Contains(err.Error(), "want=IP. got=STRING") {
+		t.Errorf("expected type error for IP argument. got=%v", err)
+	}
+}
+
+func TestCustomTypeAny(t *testing.T) {
+	fstore, ipType := testCustomIPStore(t)
+	first, err := NewMorphFunctionEntry("first_private", []string{"a", "b"}, `
+	IF is_private(a) :: SET return = a
+	IF !is_private(a) :: SET return = b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstore.Register(first); err != nil {
+		t.Fatal(err)
+	}
+	err = RegisterGo(fstore, "std", "same", func(a, b *Object) (bool, error) {
+		addrA, err := AsCustom[netip.Addr](a)
+		if err != nil {
+			return false, err
+		}
+		addrB, err := AsCustom[netip.Addr](b)
+		if err != nil {
+			return false, err
+		}
+		return addrA == addrB, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstore.Register(NewFunctionEntry("ips", "wraps IP addresses in an array", func(ctx context.Context, args ...*Object) *Object {
+		return CastArray([]interface{}{args[0], args[1]})
+	}, WithArgs(
+		NewFunctionArg("a", "", ipType.Type()),
+		NewFunctionArg("b", "", ipType.Type()),
+	), WithReturn(
+		NewFunctionReturn("", ArrayOf(ANY...)),
+	))); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = first_private(ip(@in.b), ip(@in.a))`, `"10.0.0.1"`},
+		{`SET @out = same(ip(@in.a), ip("10.0.0.1"))`, `true`},
+		{`SET @out = ips(ip(@in.a), ip(@in.b))`, `["10.0.0.1", "8.8.8.8"]`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, `{"a": "10.0.0.1", "b": "8.8.8.8"}`, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %v", tt.program, err)
+		}
+	}
+}
+
+func TestCustomTypeConvert(t *testing.T) {
+	_, ipType := testCustomIPStore(t)
+	obj := CastCustom(ipType, netip.MustParseAddr("192.168.1.1"))
+	if obj.Type() != "IP" {
+		t.Errorf("wrong type. want=IP got=%s", obj.Type())
+	}
+	addr, err := AsCustom[netip.Addr](obj)
+	if err != nil || addr.String() != "192.168.1.1" {
+		t.Errorf("wrong unwrapped value. got=%v err=%v", addr, err)
+	}
+	if _, err := AsCustom[string](obj); err == nil {
+		t.Error("expected error unwrapping into the wrong Go type")
+	}
+	if _, err := AsCustom[netip.Addr](CastString("192.168.1.1")); err == nil {
+		t.Error("expected error unwrapping a non-custom object")
+	}
+	b, err := obj.MarshalJSON()
+	if err != nil || string(b) != `"192.168.1.1"` {
+		t.Errorf("wrong JSON encoding. got=%s err=%v", b, err)
+	}
+}
+
+func TestCustomTypeName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{"ip", "invalid custom type name"},
+		{"", "invalid custom type name"},
+		{"ARRAY<IP>", "invalid custom type name"},
+		{"STRING", "reserved"},
+		{"ANY", "reserved"},
+	}
+	for _, tt := range tests {
+		_, err := NewCustomType(tt.name, CustomTypeBehavior[int]{})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: expected error to contain %q. got=%v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestCustomTypeRegister(t *testing.T) {
+	fstore, ipType := testCustomIPStore(t)
+	if err := fstore.RegisterType(ipType); err != nil {
+		t.Errorf("expected registering the same type twice to succeed. got=%v", err)
+	}
+	otherIPType := testCustomIPType(t)
+	if err := fstore.RegisterType(otherIPType); err == nil || !strings.Contains(err.Error(), `invalid custom type name "IP": a different custom type with the name is already registered`) {
+		t.Errorf("expected duplicate type name error. got=%v", err)
+	}
+
+	// type names only need to be unique within a store
+	otherStore := NewFunctionStore()
+	if err := otherStore.RegisterType(otherIPType); err != nil {
+		t.Fatal(err)
+	}
+	if err := fstore.Clone().Merge(otherStore); err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("expected merge to reject a duplicate type name. got=%v", err)
+	}
+	if err := NewFunctionStore().Merge(fstore); err != nil {
+		t.Errorf("unexpected merge error. got=%v", err)
+	}
+
+	frozen := NewFunctionStore()
+	frozen.Freeze()
+	if err := frozen.RegisterType(ipType); err == nil {
+		t.Error("expected registering a type with a frozen store to fail")
+	}
+}
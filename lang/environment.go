@@ -8,6 +8,13 @@ type environment struct {
 	ctx           context.Context
 	store         map[string]object
 	functionStore *FunctionStore
+	termination   *objectTerminate // set when the program is halted early by a flow control function like drop() or emit()
+	vars          map[string]interface{}
+	lookups       LookupProvider
+	state         StateStore
+	clock         Clock
+	strict        bool // when set, reading a missing path or an undefined variable is an error instead of NULL
+	sortKeys      bool // when set, map keys in the encoded output are sorted instead of kept in insertion order
 }
 
 func newEnvironment(fstore *FunctionStore, opts ...newEnvArg) *environment {
@@ -26,6 +33,47 @@ func WithContext(ctx context.Context) newEnvArg {
 	}
 }
 
+// exposes the values to the program as the read-only @vars variable
+func WithVars(vars map[string]interface{}) newEnvArg {
+	return func(e *environment) {
+		e.vars = vars
+	}
+}
+
+// sets the provider used by the lookup functions, overriding the function store's provider.
+// a nil provider leaves the function store's provider in place.
+func WithLookupProvider(provider LookupProvider) newEnvArg {
+	return func(e *environment) {
+		if provider != nil {
+			e.lookups = provider
+		}
+	}
+}
+
+// sets the store used by the state functions. a nil store leaves the state functions unavailable.
+func WithStateStore(store StateStore) newEnvArg {
+	return func(e *environment) {
+		e.state = store
+	}
+}
+
+// sets the clock used by now() and other time-relative functions. a nil clock leaves the system clock in place.
+func WithClock(clock Clock) newEnvArg {
+	return func(e *environment) {
+		if clock != nil {
+			e.clock = clock
+		}
+	}
+}
+
+// encodes the keys of maps in the output and records in sorted order, rather than the order they were first set in.
+// maps inside the program, such as in templates and inspect output, keep their insertion order either way.
+func WithSortedKeys(sorted bool) newEnvArg {
+	return func(e *environment) {
+		e.sortKeys = sorted
+	}
+}
+
 func (e *environment) get(name string) (object, bool) {
 	ret, ok := e.store[name]
 	return ret, ok
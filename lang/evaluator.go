@@ -1,10 +1,12 @@
 package lang
 
 import (
+	"cmp"
 	"fmt"
 	"math"
 	"slices"
 	"strings"
+	"time"
 )
 
 var (
@@ -25,6 +27,7 @@ func (p *program) eval(env *environment) object {
 		if !ok {
 			if obj.getType() == t_terminate {
 				term := obj.(*objectTerminate)
+				env.termination = term
 				if term.shouldReturnNull {
 					env.store = map[string]object{}
 				}
@@ -78,8 +81,7 @@ func evalSetStatementHandleENV(current *assignPath, valToSet object, env *enviro
 	}
 	existing, ok := env.get(current.partName)
 	if !ok {
-		newMap := &objectMap{kvPairs: make(map[string]object)}
-		return env.set(current.partName, newMap)
+		return env.set(current.partName, newObjectMap())
 	}
 	if existing.getType() != t_map {
 		msg := fmt.Sprintf("invalid path part for SET statement: cannot use a path expression on a non-map object. Object is of type %s", existing.getType())
@@ -95,13 +97,13 @@ func evalSetStatementHandleMAP(objHandle object, current *assignPath, valToSet o
 		return newObjectErrWithoutLC(msg)
 	}
 	if current.next == nil {
-		mapObj.kvPairs[current.partName] = valToSet
+		mapObj.set(current.partName, valToSet)
 		return obj_global_null
 	}
 	existing, ok := mapObj.kvPairs[current.partName]
 	if !ok {
-		newMap := &objectMap{kvPairs: make(map[string]object)}
-		mapObj.kvPairs[current.partName] = newMap
+		newMap := newObjectMap()
+		mapObj.set(current.partName, newMap)
 		return newMap
 	}
 	if existing.getType() != t_map {
@@ -117,6 +119,16 @@ func (d *delStatement) eval(env *environment) object {
 	case *identifierExpression:
 		delete(env.store, v.value)
 	case *pathExpression:
+		if env.strict {
+			// deleting beneath a missing path does nothing, so it is not treated as a read of the missing path
+			_, found, errObj := evalLookup(v.left, env)
+			if errObj != nil {
+				return errObj
+			}
+			if !found {
+				return obj_global_null
+			}
+		}
 		leftObj := v.left.eval(env)
 		leftObj, ok := checkEvalResultLC(leftObj, v.left.token().lineCol)
 		if !ok {
@@ -135,7 +147,7 @@ func (d *delStatement) eval(env *environment) object {
 		if errObj != obj_global_null {
 			return wrapErr(v.attribute.token().lineCol, errObj)
 		}
-		delete(leftMap.kvPairs, attrString)
+		leftMap.delete(attrString)
 	}
 	return obj_global_null
 }
@@ -164,6 +176,35 @@ func evalMapPathAttributeToString(attribute pathPartExpression, env *environment
 	}
 }
 
+//
+// param statement
+
+// assigns the default value to @vars.name if the host did not provide it
+func (ps *paramStatement) eval(env *environment) object {
+	varsObj, ok := env.get("@vars")
+	if !ok {
+		varsObj = env.set("@vars", newObjectMap())
+	}
+	vars, ok := varsObj.(*objectMap)
+	if !ok {
+		return newObjectErr(ps.tok.lineCol, "@vars must be a map")
+	}
+	if _, ok := vars.kvPairs[ps.name.value]; ok {
+		return obj_global_null
+	}
+	if ps.value == nil {
+		msg := fmt.Sprintf("missing required parameter %q", ps.name.value)
+		return newObjectErr(ps.name.tok.lineCol, msg)
+	}
+	defaultVal := ps.value.eval(env)
+	defaultVal, ok = checkEvalResultLC(defaultVal, ps.value.token().lineCol)
+	if !ok {
+		return defaultVal
+	}
+	vars.set(ps.name.value, defaultVal.clone())
+	return obj_global_null
+}
+
 //
 // if statement
 
@@ -222,7 +263,21 @@ func (c *callExpression) eval(env *environment) object {
 		toAdd := argExpr.eval(env)
 		args = append(args, toAdd)
 	}
-	ret := fnEntry.run(env.ctx, args...)
+	names := []string{}
+	values := []object{}
+	for _, named := range c.namedArguments {
+		names = append(names, named.name)
+		values = append(values, named.value.eval(env))
+	}
+	if c.overload != nil {
+		fnEntry = c.overload
+	} else {
+		fnEntry, args, err = fnEntry.resolve(args, names, values)
+		if err != nil {
+			return newObjectErr(c.name.token().lineCol, err.Error())
+		}
+	}
+	ret := fnEntry.call(env.ctx, args...)
 	ret, ok := checkEvalResultLC(ret, c.name.token().lineCol)
 	if !ok {
 		return ret
@@ -255,6 +310,10 @@ func (i *identifierExpression) eval(env *environment) object {
 	if res, ok := env.get(i.value); ok {
 		return res
 	}
+	if env.strict {
+		msg := fmt.Sprintf("undefined variable %q", i.value)
+		return newObjectErr(i.tok.lineCol, msg)
+	}
 	return obj_global_null
 }
 
@@ -310,6 +369,10 @@ func evalResolvePathEntryForKey(pathExpr *pathExpression, key string, env *envir
 	}
 
 	if leftObj == obj_global_null {
+		if env.strict {
+			msg := fmt.Sprintf("path %q does not exist: %q is NULL", pathExpr.string(), pathExpr.left.string())
+			return newObjectErr(pathExpr.left.token().lineCol, msg)
+		}
 		return leftObj
 	}
 	leftMap, ok := leftObj.(*objectMap)
@@ -319,6 +382,10 @@ func evalResolvePathEntryForKey(pathExpr *pathExpression, key string, env *envir
 	}
 	res, ok := leftMap.kvPairs[key] // if it is a map, but the item doesn't exist, we return null
 	if !ok {
+		if env.strict {
+			msg := fmt.Sprintf("path %q does not exist", pathExpr.string())
+			return newObjectErr(pathExpr.attribute.token().lineCol, msg)
+		}
 		return obj_global_null
 	}
 	return res
@@ -388,6 +455,8 @@ func evalHandlePrefixMinus(rightExpr *prefixExpression, rightObj object) object
 		return &objectInteger{value: -v.value}
 	case *objectFloat:
 		return &objectFloat{value: -v.value}
+	case *objectDuration:
+		return &objectDuration{value: -v.value}
 	default:
 		msg := fmt.Sprintf("incompatible non-numeric right-side expression for operator: %s", rightExpr.string())
 		return newObjectErr(rightExpr.tok.lineCol, msg)
@@ -426,6 +495,13 @@ func (i *infixExpression) eval(env *environment) object {
 			return ret
 		}
 
+		return ret
+	case isTemporalInfix(leftObj, rightObj):
+		ret := evalTemporalInfixExpression(leftObj, i.operator, rightObj)
+		ret, ok := checkEvalResultLC(ret, i.token().lineCol)
+		if !ok {
+			return ret
+		}
 		return ret
 	case leftObj.getType() == t_array && rightObj.getType() == t_array:
 		ret := evalArrayInfixExpression(leftObj, i.operator, rightObj)
@@ -441,16 +517,17 @@ func (i *infixExpression) eval(env *environment) object {
 		if leftObj.getType() != rightObj.getType() {
 			return obj_global_false
 		}
-		if leftObj.getType() == t_time {
-			lTime := leftObj.(*objectTime) // don't really need to bool check type assertions; we know that type are equal, and that they're of type t_time.
-			rTime := rightObj.(*objectTime)
-			return objectFromBoolean(lTime.value.Equal(rTime.value))
+		if custom, ok := leftObj.(*objectCustom); ok {
+			return objectFromBoolean(custom.equals(rightObj))
 		}
 		return objectFromBoolean(leftObj == rightObj)
 	case i.operator == "!=":
 		if leftObj.getType() != rightObj.getType() {
 			return obj_global_true
 		}
+		if custom, ok := leftObj.(*objectCustom); ok {
+			return objectFromBoolean(!custom.equals(rightObj))
+		}
 		return objectFromBoolean(leftObj != rightObj)
 	case i.operator == "&&":
 		return objectFromBoolean(leftObj.isTruthy() && rightObj.isTruthy())
@@ -541,6 +618,126 @@ func objHandleMathOperation(l float64, operator string, r float64, areBothIntege
 	return &objectFloat{value: result}
 }
 
+// reports whether the operands are times, durations, or numbers combined with either, which are handled by evalTemporalInfixExpression
+func isTemporalInfix(leftObj object, rightObj object) bool {
+	operands := []objectType{t_time, t_duration, t_integer, t_float}
+	if !slices.Contains(operands, leftObj.getType()) || !slices.Contains(operands, rightObj.getType()) {
+		return false
+	}
+	return slices.Contains([]objectType{t_time, t_duration}, leftObj.getType()) || slices.Contains([]objectType{t_time, t_duration}, rightObj.getType())
+}
+
+// handles time and duration arithmetic and comparisons:
+// time + duration and time - duration give a time, time - time gives a duration, durations can be added, subtracted,
+// multiplied or divided by numbers, and divided by each other to get a FLOAT ratio. times and durations can each be compared.
+func evalTemporalInfixExpression(leftObj object, operator string, rightObj object) object {
+	switch l := leftObj.(type) {
+	case *objectTime:
+		switch r := rightObj.(type) {
+		case *objectTime:
+			if operator == "-" {
+				return &objectDuration{value: l.value.Sub(r.value)}
+			}
+			if ret, ok := evalCompareOperator(l.value.Compare(r.value), operator); ok {
+				return ret
+			}
+		case *objectDuration:
+			switch operator {
+			case "+":
+				return &objectTime{value: l.value.Add(r.value)}
+			case "-":
+				return &objectTime{value: l.value.Add(-r.value)}
+			}
+		}
+	case *objectDuration:
+		switch r := rightObj.(type) {
+		case *objectDuration:
+			switch operator {
+			case "+":
+				return addDurations(l.value, r.value)
+			case "-":
+				if r.value == math.MinInt64 {
+					return newObjectErrWithoutLC("DURATION is out of range")
+				}
+				return addDurations(l.value, -r.value)
+			case "/":
+				if r.value == 0 {
+					return newObjectErrWithoutLC("cannot divide a DURATION by a zero DURATION")
+				}
+				return &objectFloat{value: float64(l.value) / float64(r.value)}
+			}
+			if ret, ok := evalCompareOperator(cmp.Compare(l.value, r.value), operator); ok {
+				return ret
+			}
+		case *objectTime:
+			if operator == "+" {
+				return &objectTime{value: r.value.Add(l.value)}
+			}
+		case *objectInteger, *objectFloat:
+			num, _ := objectNumberToFloat64(r)
+			switch operator {
+			case "*":
+				return durationFromFloat(float64(l.value) * num)
+			case "/":
+				if num == 0 {
+					return newObjectErrWithoutLC("cannot divide a DURATION by zero")
+				}
+				return durationFromFloat(float64(l.value) / num)
+			}
+		}
+	case *objectInteger, *objectFloat:
+		if r, ok := rightObj.(*objectDuration); ok && operator == "*" {
+			num, _ := objectNumberToFloat64(l)
+			return durationFromFloat(float64(r.value) * num)
+		}
+	}
+	if leftObj.getType() != rightObj.getType() {
+		switch operator {
+		case "==":
+			return obj_global_false
+		case "!=":
+			return obj_global_true
+		}
+	}
+	msg := fmt.Sprintf("invalid operator for types: %s %s %s", leftObj.getType(), operator, rightObj.getType())
+	return newObjectErrWithoutLC(msg)
+}
+
+// applies a comparison operator to the result of a compare function. reports false if the operator is not a comparison.
+func evalCompareOperator(compared int, operator string) (object, bool) {
+	switch operator {
+	case "<":
+		return objectFromBoolean(compared < 0), true
+	case "<=":
+		return objectFromBoolean(compared <= 0), true
+	case ">":
+		return objectFromBoolean(compared > 0), true
+	case ">=":
+		return objectFromBoolean(compared >= 0), true
+	case "==":
+		return objectFromBoolean(compared == 0), true
+	case "!=":
+		return objectFromBoolean(compared != 0), true
+	}
+	return nil, false
+}
+
+func addDurations(l time.Duration, r time.Duration) object {
+	sum := l + r
+	if (r > 0 && sum < l) || (r < 0 && sum > l) {
+		return newObjectErrWithoutLC("DURATION is out of range")
+	}
+	return &objectDuration{value: sum}
+}
+
+// converts the result of duration arithmetic back to a duration, rounding to the nearest nanosecond
+func durationFromFloat(nanos float64) object {
+	if math.IsNaN(nanos) || nanos >= math.MaxInt64 || nanos < math.MinInt64 {
+		return newObjectErrWithoutLC("DURATION is out of range")
+	}
+	return &objectDuration{value: time.Duration(math.Round(nanos))}
+}
+
 func evalArrayInfixExpression(leftObj object, operator string, rightObj object) object {
 	lArr := leftObj.(*objectArray)
 	rArr := rightObj.(*objectArray)
@@ -564,6 +761,10 @@ func (i *indexExpression) eval(env *environment) object {
 	}
 
 	if identResult == obj_global_null {
+		if env.strict {
+			msg := fmt.Sprintf("cannot index %q: it is NULL", i.left.string())
+			return newObjectErr(i.left.token().lineCol, msg)
+		}
 		return identResult
 	}
 	arrObj, ok := identResult.(*objectArray)
@@ -605,6 +806,69 @@ func (a *arrowFunctionExpression) eval(env *environment) object {
 		paramName:  a.paramName.value,
 		statements: a.block,
 		functions:  env.functionStore,
+		ctx:        env.ctx,
+		strict:     env.strict,
+	}
+}
+
+//
+// has expr
+
+func (h *hasExpression) eval(env *environment) object {
+	_, found, errObj := evalLookup(h.target, env)
+	if errObj != nil {
+		return errObj
+	}
+	return objectFromBoolean(found)
+}
+
+// finds the value of an identifier, path, or index expression, and whether it exists.
+// unlike eval, a missing parent is reported as not found, even in strict mode.
+func evalLookup(expr expression, env *environment) (object, bool, object) {
+	switch v := expr.(type) {
+	case *identifierExpression:
+		res, ok := env.get(v.value)
+		return res, ok, nil
+	case *pathExpression:
+		parent, found, errObj := evalLookup(v.left, env)
+		if errObj != nil || !found {
+			return nil, false, errObj
+		}
+		parentMap, ok := parent.(*objectMap)
+		if !ok {
+			return nil, false, nil
+		}
+		key, errObj := evalMapPathAttributeToString(v.attribute, env)
+		if errObj != obj_global_null {
+			return nil, false, wrapErr(v.attribute.token().lineCol, errObj)
+		}
+		res, ok := parentMap.kvPairs[key]
+		return res, ok, nil
+	case *indexExpression:
+		parent, found, errObj := evalLookup(v.left, env)
+		if errObj != nil || !found {
+			return nil, false, errObj
+		}
+		parentArr, ok := parent.(*objectArray)
+		if !ok {
+			return nil, false, nil
+		}
+		indexObj, ok := checkEvalResultLC(v.index.eval(env), v.index.token().lineCol)
+		if !ok {
+			return nil, false, indexObj
+		}
+		idxInt, ok := indexObj.(*objectInteger)
+		if !ok {
+			msg := fmt.Sprintf("index is not of type %s. got=%s", t_integer, indexObj.getType())
+			return nil, false, newObjectErr(v.index.token().lineCol, msg)
+		}
+		if idxInt.value < 0 || idxInt.value >= int64(len(parentArr.entries)) {
+			return nil, false, nil
+		}
+		return parentArr.entries[idxInt.value], true, nil
+	default:
+		msg := fmt.Sprintf("has() takes a single variable or path. got=%s", expr.string())
+		return nil, false, newObjectErr(expr.token().lineCol, msg)
 	}
 }
 
@@ -649,17 +913,18 @@ func (n *nullLiteral) eval(env *environment) object {
 // map lit
 
 func (m *mapLiteral) eval(env *environment) object {
-	objPairs := make(map[string]object)
-	for key, expr := range m.pairs {
+	ret := newObjectMap()
+	for _, key := range m.keys {
+		expr := m.pairs[key]
 		objectToAdd := expr.eval(env)
 		objectToAdd, ok := checkEvalResultLC(objectToAdd, expr.token().lineCol)
 		if !ok {
 			return objectToAdd
 		}
 
-		objPairs[key] = objectToAdd
+		ret.set(key, objectToAdd)
 	}
-	return &objectMap{kvPairs: objPairs}
+	return ret
 }
 
 func (a *arrayLiteral) eval(env *environment) object {
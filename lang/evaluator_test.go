@@ -1,6 +1,8 @@
 package lang
 
 import (
+	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -567,6 +569,31 @@ func TestEvalPathExpression(t *testing.T) {
 	}
 }
 
+func TestEvalChainedIndex(t *testing.T) {
+	fstore := newBuiltinFunctionStore()
+	in := `{"grid": [[1, 2], [3, {"a": 4}]]}`
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = @in.grid[1][0]`, `3`},
+		{`SET @out = @in.grid[1][1].a`, `4`},
+		{`SET @out = [has(@in.grid[0][1]), has(@in.grid[0][2])]`, `[true, false]`},
+		{`SET @out = get_path(@in, "grid[1][1].a")`, `4`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, in, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %v", tt.program, err)
+		}
+	}
+	for _, program := range []string{`SET x[0] = 1`, `SET x = [[1]]
+SET x[0][0] = 2`, `DEL @out.a[0]`} {
+		if _, err := NewProgram(program, fstore); err == nil {
+			t.Errorf("%s: expected SET and DEL to reject index targets", program)
+		}
+	}
+}
+
 func TestEvalIndexOutOfBoundsReturnsError(t *testing.T) {
 	env := newEnvironment(nil)
 	dataMap := convertBytesToObject([]byte(`{
@@ -775,3 +802,306 @@ func setupEvalTestParser(input string) *parser {
 	l := newLexer([]rune(input))
 	return newParser(l)
 }
+
+func TestEvalHas(t *testing.T) {
+	fstore := newBuiltinFunctionStore()
+	in := `{"a": {"b": null, "list": [1, {"c": 2}]}, "name": "x"}`
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = [has(@in.a.b), has(@in.a.missing), has(@in.missing.deeper)]`, `[true, false, false]`},
+		{`SET @out = [has(@in.a.list[1].c), has(@in.a.list[2]), has(@in.name.deeper)]`, `[true, false, false]`},
+		{`SET key = "list"
+SET @out = [has(@in.a.'${key}'), has(@in.a."b"), has(x)]`, `[true, true, false]`},
+		{`SET x = null
+SET @out = has(x) && !has(y)`, `true`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, in, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %v", tt.program, err)
+		}
+	}
+
+	errTests := []struct {
+		program string
+		wantErr string
+	}{
+		{`SET @out = has(1)`, "has() takes a single variable or path"},
+		{`SET @out = has(@in.a, @in.b)`, "has() takes a single variable or path"},
+		{`SET @out = has()`, "has() takes a single variable or path"},
+		{`SET @out = @in.a | has()`, "1:20:\n\thas() cannot be the right side of a pipe"},
+	}
+	for _, tt := range errTests {
+		_, err := NewProgram(tt.program, fstore)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error to contain %q. got=%v", tt.program, tt.wantErr, err)
+		}
+	}
+}
+
+func TestEvalStatementKeywordsAsNames(t *testing.T) {
+	fstore := newBuiltinFunctionStore()
+	in := `{"param": 1, "include": "x", "nested": {"Param": 2}}`
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out.param = @in.param`, `{"param": 1}`},
+		{`SET @out.include = @in.include`, `{"include": "x"}`},
+		{`SET param = @in.nested.Param
+SET include = [param]
+SET @out = include`, `[2]`},
+		{`SET @out = {"param": @in.param, "include": @in.include}
+DEL @out.include`, `{"param": 1}`},
+		{`PARAM param = 3
+SET @out = @vars.param`, `3`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, in, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %v", tt.program, err)
+		}
+	}
+}
+
+func TestEvalStrict(t *testing.T) {
+	fstore := newBuiltinFunctionStore()
+	in := []byte(`{"a": {"b": null, "list": [1]}}`)
+	tests := []struct {
+		program string
+		want    string
+		wantErr string
+	}{
+		{`SET @out = @in.a.b`, `null`, ""},
+		{`SET @out = has(@in.a.c) || has(@in.x.y)`, `false`, ""},
+		{`IF has(@in.a.c) :: SET @out = @in.a.c`, `null`, ""},
+		{`DEL x.y.z
+SET @out.ok = true`, `{"ok": true}`, ""},
+		{`SET @out = map(@in.a.list, x ~> { SET return = x.value })`, `[1]`, ""},
+		{`SET @out = @in.a.c`, "", `1:18: path "@in.a.c" does not exist`},
+		{`SET @out = @in.x.y`, "", `1:16: path "@in.x" does not exist`},
+		{`SET @out = @in.a.b.c`, "", `path "@in.a.b.c" does not exist: "@in.a.b" is NULL`},
+		{`SET @out = @in.a.b[0]`, "", `cannot index "@in.a.b": it is NULL`},
+		{`SET @out = y`, "", `1:12: undefined variable "y"`},
+		{`SET @out = map(@in.a.list, x ~> { SET return = y })`, "", `undefined variable "y"`},
+	}
+	for _, tt := range tests {
+		program, err := NewProgram(tt.program, fstore, WithStrict(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := program.Run(in)
+		if len(tt.wantErr) > 0 {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error to contain %q. got=%v", tt.program, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.program, err)
+			continue
+		}
+		var got, want interface{}
+		json.Unmarshal(out, &got)
+		json.Unmarshal([]byte(tt.want), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: wrong output. want=%s got=%s", tt.program, tt.want, out)
+		}
+	}
+}
+
+func TestEvalKeyOrder(t *testing.T) {
+	fstore := newBuiltinFunctionStore()
+	in := []byte(`{"zeta": 1, "alpha": {"y": 2, "x": 1}, "mid": [{"b": 1, "a": 2}]}`)
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = @in`, `{"zeta":1,"alpha":{"y":2,"x":1},"mid":[{"b":1,"a":2}]}`},
+		{`SET @out = @in
+SET @out.zeta = 5
+SET @out.new = true
+DEL @out.alpha
+SET @out.alpha = null`, `{"zeta":5,"mid":[{"b":1,"a":2}],"new":true,"alpha":null}`},
+		{`SET @out = {"c": 1, "b": 2, "a": {"z": 1, "y": 2}}`, `{"c":1,"b":2,"a":{"z":1,"y":2}}`},
+		{`SET @out = '${@in.alpha}'`, `"{y: 2, x: 1}"`},
+		{`SET @out = map(@in, x ~> { SET return = x.key })`, `{"zeta":"zeta","alpha":"alpha","mid":"mid"}`},
+		{`SET @out = filter(@in, x ~> { SET return = x.key != "zeta" })`, `{"alpha":{"y":2,"x":1},"mid":[{"b":1,"a":2}]}`},
+		{`SET @out = reduce(@in, [], x ~> { SET return = x.current + [x.key] })`, `["zeta","alpha","mid"]`},
+		{`SET @out = map(@in.mid, x ~> { SET return = x.value })`, `[{"b":1,"a":2}]`},
+	}
+	for _, tt := range tests {
+		program, err := NewProgram(tt.program, fstore)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.program, err)
+		}
+		got, err := program.Run(in)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.program, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: wrong output. want=%s got=%s", tt.program, tt.want, got)
+		}
+	}
+
+	program, err := NewProgram(`SET @out = @in
+SET @out.beta = 1`, fstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := program.RunResult(in, WithSortedKeys(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"alpha":{"x":1,"y":2},"beta":1,"mid":[{"a":2,"b":1}],"zeta":1}`
+	if string(res.Output) != want {
+		t.Errorf("wrong sorted output. want=%s got=%s", want, res.Output)
+	}
+}
+
+func TestEvalTimeArithmetic(t *testing.T) {
+	fstore := newBuiltinFunctionStore()
+	in := []byte(`{"start": "2025-10-06T20:00:00Z", "end": "2025-10-06T21:30:00Z"}`)
+	tests := []struct {
+		program string
+		want    string
+		wantErr string
+	}{
+		{`SET @out = time(@in.start) + duration("15m")`, `"2025-10-06T20:15:00Z"`, ""},
+		{`SET @out = duration("15m") + time(@in.start)`, `"2025-10-06T20:15:00Z"`, ""},
+		{`SET @out = time(@in.start) - duration("1h")`, `"2025-10-06T19:00:00Z"`, ""},
+		{`SET @out = time(@in.end) - time(@in.start)`, `"1h30m0s"`, ""},
+		{`SET @out = time(@in.start) - time(@in.end)`, `"-1h30m0s"`, ""},
+		{`SET @out = [time(@in.start) < time(@in.end), time(@in.start) >= time(@in.end), time(@in.start) == time("2025-10-06T22:00:00+02:00")]`, `[true, false, true]`, ""},
+		{`SET @out = [time(@in.start) != time(@in.end), time(@in.start) == null, time(@in.start) != 5]`, `[true, false, true]`, ""},
+		{`SET @out = [duration("1h") > duration("59m"), duration("60s") == duration("1m"), duration(0) || false]`, `[true, true, false]`, ""},
+		{`SET @out = [duration("1h") + duration("30m"), duration("1h") - duration("2h"), -duration("1m")]`, `["1h30m0s", "-1h0m0s", "-1m0s"]`, ""},
+		{`SET @out = [duration("1h") * 2, 1.5 * duration("1h"), duration("1h") / 4, duration("90m") / duration("1h")]`, `["2h0m0s", "1h30m0s", "15m0s", 1.5]`, ""},
+		{`SET @out = '${duration(1.5)} ${string(duration("2m"))}'`, `"1.5s 2m0s"`, ""},
+		{`SET @out = duration_seconds(time(@in.end) - time(@in.start)) / 60`, `90`, ""},
+		{`SET @out = truncate_time(add_time(time(@in.end), "29m59s"), duration("1h"))`, `"2025-10-06T21:00:00Z"`, ""},
+		{`SET @out = time_diff(time(@in.end), time(@in.start)) == time(@in.end) - time(@in.start)`, `true`, ""},
+		{`SET @out = time(@in.start) + time(@in.end)`, "", "1:28: invalid operator for types: TIME + TIME"},
+		{`SET @out = duration("1h") / 0`, "", "cannot divide a DURATION by zero"},
+		{`SET @out = duration("2562047h") * 2`, "", "DURATION is out of range"},
+		{`SET @out = duration("1 hour")`, "", `Invalid STRING 1 hour`},
+		{`SET @out = truncate_time(time(@in.start), "-1h")`, "", "truncate_time() duration must be positive"},
+		{`SET @out = add_time(time(@in.start), "soon")`, "", `add_time() invalid duration "soon"`},
+	}
+	for _, tt := range tests {
+		program, err := NewProgram(tt.program, fstore)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.program, err)
+		}
+		out, err := program.Run(in)
+		if len(tt.wantErr) > 0 {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error to contain %q. got=%v", tt.program, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.program, err)
+			continue
+		}
+		var got, want interface{}
+		json.Unmarshal(out, &got)
+		json.Unmarshal([]byte(tt.want), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: wrong output. want=%s got=%s", tt.program, tt.want, out)
+		}
+	}
+}
+
+func TestEvalTimeFunctions(t *testing.T) {
+	fstore := newBuiltinFunctionStore()
+	in := `{"sunday": "2025-10-05T23:30:00.123456789Z", "new_year": "2024-12-30T12:00:00Z", "summer": "2025-07-01T12:00:00Z"}`
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = [format_time(time(@in.sunday), "rfc_3339"), format_time(time(@in.sunday))]`, `["2025-10-05T23:30:00Z", "2025-10-05T23:30:00.123456789Z"]`},
+		{`SET @out = [format_time(time(@in.sunday), "unix"), format_time(time(@in.sunday), "unix_micro"), format_time(time(@in.sunday), "UNIX_NANO")]`, `[1759707000, 1759707000123456, 1759707000123456789]`},
+		{`SET @out = format_time(to_timezone(time(@in.sunday), "Asia/Tokyo"), "2006-01-02 15:04 MST")`, `"2025-10-06 08:30 JST"`},
+		{`SET @out = [to_timezone(time(@in.summer), "Europe/London"), to_timezone(time(@in.new_year), "Europe/London")]`, `["2025-07-01T13:00:00+01:00", "2024-12-30T12:00:00Z"]`},
+		{`SET @out = to_timezone(time(@in.sunday), "Asia/Tokyo") == time(@in.sunday)`, `true`},
+		{`SET @out = [weekday(time(@in.sunday)), weekday(to_timezone(time(@in.sunday), "Asia/Tokyo"))]`, `[7, 1]`},
+		{`SET @out = [iso_week(time(@in.new_year)), year(time(@in.new_year)), day_of_year(time(@in.new_year))]`, `[1, 2024, 365]`},
+		{`SET @out = [month(time(@in.sunday)), day(time(@in.sunday)), hour(time(@in.sunday)), minute(time(@in.sunday)), second(time(@in.sunday))]`, `[10, 5, 23, 30, 0]`},
+		{`SET @out = [start_of(time(@in.sunday), "week"), start_of(time(@in.sunday), "year"), start_of(time(@in.sunday), "minute")]`, `["2025-09-29T00:00:00Z", "2025-01-01T00:00:00Z", "2025-10-05T23:30:00Z"]`},
+		{`SET @out = start_of(to_timezone(time(@in.sunday), "Asia/Tokyo"), "Week")`, `"2025-10-06T00:00:00+09:00"`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, in, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %v", tt.program, err)
+		}
+	}
+
+	errTests := []struct {
+		program string
+		wantErr string
+	}{
+		{`SET @out = to_timezone(now(), "Mars/Olympus_Mons")`, `to_timezone() invalid time zone "Mars/Olympus_Mons"`},
+		{`SET @out = start_of(now(), "fortnight")`, `start_of() unit must be one of minute, hour, day, week, month, or year. got="fortnight"`},
+	}
+	for _, tt := range errTests {
+		program, err := NewProgram(tt.program, fstore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := program.Run([]byte(`null`)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error to contain %q. got=%v", tt.program, tt.wantErr, err)
+		}
+	}
+}
+
+func TestEvalStringFunctions(t *testing.T) {
+	fstore := newBuiltinFunctionStore()
+	in := `{"word": "日本語テキスト", "padded": "\t ça va \n"}`
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = [split("a,,b", ","), split("", ","), split(@in.word, "")]`, `[["a", "", "b"], [""], ["日", "本", "語", "テ", "キ", "ス", "ト"]]`},
+		{`SET @out = [join([], "-"), join(["a"], "-"), join(split(@in.word, ""), "/")]`, `["", "a", "日/本/語/テ/キ/ス/ト"]`},
+		{`SET @out = [trim(@in.padded), trim_left(@in.padded), trim_right(@in.padded), trim("ééxéé", "é")]`, `["ça va", "ça va \n", "\t ça va", "x"]`},
+		{`SET @out = [trim_prefix("aab", "a"), trim_suffix("abb", "b"), trim_prefix("abc", "x")]`, `["ab", "ab", "abc"]`},
+		{`SET @out = [substring(@in.word, 3), substring(@in.word, 0, 2), substring(@in.word, 5, 100), substring(@in.word, 50)]`, `["テキスト", "日本", "スト", ""]`},
+		{`SET @out = [index_of(@in.word, "テ"), index_of(@in.word, "x"), index_of(@in.word, "")]`, `[3, -1, 0]`},
+		{`SET @out = [starts_with(@in.word, "日本"), ends_with(@in.word, "日本"), starts_with("", "")]`, `[true, false, true]`},
+		{`SET @out = [pad_left("é", 4, "ab"), pad_right("日本", 3), pad_left("long", 2, "x")]`, `["abaé", "日本 ", "long"]`},
+		{`SET @out = [repeat("é", 3), repeat("x", 0), repeat("", 1000000000)]`, `["ééé", "", ""]`},
+		{`SET @out = [replace("a.b.c", ".", "/"), replace("a.b.c", ".", "/", 1), replace("a.b.c", ".", "/", -5)]`, `["a/b/c", "a/b.c", "a/b/c"]`},
+		{`SET @out = [upper("straße"), lower("ÀÉÎ"), title("élan vital\tof  ALL")]`, `["STRAßE", "àéî", "Élan Vital\tOf  ALL"]`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, in, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %v", tt.program, err)
+		}
+	}
+
+	errTests := []struct {
+		program string
+		wantErr string
+	}{
+		{`SET @out = substring("abc", -1)`, "substring() positions cannot be negative"},
+		{`SET @out = substring("abc", 2, 1)`, "substring() start cannot be after end"},
+		{`SET @out = repeat("ab", -1)`, "repeat() count cannot be negative"},
+		{`SET @out = repeat("ab", 1000000000000)`, "repeat() result is too large"},
+		{`SET @out = pad_left("ab", 5, "")`, "pad_left() padding cannot be empty"},
+		{`SET @out = pad_right("ab", 1000000000000)`, "pad_right() length is too large"},
+		{`SET @out = join(["a", 1], ",")`, "got=INTEGER at items[1]"},
+	}
+	for _, tt := range errTests {
+		program, err := NewProgram(tt.program, fstore)
+		if err != nil {
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error to contain %q. got=%v", tt.program, tt.wantErr, err)
+			}
+			continue
+		}
+		if _, err := program.Run([]byte(`null`)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error to contain %q. got=%v", tt.program, tt.wantErr, err)
+		}
+	}
+}
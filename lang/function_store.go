@@ -2,13 +2,22 @@ package lang
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
+	"sync"
 )
 
+// holds the functions available to programs, grouped by namespace. it is safe for concurrent use.
+// programs compile against a snapshot of the store, so later changes to the store do not affect them.
 type FunctionStore struct {
-	Namespaces map[string]*functionNamespace
+	mu          sync.RWMutex
+	namespaces  map[string]*functionNamespace
+	customTypes map[string]customKind
+	lookups     LookupProvider
+	frozen      bool
 }
 
 type functionNamespace struct {
@@ -23,12 +32,15 @@ func newFunctionNamespace(name string) *functionNamespace {
 	}
 }
 
+var errFunctionStoreFrozen = errors.New("function store is frozen")
+
 // creates an empty function store with an empty "std" namespace.
 func NewFunctionStore() *FunctionStore {
 	s := &FunctionStore{
-		Namespaces: make(map[string]*functionNamespace),
+		namespaces:  make(map[string]*functionNamespace),
+		customTypes: make(map[string]customKind),
 	}
-	s.Namespaces["std"] = newFunctionNamespace("std")
+	s.namespaces["std"] = newFunctionNamespace("std")
 	return s
 }
 
@@ -37,11 +49,85 @@ func DefaultFunctionStore() *FunctionStore {
 	return newBuiltinFunctionStore()
 }
 
+// sets the provider used by the lookup functions for programs that use this store.
+// a provider passed to a run with WithLookupProvider takes precedence.
+func (fs *FunctionStore) SetLookupProvider(provider LookupProvider) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.frozen {
+		return errFunctionStoreFrozen
+	}
+	fs.lookups = provider
+	return nil
+}
+
+// returns an unfrozen copy of the store. registering functions to the copy does not affect the original.
+func (fs *FunctionStore) Clone() *FunctionStore {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	ret := &FunctionStore{
+		namespaces:  make(map[string]*functionNamespace, len(fs.namespaces)),
+		customTypes: make(map[string]customKind, len(fs.customTypes)),
+		lookups:     fs.lookups,
+	}
+	maps.Copy(ret.customTypes, fs.customTypes)
+	for name, ns := range fs.namespaces {
+		cloned := newFunctionNamespace(name)
+		maps.Copy(cloned.Functions, ns.Functions)
+		ret.namespaces[name] = cloned
+	}
+	return ret
+}
+
+// prevents any further changes to the store. registering, unregistering, or merging functions returns an error afterwards.
+func (fs *FunctionStore) Freeze() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.frozen = true
+}
+
+func (fs *FunctionStore) IsFrozen() bool {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.frozen
+}
+
+// returns a frozen copy of the store for a compiled program
+func (fs *FunctionStore) snapshot() *FunctionStore {
+	ret := fs.Clone()
+	ret.frozen = true
+	return ret
+}
+
+// returns the names of all namespaces in the store, sorted
+func (fs *FunctionStore) NamespaceNames() []string {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return slices.Sorted(maps.Keys(fs.namespaces))
+}
+
+// returns the functions registered to a namespace, sorted by name
+func (fs *FunctionStore) Functions(namespace string) []*FunctionEntry {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	ns, ok := fs.namespaces[namespace]
+	if !ok {
+		return []*FunctionEntry{}
+	}
+	ret := slices.Collect(maps.Values(ns.Functions))
+	slices.SortFunc(ret, func(a, b *FunctionEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return ret
+}
+
 func (fs *FunctionStore) get(namespace string, name string) (*FunctionEntry, error) {
 	if len(namespace) == 0 {
 		namespace = "std"
 	}
-	ns, ok := fs.Namespaces[namespace]
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	ns, ok := fs.namespaces[namespace]
 	if !ok {
 		return nil, fmt.Errorf("function namespace %q does not exist", namespace)
 	}
@@ -51,12 +137,83 @@ func (fs *FunctionStore) get(namespace string, name string) (*FunctionEntry, err
 	}
 	return fn, nil
 }
-func (fs *FunctionStore) Register(fe *FunctionEntry) {
-	fs.register("std", fe)
+func (fs *FunctionStore) Register(fe *FunctionEntry) error {
+	return fs.RegisterToNamespace("std", fe)
 }
-func (fs *FunctionStore) RegisterToNamespace(namespace string, fe *FunctionEntry) {
+func (fs *FunctionStore) RegisterToNamespace(namespace string, fe *FunctionEntry) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.frozen {
+		return errFunctionStoreFrozen
+	}
+	if err := checkReservedName(namespace, fe); err != nil {
+		return err
+	}
+	if err := fe.checkArgs(); err != nil {
+		return err
+	}
 	fs.register(namespace, fe)
+	return nil
+}
+
+// the names of functions that are built into the language, which cannot be registered to the "std" namespace
+var reservedFunctionNames = []string{"has"}
+
+func checkReservedName(namespace string, fe *FunctionEntry) error {
+	if (namespace == "" || namespace == "std") && slices.Contains(reservedFunctionNames, fe.Name) {
+		return fmt.Errorf("function name %q is reserved: %s() is built into the language", fe.Name, fe.Name)
+	}
+	return nil
 }
+
+// removes a function from a namespace
+func (fs *FunctionStore) Unregister(namespace string, name string) error {
+	if len(namespace) == 0 {
+		namespace = "std"
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.frozen {
+		return errFunctionStoreFrozen
+	}
+	ns, ok := fs.namespaces[namespace]
+	if !ok {
+		return fmt.Errorf("function namespace %q does not exist", namespace)
+	}
+	if _, ok := ns.Functions[name]; !ok {
+		return fmt.Errorf("function %q in namespace %q does not exist", name, namespace)
+	}
+	delete(ns.Functions, name)
+	return nil
+}
+
+// registers every function in other to the same namespace in this store, replacing functions with the same name.
+// the custom types registered with other are registered too, and a different type with the name of one already in this store returns an error.
+func (fs *FunctionStore) Merge(other *FunctionStore) error {
+	if other == fs {
+		return nil
+	}
+	src := other.Clone()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.frozen {
+		return errFunctionStoreFrozen
+	}
+	for _, ct := range src.customTypes {
+		if err := fs.checkType(ct); err != nil {
+			return err
+		}
+	}
+	maps.Copy(fs.customTypes, src.customTypes)
+	for name, ns := range src.namespaces {
+		for _, fe := range ns.Functions {
+			fs.register(name, fe)
+		}
+	}
+	return nil
+}
+
+// callers must hold the write lock
 func (fs *FunctionStore) register(namespace string, fe *FunctionEntry) {
 	if len(namespace) == 0 {
 		namespace = "std"
@@ -67,39 +224,52 @@ func (fs *FunctionStore) register(namespace string, fe *FunctionEntry) {
 		fe = &cloned
 	}
 	fe.Namespace = namespace
-	if ns, ok := fs.Namespaces[namespace]; ok {
+	if slices.ContainsFunc(fe.overloads, func(overload *FunctionEntry) bool { return overload.Namespace != namespace }) {
+		overloads := []*FunctionEntry{}
+		for _, overload := range fe.overloads {
+			cloned := *overload
+			cloned.Namespace = namespace
+			overloads = append(overloads, &cloned)
+		}
+		fe.overloads = overloads
+	}
+	if ns, ok := fs.namespaces[namespace]; ok {
 		ns.Functions[fe.Name] = fe
 		return
 	}
 	newNs := newFunctionNamespace(namespace)
 	newNs.Functions[fe.Name] = fe
-	fs.Namespaces[namespace] = newNs
+	fs.namespaces[namespace] = newNs
 }
 
 // function entries contain documentation information AND runnable instances of functions
 type FunctionEntry struct {
-	Namespace   string // populated when the function is registered.
-	Name        string
-	Description string
-	Fn          Function
-	Args        []FunctionArg
-	Return      *FunctionReturn
-	Attributes  []FunctionAttribute
-	Tags        []FunctionTag
-	Examples    []ProgramExample
+	Namespace    string // populated when the function is registered.
+	Name         string
+	Description  string
+	Fn           Function
+	Args         []FunctionArg
+	Return       *FunctionReturn
+	Attributes   []FunctionAttribute
+	Capabilities []FunctionCapability
+	Tags         []FunctionTag
+	Examples     []ProgramExample
+	overloads    []*FunctionEntry // additional entries registered under the same name with RegisterOverload
+	body         *program         // the compiled source of a Morph function, so that function policies can check the functions it calls
 }
 
 func NewFunctionEntry(name string, description string, fn Function, opts ...functionEntryOpt) *FunctionEntry {
 	ret := &FunctionEntry{
-		Name:        name,
-		Namespace:   "",
-		Description: description,
-		Fn:          fn,
-		Args:        []FunctionArg{},
-		Return:      nil,
-		Attributes:  []FunctionAttribute{},
-		Tags:        []FunctionTag{"General"},
-		Examples:    []ProgramExample{},
+		Name:         name,
+		Namespace:    "",
+		Description:  description,
+		Fn:           fn,
+		Args:         []FunctionArg{},
+		Return:       nil,
+		Attributes:   []FunctionAttribute{},
+		Capabilities: []FunctionCapability{},
+		Tags:         []FunctionTag{"General"},
+		Examples:     []ProgramExample{},
 	}
 	for _, fn := range opts {
 		fn(ret)
@@ -119,7 +289,7 @@ func (fe *FunctionEntry) fullName() string {
 func (fe *FunctionEntry) Signature() string {
 	argStrList := []string{}
 	for _, a := range fe.Args {
-		argStrList = append(argStrList, a.typesString())
+		argStrList = append(argStrList, a.signatureString())
 	}
 	args := strings.Join(argStrList, ", ")
 	ret := ""
@@ -129,38 +299,153 @@ func (fe *FunctionEntry) Signature() string {
 	return fmt.Sprintf("%s(%s) %s", fe.fullName(), args, ret)
 }
 
+// runs the overload that accepts the arguments
 func (fe *FunctionEntry) run(ctx context.Context, args ...object) object {
-	if len(args) < len(fe.Args) {
-		msg := fmt.Sprintf("function %q too few arguments supplied. want=%d got=%d\n\tfunction signature: %s", fe.fullName(), len(fe.Args), len(args), fe.Signature())
+	match, args, err := fe.resolve(args, nil, nil)
+	if err != nil {
+		return newObjectErrWithoutLC(err.Error())
+	}
+	return match.call(ctx, args...)
+}
+
+// validates the arguments against this entry and runs it, without considering other overloads
+func (fe *FunctionEntry) call(ctx context.Context, args ...object) object {
+	requiredCount := fe.requiredArgCount()
+	if len(args) < requiredCount {
+		msg := fmt.Sprintf("function %q too few arguments supplied. want=%d got=%d\n\tfunction signature: %s", fe.fullName(), requiredCount, len(args), fe.Signature())
 		return newObjectErrWithoutLC(msg)
 	}
+	args = fe.withDefaults(args)
 
 	for argIdx, wantArg := range fe.Args {
-		if len(wantArg.Types) == 0 {
+		if len(wantArg.Types) == 0 || argIdx >= len(args) {
 			continue
 		}
 		arg := args[argIdx]
-		if !slices.Contains(wantArg.Types, PublicType(arg.getType())) {
-			msg := fmt.Sprintf("function %q invalid argument type for %q. want=%s. got=%s\n\tfunction signature: %s", fe.fullName(), wantArg.Name, wantArg.typesString(), arg.getType(), fe.Signature())
+		if arg == obj_omitted_arg {
+			continue
+		}
+		if mismatch, ok := matchTypes(wantArg.Types, arg); !ok {
+			msg := fmt.Sprintf("function %q invalid argument type for %q. want=%s. got=%s\n\tfunction signature: %s", fe.fullName(), wantArg.Name, typeListString(wantArg.Types), mismatch.describe(wantArg.Name), fe.Signature())
 			return newObjectErrWithoutLC(msg)
 		}
 	}
 	if err := fe.checkVariadic(args...); err != nil {
 		return newObjectErrWithoutLC(err.Error())
 	}
+	for argIdx, arg := range args {
+		if arg == obj_omitted_arg {
+			args[argIdx] = obj_global_null // optional arguments that are left out without a default are passed as NULL
+		}
+	}
 	ret := evalFunction(ctx, fe.Fn, args...)
 	if isObjectErr(ret) {
 		return ret
 	}
 	if fe.Return != nil {
-		if !slices.Contains(fe.Return.Types, PublicType(ret.getType())) {
-			msg := fmt.Sprintf("function %q invalid return type. want=%s got=%s\n\tfunction signature: %s", fe.Name, fe.Return.typesString(), ret.getType(), fe.Signature())
+		if mismatch, ok := matchTypes(fe.Return.Types, ret); !ok {
+			msg := fmt.Sprintf("function %q invalid return type. want=%s got=%s\n\tfunction signature: %s", fe.Name, fe.Return.typesString(), mismatch.describe("return"), fe.Signature())
 			return newObjectErrWithoutLC(msg)
 		}
 	}
 	return ret
 }
 
+// returns the number of leading arguments that must be supplied
+func (fe *FunctionEntry) requiredArgCount() int {
+	for idx, arg := range fe.Args {
+		if arg.Optional {
+			return idx
+		}
+	}
+	return len(fe.Args)
+}
+
+// marks an optional argument that was left out, so that it can be told apart from an explicit null.
+// it behaves like NULL until withDefaults or call replaces it.
+var obj_omitted_arg = &objectOmitted{}
+
+type objectOmitted struct {
+	objectNull
+	_ byte // pointers to zero-size values may be equal, which would make every NULL look omitted
+}
+
+// replaces optional arguments that were left out with their defaults. an explicit null is passed as is.
+// the returned list is a copy, so callers may replace the omitted arguments that remain.
+func (fe *FunctionEntry) withDefaults(args []object) []object {
+	lastDefault := -1
+	for idx, arg := range fe.Args {
+		if arg.Optional && arg.Default != nil {
+			lastDefault = idx
+		}
+	}
+	ret := slices.Clone(args)
+	for len(ret) <= lastDefault {
+		ret = append(ret, obj_omitted_arg)
+	}
+	for idx := 0; idx <= lastDefault; idx++ {
+		wantArg := fe.Args[idx]
+		if wantArg.Default != nil && ret[idx] == obj_omitted_arg {
+			ret[idx] = wantArg.Default.inner.clone()
+		}
+	}
+	return ret
+}
+
+// places arguments passed by name at the position of the matching parameter.
+// optional parameters skipped over by a named argument are marked as omitted, so that their default applies.
+func (fe *FunctionEntry) bindNamedArgs(args []object, names []string, values []object) ([]object, error) {
+	ret := slices.Clone(args)
+	isVariadic := slices.Contains(fe.Attributes, FUNCTION_ATTRIBUTE_VARIADIC)
+	for nameIdx, name := range names {
+		argIdx := slices.IndexFunc(fe.Args, func(fa FunctionArg) bool { return fa.Name == name })
+		switch {
+		case argIdx < 0:
+			return nil, fmt.Errorf("function %q has no argument named %q\n\tfunction signature: %s", fe.fullName(), name, fe.Signature())
+		case isVariadic && argIdx == len(fe.Args)-1:
+			return nil, fmt.Errorf("function %q variadic argument %q cannot be passed by name", fe.fullName(), name)
+		case argIdx < len(args):
+			return nil, fmt.Errorf("function %q argument %q was already passed by position", fe.fullName(), name)
+		}
+		for len(ret) <= argIdx {
+			ret = append(ret, nil)
+		}
+		ret[argIdx] = values[nameIdx]
+	}
+	for argIdx, arg := range ret {
+		if arg != nil {
+			continue
+		}
+		if !fe.Args[argIdx].Optional {
+			return nil, fmt.Errorf("function %q missing required argument %q\n\tfunction signature: %s", fe.fullName(), fe.Args[argIdx].Name, fe.Signature())
+		}
+		ret[argIdx] = obj_omitted_arg
+	}
+	return ret, nil
+}
+
+// checks that optional arguments come after every required argument
+func (fe *FunctionEntry) checkArgs() error {
+	isVariadic := slices.Contains(fe.Attributes, FUNCTION_ATTRIBUTE_VARIADIC)
+	seenOptional := false
+	for idx, arg := range fe.Args {
+		if isVariadic && idx == len(fe.Args)-1 {
+			if arg.Default != nil {
+				return fmt.Errorf("function %q variadic argument %q cannot have a default", fe.fullName(), arg.Name)
+			}
+			continue
+		}
+		if arg.Optional {
+			seenOptional = true
+			continue
+		}
+		if seenOptional {
+			return fmt.Errorf("function %q required argument %q cannot come after an optional argument", fe.fullName(), arg.Name)
+		}
+	}
+	return nil
+}
+
 func evalFunction(ctx context.Context, fn Function, args ...object) object {
 	objList := []*Object{}
 	for _, arg := range args {
@@ -178,15 +463,18 @@ func (fe *FunctionEntry) checkVariadic(args ...object) error {
 	if len(args) > len(fe.Args) && !isVariadic {
 		return fmt.Errorf("invalid number of args for function %q: too many arguments supplied. want=%d got=%d", fe.fullName(), len(fe.Args), len(args))
 	}
-	if !isVariadic {
+	if !isVariadic || len(fe.Args) == 0 {
 		return nil
 	}
 	firstVariadicArg := fe.Args[len(fe.Args)-1]
 	curIdx := len(fe.Args) - 1
+	if curIdx >= len(args) {
+		return nil
+	}
 	lastArgs := args[curIdx:]
 	for _, arg := range lastArgs {
-		if !slices.Contains(firstVariadicArg.Types, PublicType(arg.getType())) {
-			return fmt.Errorf("type error for function %q: argument at zero-indexed position %d does not match any type of variadic parameter %q (%s)", fe.fullName(), curIdx, firstVariadicArg.Name, firstVariadicArg.typesString())
+		if mismatch, ok := matchTypes(firstVariadicArg.Types, arg); !ok {
+			return fmt.Errorf("type error for function %q: argument at zero-indexed position %d does not match any type of variadic parameter %q (%s). got=%s", fe.fullName(), curIdx, firstVariadicArg.Name, firstVariadicArg.typesString(), mismatch.describe(firstVariadicArg.Name))
 		}
 		curIdx++
 	}
@@ -195,6 +483,11 @@ func (fe *FunctionEntry) checkVariadic(args ...object) error {
 
 type functionEntryOpt func(*FunctionEntry)
 
+func WithDescription(description string) functionEntryOpt {
+	return func(fe *FunctionEntry) {
+		fe.Description = description
+	}
+}
 func WithArgs(args ...FunctionArg) functionEntryOpt {
 	return func(fe *FunctionEntry) {
 		fe.Args = args
@@ -234,6 +527,8 @@ type FunctionArg struct {
 	Name        string
 	Description string
 	Types       []PublicType
+	Optional    bool    // optional arguments may be left out, and must come after every required argument. an optional variadic argument may receive zero values
+	Default     *Object // passed to the function when an optional argument is left out. an explicit null is passed as is. if nil, the function receives fewer arguments or NULL
 }
 
 func NewFunctionArg(name string, description string, types ...PublicType) FunctionArg {
@@ -244,36 +539,33 @@ func NewFunctionArg(name string, description string, types ...PublicType) Functi
 	}
 }
 
-func (fa FunctionArg) typesString() string {
-	strs := []string{}
-	isAny := true
-	isBasic := true
-	for _, t := range ANY {
-		if !slices.Contains(fa.Types, t) {
-			isAny = false
-			break
-		}
-	}
-
-	for _, t := range BASIC {
-		if !slices.Contains(fa.Types, t) {
-			isBasic = false
-			break
-		}
+// creates an argument that callers may leave out. a nil defaultValue means the argument has no default.
+func NewOptionalFunctionArg(name string, description string, defaultValue *Object, types ...PublicType) FunctionArg {
+	return FunctionArg{
+		Name:        name,
+		Description: description,
+		Types:       types,
+		Optional:    true,
+		Default:     defaultValue,
 	}
+}
 
-	if isBasic {
-		strs = []string{"BASIC"}
+// returns the argument as it appears in the function signature, such as tz?:STRING="UTC"
+func (fa FunctionArg) signatureString() string {
+	if !fa.Optional {
+		return fa.typesString()
 	}
-	if isAny {
-		strs = []string{"ANY"}
-	}
-	if !isAny && !isBasic {
-		for _, t := range fa.Types {
-			strs = append(strs, string(t))
+	ret := strings.Replace(fa.typesString(), ":", "?:", 1)
+	if fa.Default != nil {
+		if b, err := fa.Default.MarshalJSON(); err == nil {
+			ret = fmt.Sprintf("%s=%s", ret, string(b))
 		}
 	}
-	return fmt.Sprintf("%s:%s", fa.Name, strings.Join(strs, "|"))
+	return ret
+}
+
+func (fa FunctionArg) typesString() string {
+	return fmt.Sprintf("%s:%s", fa.Name, typeListString(fa.Types))
 }
 
 type FunctionReturn struct {
@@ -289,31 +581,7 @@ func NewFunctionReturn(description string, types ...PublicType) *FunctionReturn
 }
 
 func (fr *FunctionReturn) typesString() string {
-	isAny := true
-	for _, t := range ANY {
-		if !slices.Contains(fr.Types, t) {
-			isAny = false
-			break
-		}
-	}
-	if isAny {
-		return "ANY"
-	}
-	isBasic := true
-	for _, t := range BASIC {
-		if !slices.Contains(fr.Types, t) {
-			isBasic = false
-			break
-		}
-	}
-	if isBasic {
-		return "BASIC"
-	}
-	strs := []string{}
-	for _, t := range fr.Types {
-		strs = append(strs, string(t))
-	}
-	return strings.Join(strs, "|")
+	return typeListString(fr.Types)
 }
 
 type FunctionAttribute string
@@ -335,6 +603,8 @@ const (
 	FUNCTION_TAG_STRINGS         FunctionTag = "Strings"
 	FUNCTION_TAG_MAPS            FunctionTag = "Maps"
 	FUNCTION_TAG_ARRAYS          FunctionTag = "Arrays"
+	FUNCTION_TAG_LOOKUPS         FunctionTag = "Lookups"
+	FUNCTION_TAG_STATE           FunctionTag = "State"
 )
 
 type ProgramExample struct {
@@ -0,0 +1,275 @@
+package lang
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFunctionStoreCloneAndMerge(t *testing.T) {
+	original := NewFunctionStore()
+	original.Register(testFunctionStoreEntry("one", 1))
+
+	cloned := original.Clone()
+	cloned.Register(testFunctionStoreEntry("two", 2))
+	if _, err := original.get("std", "two"); err == nil {
+		t.Error("expected registering to a clone to leave the original unchanged")
+	}
+
+	other := NewFunctionStore()
+	other.RegisterToNamespace("extra", testFunctionStoreEntry("three", 3))
+	other.Register(testFunctionStoreEntry("one", 100))
+	if err := original.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		namespace string
+		name      string
+		want      string
+	}{
+		{"extra", "three", "3"},
+		{"std", "one", "100"},
+	} {
+		if err := testBuiltinFunctionEntry(original, "null", "SET @out = "+tt.namespace+"."+tt.name+"()", tt.want); err != nil {
+			t.Error(err)
+		}
+	}
+	if names := original.NamespaceNames(); strings.Join(names, ",") != "extra,std" {
+		t.Errorf("wrong namespace names. got=%v", names)
+	}
+}
+
+func TestFunctionStoreFreezeAndUnregister(t *testing.T) {
+	fstore := NewFunctionStore()
+	fstore.Register(testFunctionStoreEntry("one", 1))
+	fstore.Register(testFunctionStoreEntry("two", 2))
+
+	if err := fstore.Unregister("std", "two"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fstore.get("std", "two"); err == nil {
+		t.Error("expected function to be unregistered")
+	}
+	if err := fstore.Unregister("std", "two"); err == nil {
+		t.Error("expected an error unregistering a function that does not exist")
+	}
+
+	fstore.Freeze()
+	if !fstore.IsFrozen() {
+		t.Error("expected store to be frozen")
+	}
+	if err := fstore.Register(testFunctionStoreEntry("three", 3)); err == nil {
+		t.Error("expected an error registering to a frozen store")
+	}
+	if err := fstore.Unregister("std", "one"); err == nil {
+		t.Error("expected an error unregistering from a frozen store")
+	}
+	if err := fstore.Merge(NewFunctionStore()); err == nil {
+		t.Error("expected an error merging into a frozen store")
+	}
+	if fstore.Clone().IsFrozen() {
+		t.Error("expected a clone of a frozen store to be unfrozen")
+	}
+}
+
+func TestFunctionStoreProgramSnapshot(t *testing.T) {
+	fstore := NewFunctionStore()
+	fstore.Register(testFunctionStoreEntry("value", 1))
+	program, err := NewProgram(`SET @out = value()`, fstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for idx := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			fstore.Register(testFunctionStoreEntry("value", int64(idx+2)))
+		}()
+		go func() {
+			defer wg.Done()
+			got, err := program.Run([]byte(`null`))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if string(got) != "1" {
+				t.Errorf("expected compiled program to keep its original functions. got=%s", string(got))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFunctionStoreOptionalArgs(t *testing.T) {
+	fstore := NewFunctionStore()
+	err := fstore.Register(NewFunctionEntry("greet", "greets someone", func(ctx context.Context, args ...*Object) *Object {
+		strs := []string{}
+		for _, arg := range args {
+			str, _ := arg.AsString()
+			strs = append(strs, str)
+		}
+		return CastString(strings.Join(strs, " "))
+	}, WithArgs(
+		NewFunctionArg("name", "", STRING),
+		NewOptionalFunctionArg("greeting", "", CastString("hello"), STRING),
+		NewOptionalFunctionArg("suffix", "", nil, STRING),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fe, _ := fstore.get("std", "greet")
+	if want := `std.greet(name:STRING, greeting?:STRING="hello", suffix?:STRING)`; !strings.HasPrefix(fe.Signature(), want) {
+		t.Errorf("wrong signature. want=%s got=%s", want, fe.Signature())
+	}
+
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = greet("bob")`, `"bob hello"`},
+		{`SET @out = greet("bob", "hi")`, `"bob hi"`},
+		{`SET @out = greet("bob", suffix="!")`, `"bob hello !"`},
+		{`SET @out = greet(suffix="!", greeting="hey", name="bob")`, `"bob hey !"`},
+		{`SET @out = "bob" | greet(greeting="yo")`, `"bob yo"`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, "null", tt.program, tt.want); err != nil {
+			t.Errorf("%s: %s", tt.program, err)
+		}
+	}
+
+	errTests := []struct {
+		program string
+		wantErr string
+	}{
+		{`SET @out = greet()`, "too few arguments supplied"},
+		{`SET @out = greet(greeting="hi")`, `missing required argument "name"`},
+		{`SET @out = greet("bob", name="alice")`, `argument "name" was already passed by position`},
+		{`SET @out = greet("bob", nope=1)`, `has no argument named "nope"`},
+		{`SET @out = greet("bob", greeting=1)`, `invalid argument type for "greeting"`},
+		{`SET @out = greet("bob", null, "!")`, `invalid argument type for "greeting". want=STRING. got=NULL`},
+	}
+	for _, tt := range errTests {
+		program, err := NewProgram(tt.program, fstore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = program.Run([]byte(`null`))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error to contain %q. got=%v", tt.program, tt.wantErr, err)
+		}
+	}
+
+	// an explicit null is passed as is, rather than replaced by the default
+	err = fstore.Register(NewFunctionEntry("either", "", func(ctx context.Context, args ...*Object) *Object {
+		return args[0]
+	}, WithArgs(
+		NewOptionalFunctionArg("value", "", CastString("default"), STRING, NULL),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for program, want := range map[string]string{
+		`SET @out = either()`:           `"default"`,
+		`SET @out = either(null)`:       `null`,
+		`SET @out = either(value=null)`: `null`,
+		`SET @out = null | either()`:    `null`,
+		`SET @out = either("x")`:        `"x"`,
+	} {
+		if err := testBuiltinFunctionEntry(fstore, "null", program, want); err != nil {
+			t.Errorf("%s: %s", program, err)
+		}
+	}
+
+	err = fstore.Register(NewFunctionEntry("bad", "", nil, WithArgs(
+		NewOptionalFunctionArg("first", "", nil, STRING),
+		NewFunctionArg("second", "", STRING),
+	)))
+	if err == nil || !strings.Contains(err.Error(), "cannot come after an optional argument") {
+		t.Errorf("expected optional argument order error. got=%v", err)
+	}
+}
+
+func TestFunctionStoreVariadicArgs(t *testing.T) {
+	join := func(ctx context.Context, args ...*Object) *Object {
+		strs := []string{}
+		for _, arg := range args {
+			str, _ := arg.AsString()
+			strs = append(strs, str)
+		}
+		return CastString(strings.Join(strs, ","))
+	}
+	fstore := NewFunctionStore()
+	err := fstore.Register(NewFunctionEntry("join_some", "", join, WithArgs(NewFunctionArg("items", "", STRING)), WithAtributes(FUNCTION_ATTRIBUTE_VARIADIC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fstore.Register(NewFunctionEntry("join_any", "", join, WithArgs(NewOptionalFunctionArg("items", "", nil, STRING)), WithAtributes(FUNCTION_ATTRIBUTE_VARIADIC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = join_some("a", "b")`, `"a,b"`},
+		{`SET @out = join_any("a", "b")`, `"a,b"`},
+		{`SET @out = join_any()`, `""`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, "null", tt.program, tt.want); err != nil {
+			t.Errorf("%s: %s", tt.program, err)
+		}
+	}
+
+	program, err := NewProgram(`SET @out = join_some()`, fstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = program.Run([]byte(`null`))
+	if err == nil || !strings.Contains(err.Error(), `function "std.join_some" too few arguments supplied. want=1 got=0`) {
+		t.Errorf("expected a required variadic argument to need at least one value. got=%v", err)
+	}
+
+	err = fstore.Register(NewFunctionEntry("bad", "", join, WithArgs(NewOptionalFunctionArg("items", "", CastString("a"), STRING)), WithAtributes(FUNCTION_ATTRIBUTE_VARIADIC)))
+	if err == nil || !strings.Contains(err.Error(), "cannot have a default") {
+		t.Errorf("expected variadic default error. got=%v", err)
+	}
+}
+
+func TestFunctionStoreReservedNames(t *testing.T) {
+	fstore := DefaultFunctionStore()
+	if err := fstore.Register(testFunctionStoreEntry("has", 1)); err == nil || !strings.Contains(err.Error(), `function name "has" is reserved`) {
+		t.Errorf("expected reserved name error. got=%v", err)
+	}
+	if err := fstore.RegisterOverload(testFunctionStoreEntry("has", 1)); err == nil || !strings.Contains(err.Error(), `function name "has" is reserved`) {
+		t.Errorf("expected reserved name error for an overload. got=%v", err)
+	}
+	if err := fstore.RegisterToNamespace("custom", testFunctionStoreEntry("has", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := testBuiltinFunctionEntry(fstore, `{"a": 1}`, `SET @out = [custom.has(), has(@in.a)]`, `[1, true]`); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFunctionStoreBuiltinArgs(t *testing.T) {
+	fstore := DefaultFunctionStore()
+	for _, ns := range fstore.NamespaceNames() {
+		for _, fe := range fstore.Functions(ns) {
+			if err := fe.checkArgs(); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func testFunctionStoreEntry(name string, value int64) *FunctionEntry {
+	return NewFunctionEntry(name, "returns a fixed value", func(ctx context.Context, args ...*Object) *Object {
+		return CastInt(value)
+	})
+}
@@ -0,0 +1,367 @@
+package lang
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// creates a function entry from an ordinary Go function and registers it to the namespace.
+// see NewGoFunctionEntry for the supported function signatures.
+func RegisterGo(store *FunctionStore, namespace string, name string, fn any, opts ...functionEntryOpt) error {
+	fe, err := NewGoFunctionEntry(name, fn, opts...)
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return fmt.Errorf("function %q: cannot register to a nil function store", name)
+	}
+	return store.RegisterToNamespace(namespace, fe)
+}
+
+// creates a function entry from an ordinary Go function, such as func(ctx context.Context, s string, n int64) (string, error).
+// the argument and return types are inferred from the signature, and arguments are converted to their Go types before each call.
+//
+// the function may optionally take a context.Context as its first parameter, and may be variadic. like in Go, calls may leave out the variadic arguments.
+// parameters and results can be strings, booleans, integers, floats, time.Time, time.Duration, *Object, interface{}, or slices and string-keyed maps of those types.
+// results can also be structs, pointers, json.Marshaler, or encoding.TextMarshaler values, which are converted the way encoding/json would encode them.
+// it may return a single value, an error, or a value and an error. a non-nil error is returned to the program as an ERROR.
+//
+// Go does not keep parameter names, so arguments are named arg1, arg2, and so on. use WithArgNames to name them for documentation.
+func NewGoFunctionEntry(name string, fn any, opts ...functionEntryOpt) (*FunctionEntry, error) {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func || fnValue.IsNil() {
+		return nil, fmt.Errorf("function %q: expected a Go function. got=%T", name, fn)
+	}
+	sig, err := newGoSignature(fnValue.Type())
+	if err != nil {
+		return nil, fmt.Errorf("function %q: %w", name, err)
+	}
+	entryOpts := []functionEntryOpt{WithArgs(sig.args...)}
+	if sig.ret != nil {
+		entryOpts = append(entryOpts, WithReturn(sig.ret))
+	}
+	if fnValue.Type().IsVariadic() {
+		entryOpts = append(entryOpts, WithAtributes(FUNCTION_ATTRIBUTE_VARIADIC))
+	}
+	call := func(ctx context.Context, args ...*Object) *Object {
+		return sig.call(ctx, name, fnValue, args)
+	}
+	return NewFunctionEntry(name, "", call, append(entryOpts, opts...)...), nil
+}
+
+// names the arguments of a function entry, in order. it must be applied after the arguments are set, for example after WithArgs.
+func WithArgNames(names ...string) functionEntryOpt {
+	return func(fe *FunctionEntry) {
+		for idx, name := range names {
+			if idx < len(fe.Args) {
+				fe.Args[idx].Name = name
+			}
+		}
+	}
+}
+
+// creates a function entry from a typed Go function with one argument. see NewGoFunctionEntry.
+func Func1[A, R any](name string, fn func(context.Context, A) (R, error), opts ...functionEntryOpt) (*FunctionEntry, error) {
+	return NewGoFunctionEntry(name, fn, opts...)
+}
+
+// creates a function entry from a typed Go function with two arguments. see NewGoFunctionEntry.
+func Func2[A, B, R any](name string, fn func(context.Context, A, B) (R, error), opts ...functionEntryOpt) (*FunctionEntry, error) {
+	return NewGoFunctionEntry(name, fn, opts...)
+}
+
+// creates a function entry from a typed Go function with three arguments. see NewGoFunctionEntry.
+func Func3[A, B, C, R any](name string, fn func(context.Context, A, B, C) (R, error), opts ...functionEntryOpt) (*FunctionEntry, error) {
+	return NewGoFunctionEntry(name, fn, opts...)
+}
+
+var (
+	goContextType  = reflect.TypeFor[context.Context]()
+	goErrorType    = reflect.TypeFor[error]()
+	goObjectType   = reflect.TypeFor[*Object]()
+	goTimeType     = reflect.TypeFor[time.Time]()
+	goDurationType = reflect.TypeFor[time.Duration]()
+)
+
+// describes how to call a Go function from morph
+type goSignature struct {
+	hasCtx    bool
+	params    []reflect.Type // excludes the context. the last entry is the slice type of a variadic parameter
+	variadic  bool
+	hasResult bool
+	hasErr    bool
+	args      []FunctionArg
+	ret       *FunctionReturn
+}
+
+func newGoSignature(fnType reflect.Type) (*goSignature, error) {
+	sig := &goSignature{variadic: fnType.IsVariadic()}
+	for idx := range fnType.NumIn() {
+		t := fnType.In(idx)
+		if idx == 0 && t == goContextType {
+			sig.hasCtx = true
+			continue
+		}
+		sig.params = append(sig.params, t)
+		isVariadicParam := sig.variadic && idx == fnType.NumIn()-1
+		if isVariadicParam {
+			t = t.Elem()
+		}
+		types, err := goPublicTypes(t, true)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", len(sig.params), err)
+		}
+		name := fmt.Sprintf("arg%d", len(sig.params))
+		if isVariadicParam {
+			// like in Go, a variadic parameter may receive zero values
+			sig.args = append(sig.args, NewOptionalFunctionArg(name, "", nil, types...))
+			continue
+		}
+		sig.args = append(sig.args, NewFunctionArg(name, "", types...))
+	}
+
+	switch fnType.NumOut() {
+	case 0:
+	case 1:
+		if fnType.Out(0) == goErrorType {
+			sig.hasErr = true
+		} else {
+			sig.hasResult = true
+		}
+	case 2:
+		if fnType.Out(1) != goErrorType {
+			return nil, errors.New("the second result must be an error")
+		}
+		sig.hasResult = true
+		sig.hasErr = true
+	default:
+		return nil, errors.New("functions may return at most a value and an error")
+	}
+	if sig.hasResult {
+		types, err := goPublicTypes(fnType.Out(0), false)
+		if err != nil {
+			return nil, fmt.Errorf("result: %w", err)
+		}
+		sig.ret = NewFunctionReturn("", types...)
+	}
+	return sig, nil
+}
+
+func (sig *goSignature) call(ctx context.Context, name string, fnValue reflect.Value, args []*Object) *Object {
+	fixedCount := len(sig.params)
+	if sig.variadic {
+		fixedCount--
+	}
+	if len(args) < fixedCount || (!sig.variadic && len(args) > fixedCount) {
+		return ObjectError(fmt.Sprintf("function %s() wrong number of arguments. want=%d got=%d", name, fixedCount, len(args)))
+	}
+	in := []reflect.Value{}
+	if sig.hasCtx {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+	for idx, arg := range args {
+		var t reflect.Type
+		if sig.variadic && idx >= fixedCount {
+			t = sig.params[len(sig.params)-1].Elem()
+		} else {
+			t = sig.params[idx]
+		}
+		v, err := goValueFromObject(arg.inner, t)
+		if err != nil {
+			return ObjectError(fmt.Sprintf("function %s() argument %d: %s", name, idx+1, err.Error()))
+		}
+		in = append(in, v)
+	}
+	out := fnValue.Call(in)
+	if sig.hasErr {
+		if errVal := out[len(out)-1]; !errVal.IsNil() {
+			return ObjectError(errVal.Interface().(error).Error())
+		}
+	}
+	if !sig.hasResult {
+		return ObjectNull
+	}
+	obj, err := goValueToObject(out[0])
+	if err != nil {
+		return ObjectError(fmt.Sprintf("function %s() result: %s", name, err.Error()))
+	}
+	return &Object{inner: obj}
+}
+
+// returns the morph types that correspond to a Go type. float parameters also accept integers.
+func goPublicTypes(t reflect.Type, isParam bool) ([]PublicType, error) {
+	switch {
+	case t == goObjectType:
+		return ANY, nil
+	case t == goTimeType:
+		return []PublicType{TIME}, nil
+	case t == goDurationType:
+		return []PublicType{DURATION}, nil
+	case !isParam && t.Implements(goJSONMarshalerType):
+		return BASIC_WITHOUT_ERROR, nil
+	case !isParam && t.Implements(goTextMarshalerType):
+		return []PublicType{STRING}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return []PublicType{STRING}, nil
+	case reflect.Bool:
+		return []PublicType{BOOLEAN}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []PublicType{INTEGER}, nil
+	case reflect.Float32, reflect.Float64:
+		if isParam {
+			return []PublicType{FLOAT, INTEGER}, nil
+		}
+		return []PublicType{FLOAT}, nil
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return BASIC_WITHOUT_ERROR, nil
+		}
+	case reflect.Slice:
+		elems, err := goPublicTypes(t.Elem(), isParam)
+		if err != nil {
+			return nil, err
+		}
+		if goIsAnyElem(t.Elem()) {
+			return []PublicType{ARRAY}, nil
+		}
+		return []PublicType{ArrayOf(elems...)}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		elems, err := goPublicTypes(t.Elem(), isParam)
+		if err != nil {
+			return nil, err
+		}
+		if goIsAnyElem(t.Elem()) {
+			return []PublicType{MAP}, nil
+		}
+		return []PublicType{MapOf(elems...)}, nil
+	case reflect.Struct:
+		// results are converted like encoding/json would encode them
+		if !isParam {
+			return []PublicType{MAP}, nil
+		}
+	case reflect.Pointer:
+		if !isParam {
+			elems, err := goPublicTypes(t.Elem(), isParam)
+			if err != nil {
+				return nil, err
+			}
+			if slices.Contains(elems, NULL) {
+				return elems, nil
+			}
+			return append(slices.Clone(elems), NULL), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// reports whether a container element type accepts any value, so the container type does not need parameters
+func goIsAnyElem(t reflect.Type) bool {
+	return t == goObjectType || (t.Kind() == reflect.Interface && t.NumMethod() == 0)
+}
+
+// converts an object to a Go value of type t
+func goValueFromObject(obj object, t reflect.Type) (reflect.Value, error) {
+	switch {
+	case t == goObjectType:
+		return reflect.ValueOf(&Object{inner: obj}), nil
+	case t == goTimeType:
+		if v, ok := obj.(*objectTime); ok {
+			return reflect.ValueOf(v.value), nil
+		}
+	case t == goDurationType:
+		if v, ok := obj.(*objectDuration); ok {
+			return reflect.ValueOf(v.value), nil
+		}
+	}
+	ret := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		if v, ok := obj.(*objectString); ok {
+			ret.SetString(v.value)
+			return ret, nil
+		}
+	case reflect.Bool:
+		if v, ok := obj.(*objectBoolean); ok {
+			ret.SetBool(v.value)
+			return ret, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, ok := obj.(*objectInteger); ok {
+			if ret.OverflowInt(v.value) {
+				return ret, fmt.Errorf("integer %d overflows %s", v.value, t)
+			}
+			ret.SetInt(v.value)
+			return ret, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v, ok := obj.(*objectInteger); ok {
+			if v.value < 0 || ret.OverflowUint(uint64(v.value)) {
+				return ret, fmt.Errorf("integer %d overflows %s", v.value, t)
+			}
+			ret.SetUint(uint64(v.value))
+			return ret, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch v := obj.(type) {
+		case *objectFloat:
+			ret.SetFloat(v.value)
+			return ret, nil
+		case *objectInteger:
+			ret.SetFloat(float64(v.value))
+			return ret, nil
+		}
+	case reflect.Interface:
+		native, err := convertObjectToNative(obj)
+		if err != nil {
+			return ret, err
+		}
+		if native != nil {
+			ret.Set(reflect.ValueOf(native))
+		}
+		return ret, nil
+	case reflect.Slice:
+		if v, ok := obj.(*objectArray); ok {
+			ret = reflect.MakeSlice(t, 0, len(v.entries))
+			for idx, entry := range v.entries {
+				item, err := goValueFromObject(entry, t.Elem())
+				if err != nil {
+					return ret, fmt.Errorf("index %d: %w", idx, err)
+				}
+				ret = reflect.Append(ret, item)
+			}
+			return ret, nil
+		}
+	case reflect.Map:
+		if v, ok := obj.(*objectMap); ok {
+			ret = reflect.MakeMapWithSize(t, len(v.kvPairs))
+			for k, entry := range v.kvPairs {
+				item, err := goValueFromObject(entry, t.Elem())
+				if err != nil {
+					return ret, fmt.Errorf("key %q: %w", k, err)
+				}
+				ret.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), item)
+			}
+			return ret, nil
+		}
+	}
+	return ret, fmt.Errorf("unable to convert %s to %s", obj.getType(), t)
+}
+
+// converts a Go value returned by a function to an object
+func goValueToObject(v reflect.Value) (object, error) {
+	obj := convertValueToObject(v, false, 0)
+	if isObjectErr(obj) {
+		return nil, objectToError(obj)
+	}
+	return obj, nil
+}
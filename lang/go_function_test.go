@@ -0,0 +1,169 @@
+package lang
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoFunctionRegister(t *testing.T) {
+	fstore := DefaultFunctionStore()
+	err := RegisterGo(fstore, "go", "repeat", func(ctx context.Context, s string, n int64) (string, error) {
+		if n < 0 {
+			return "", errors.New("count cannot be negative")
+		}
+		return strings.Repeat(s, int(n)), nil
+	}, WithArgNames("text", "count"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RegisterGo(fstore, "go", "sum", func(nums ...float64) float64 {
+		total := 0.0
+		for _, n := range nums {
+			total += n
+		}
+		return total
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RegisterGo(fstore, "go", "keys", func(m map[string]interface{}) []string {
+		ret := []string{}
+		for k := range m {
+			ret = append(ret, k)
+		}
+		return ret
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upper, err := Func1("upper", func(ctx context.Context, s string) (string, error) {
+		return strings.ToUpper(s), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fstore.Register(upper)
+	label, err := Func2("label", func(ctx context.Context, name string, tags []string) (map[string]interface{}, error) {
+		return map[string]interface{}{"name": name, "tags": tags, "count": len(tags)}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fstore.Register(label)
+	type point struct {
+		X    int64  `json:"x"`
+		Y    int64  `json:"y"`
+		Name string `json:"name,omitempty"`
+	}
+	err = RegisterGo(fstore, "go", "point", func(x, y int64) *point {
+		return &point{X: x, Y: y}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RegisterGo(fstore, "go", "double", func(d time.Duration) time.Duration {
+		return d * 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = go.repeat("ab", 3)`, `"ababab"`},
+		{`SET @out = go.sum(1, 2.5, 3)`, `6.5`},
+		{`SET @out = go.sum()`, `0`},
+		{`SET @out = go.keys({"a": 1})`, `["a"]`},
+		{`SET @out = upper("abc")`, `"ABC"`},
+		{`SET @out = label("x", ["a", "b"])`, `{"name": "x", "tags": ["a", "b"], "count": 2}`},
+		{`SET @out = go.point(1, 2)`, `{"x": 1, "y": 2}`},
+		{`SET @out = go.double(duration("45m")) == duration("90m")`, `true`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, `null`, tt.program, tt.want); err != nil {
+			t.Error(err)
+		}
+	}
+
+	fe, err := fstore.get("go", "repeat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig := fe.Signature(); sig != "go.repeat(text:STRING, count:INTEGER) STRING" {
+		t.Errorf("wrong signature. got=%q", sig)
+	}
+	fe, err = fstore.get("go", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig := fe.Signature(); sig != "go.keys(arg1:MAP) ARRAY<STRING>" {
+		t.Errorf("wrong signature. got=%q", sig)
+	}
+	fe, err = fstore.get("go", "point")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig := fe.Signature(); sig != "go.point(arg1:INTEGER, arg2:INTEGER) MAP|NULL" {
+		t.Errorf("wrong signature. got=%q", sig)
+	}
+	fe, err = fstore.get("go", "double")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig := fe.Signature(); sig != "go.double(arg1:DURATION) DURATION" {
+		t.Errorf("wrong signature. got=%q", sig)
+	}
+}
+
+func TestGoFunctionErr(t *testing.T) {
+	fstore := DefaultFunctionStore()
+	err := RegisterGo(fstore, "std", "fail", func(n int8) (int8, error) {
+		return 0, fmt.Errorf("failed with %d", n)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		program string
+		wantErr string
+	}{
+		{`SET @out = fail(1)`, "failed with 1"},
+		{`SET @out = fail(1000)`, "fail() argument 1: integer 1000 overflows int8"},
+		{`SET @out = fail("a")`, `invalid argument type for "arg1"`},
+	}
+	for _, tt := range tests {
+		program, err := NewProgram(tt.program, fstore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = program.Run([]byte(`null`))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("expected error to contain %q. got=%v", tt.wantErr, err)
+		}
+	}
+
+	if err := RegisterGo(fstore.snapshot(), "std", "frozen", func() int64 { return 1 }); !errors.Is(err, errFunctionStoreFrozen) {
+		t.Errorf("expected registering to a frozen store to fail. got=%v", err)
+	}
+	if err := RegisterGo(nil, "std", "nil_store", func() int64 { return 1 }); err == nil {
+		t.Error("expected registering to a nil store to fail")
+	}
+
+	invalid := []any{
+		"not a function",
+		func(ch chan int) {},
+		func() (int, int) { return 0, 0 },
+		func() map[int]string { return nil },
+	}
+	for _, fn := range invalid {
+		if _, err := NewGoFunctionEntry("bad", fn); err == nil {
+			t.Errorf("expected an error registering %T", fn)
+		}
+	}
+}
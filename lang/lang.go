@@ -1,8 +1,11 @@
 package lang
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 )
 
 // public helpers for running a morph program.
@@ -10,43 +13,278 @@ import (
 type Program struct {
 	inner         *program
 	functionStore *FunctionStore
+	strict        bool
 }
 
-func NewProgram(programInput string, funcStore *FunctionStore) (*Program, error) {
+func NewProgram(programInput string, funcStore *FunctionStore, opts ...newProgramArg) (*Program, error) {
+	cfg := &programConfig{}
+	for _, fn := range opts {
+		fn(cfg)
+	}
 	l := newLexer([]rune(programInput))
 	p := newParser(l)
+	if cfg.modules != nil {
+		p.modules = &moduleLoader{resolver: cfg.modules, stack: []string{}}
+	}
 	program, err := p.parseProgram()
 	if err != nil {
 		return nil, err
 	}
+	return newCompiledProgram(program, funcStore, cfg)
+}
+
+// compiles a single Morph expression, such as a condition. running it sets @out to the value of the expression.
+// unlike NewProgram, the input cannot contain statements, and errors point at positions in the expression itself.
+func NewExpression(expressionInput string, funcStore *FunctionStore, opts ...newProgramArg) (*Program, error) {
+	cfg := &programConfig{}
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	p := newParser(newLexer([]rune(expressionInput)))
+	value := p.parseExpression(lowest)
+	if !p.hasErrors() && !p.isPeekToken(tok_eof) {
+		p.err(fmt.Sprintf("expected a single expression. unexpected sequence: %s", p.lexer.stringFromToken(p.peekToken)), p.peekToken.start)
+	}
+	if p.hasErrors() {
+		return nil, p.errors[0]
+	}
+	// errors about the assignment to @out point at the expression, since @out is not part of the input
+	outTok := value.token()
+	outTok.tokenType = tok_ident
+	outTok.value = "@out"
+	program := &program{statements: []statement{
+		&setStatement{tok: outTok, target: &identifierExpression{tok: outTok, value: "@out"}, value: value},
+	}}
+	return newCompiledProgram(program, funcStore, cfg)
+}
+
+// checks a parsed program against the function store and policy
+func newCompiledProgram(program *program, funcStore *FunctionStore, cfg *programConfig) (*Program, error) {
+	if funcStore == nil {
+		funcStore = NewFunctionStore() // a nil store behaves like an empty one
+	}
+	snapshot := funcStore.snapshot() // later changes to the store must not affect compiled programs
+	if err := cfg.policy.check(program, snapshot); err != nil {
+		return nil, err
+	}
+	if err := resolveOverloads(program, snapshot); err != nil {
+		return nil, err
+	}
 	return &Program{
 		inner:         program,
-		functionStore: funcStore,
+		functionStore: snapshot,
+		strict:        cfg.strict,
 	}, nil
 }
 
+type programConfig struct {
+	modules ModuleResolver
+	policy  FunctionPolicy
+	strict  bool
+}
+
+type newProgramArg func(*programConfig)
+
+// sets the resolver used to load the modules named by INCLUDE statements.
+// a nil resolver leaves INCLUDE statements unsupported.
+func WithModuleResolver(resolver ModuleResolver) newProgramArg {
+	return func(pc *programConfig) {
+		pc.modules = resolver
+	}
+}
+
+// when strict is true, reading a missing path or an undefined variable is an error with its line and column, instead of NULL.
+// use has() to check whether a path exists before reading it.
+func WithStrict(strict bool) newProgramArg {
+	return func(pc *programConfig) {
+		pc.strict = strict
+	}
+}
+
+// a parameter declared by a PARAM statement
+type Param struct {
+	Name       string
+	HasDefault bool
+}
+
+// returns the parameters declared by the program's PARAM statements, in declaration order
+func (p *Program) Params() []Param {
+	ret := []Param{}
+	for _, stmt := range p.inner.statements {
+		if ps, ok := stmt.(*paramStatement); ok {
+			ret = append(ret, Param{Name: ps.name.value, HasDefault: ps.value != nil})
+		}
+	}
+	return ret
+}
+
+// checks that vars contains a value for every parameter the program declares without a default
+func (p *Program) CheckVars(vars map[string]interface{}) error {
+	missing := []string{}
+	for _, param := range p.Params() {
+		if _, ok := vars[param.Name]; !ok && !param.HasDefault && !slices.Contains(missing, param.Name) {
+			missing = append(missing, param.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required parameters: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Result describes the outcome of a single program run
+type Result struct {
+	Output  []byte   // the JSON encoded value of @out. "null" if @out was never set, or if the run was dropped
+	Records [][]byte // the JSON encoded values passed to emit_record(), in the order they were emitted. empty if the run was dropped
+
+	Dropped      bool   // whether the program was halted by drop()
+	EmittedEarly bool   // whether the program was halted by emit()
+	Reason       string // the reason passed to drop() or emit(), if any
+
+	output   object // nil if @out was never set
+	records  []object
+	sortKeys bool // whether map keys are encoded in sorted order rather than insertion order
+}
+
+// returns every record produced by the run: the values passed to emit_record(), followed by @out if it was set
+func (r *Result) All() [][]byte {
+	ret := append([][]byte{}, r.Records...)
+	if r.output != nil {
+		ret = append(ret, r.Output)
+	}
+	return ret
+}
+
+// returns the value of @out, or NULL if it was never set or the run was dropped
+func (r *Result) OutputObject() *Object {
+	if r.output == nil {
+		return ObjectNull
+	}
+	return &Object{inner: r.output}
+}
+
+// returns the values passed to emit_record(), in the order they were emitted
+func (r *Result) RecordObjects() []*Object {
+	ret := []*Object{}
+	for _, record := range r.records {
+		ret = append(ret, &Object{inner: record})
+	}
+	return ret
+}
+
+// like All, but returns the records as objects rather than encoding them to JSON
+func (r *Result) AllObjects() []*Object {
+	ret := r.RecordObjects()
+	if r.output != nil {
+		ret = append(ret, &Object{inner: r.output})
+	}
+	return ret
+}
+
 func (p *Program) Run(inputData []byte) ([]byte, error) {
+	res, err := p.RunResult(inputData)
+	if err != nil {
+		return nil, err
+	}
+	return res.Output, nil
+}
+
+// runs the program against the input data and reports how the run finished.
+// opts configure the run environment; for example WithContext to pass a context to function calls.
+func (p *Program) RunResult(inputData []byte, opts ...newEnvArg) (*Result, error) {
 	inputObject := convertBytesToObject(inputData)
 	if isObjectErr(inputObject) {
 		return nil, objectToError(inputObject)
 	}
-	env := newEnvironment(p.functionStore)
-	if p.functionStore != nil {
-		env.functionStore = p.functionStore
+	ret, err := p.run(inputObject, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range ret.records {
+		b, err := convertObjectToJSONOrdered(record, ret.sortKeys)
+		if err != nil {
+			return nil, err
+		}
+		ret.Records = append(ret.Records, b)
+	}
+	if ret.output == nil {
+		return ret, nil
 	}
-	env.set("@in", convertBytesToObject(inputData))
+	output, err := convertObjectToJSONOrdered(ret.output, ret.sortKeys)
+	if err != nil {
+		return nil, err
+	}
+	ret.Output = output
+	return ret, nil
+}
+
+// runs the program against an already decoded input, such as the output of another program.
+// unlike RunResult, the output and records are not encoded to JSON; read them with OutputObject and AllObjects.
+func (p *Program) RunObject(input *Object, opts ...newEnvArg) (*Result, error) {
+	if isObjectErr(input.inner) {
+		return nil, objectToError(input.inner)
+	}
+	return p.run(input.inner.clone(), opts...)
+}
+
+func (p *Program) run(inputObject object, opts ...newEnvArg) (*Result, error) {
+	env := newEnvironment(p.functionStore, opts...)
+	env.strict = p.strict
+	sink := &recordSink{}
+	env.ctx = context.WithValue(env.ctx, recordSinkKey{}, sink)
+	env.ctx = context.WithValue(env.ctx, programKey{}, p)
+	lookups := env.lookups
+	if lookups == nil {
+		lookups = p.functionStore.lookups
+	}
+	if lookups != nil {
+		env.ctx = context.WithValue(env.ctx, lookupCacheCtxKey{}, newLookupCache(lookups))
+	}
+	if env.state != nil {
+		env.ctx = context.WithValue(env.ctx, stateStoreKey{}, env.state)
+	}
+	if env.clock != nil {
+		env.ctx = context.WithValue(env.ctx, clockKey{}, env.clock)
+	}
+	env.set("@in", inputObject)
+	varsObject := convertMapToObject(env.vars, false)
+	if isObjectErr(varsObject) {
+		return nil, fmt.Errorf("invalid @vars: %w", objectToError(varsObject))
+	}
+	env.set("@vars", varsObject)
 	res := p.inner.eval(env)
 	if isObjectErr(res) {
 		return nil, errors.New(res.inspect())
 	}
-	res, ok := env.get("@out")
-	if !ok {
-		return []byte("null"), nil
+	ret := &Result{
+		Output:   []byte("null"),
+		Records:  [][]byte{},
+		records:  []object{},
+		sortKeys: env.sortKeys,
 	}
-	outputIface, err := convertObjectToNative(res)
-	if err != nil {
-		return nil, err
+	if env.termination != nil {
+		ret.Dropped = env.termination.shouldReturnNull
+		ret.EmittedEarly = !env.termination.shouldReturnNull
+		ret.Reason = env.termination.reason
+	}
+	if ret.Dropped {
+		return ret, nil
+	}
+	ret.records = append(ret.records, sink.records...)
+	if out, ok := env.get("@out"); ok {
+		ret.output = out
 	}
-	return json.Marshal(outputIface)
+	return ret, nil
+}
+
+// collects the records passed to emit_record() during a single program run
+type recordSink struct {
+	records []object
+}
+
+type recordSinkKey struct{}
 
+func recordSinkFromContext(ctx context.Context) (*recordSink, bool) {
+	sink, ok := ctx.Value(recordSinkKey{}).(*recordSink)
+	return sink, ok
 }
@@ -11,6 +11,7 @@ type lexer struct {
 	currentIdx  int
 	nextIdx     int
 	isEnd       bool
+	source      string // the name of the included module being lexed. empty for the main program
 
 	context *lexContext
 }
@@ -476,7 +477,11 @@ func (l *lexer) stringFromToken(t token) string {
 }
 
 func (l *lexer) lineColString(targetIdx int) string {
-	return lineColString(lineAndCol(l.input, targetIdx))
+	lc := lineColString(lineAndCol(l.input, targetIdx))
+	if len(l.source) > 0 {
+		return l.source + ":" + lc
+	}
+	return lc
 }
 
 // reader helpers
@@ -489,7 +494,7 @@ func (l *lexer) readIdentifier() token {
 	if !isLetter(l.currentChar) {
 		return token{
 			tokenType: tok_illegal,
-			value:     "identifiers that start with @ must be @in or @out",
+			value:     "identifiers that start with @ must be @in, @out, or @vars",
 			start:     start,
 			end:       l.currentIdx,
 			lineCol:   l.lineColString(start),
@@ -503,10 +508,10 @@ func (l *lexer) readIdentifier() token {
 		endIdx = l.nextIdx
 	}
 	val := string(l.input[start:endIdx])
-	if strings.HasPrefix(val, "@") && !slices.Contains([]string{"@in", "@out"}, val) {
+	if strings.HasPrefix(val, "@") && !slices.Contains([]string{"@in", "@out", "@vars"}, val) {
 		return token{
 			tokenType: tok_illegal,
-			value:     "identifiers that start with @ must be @in or @out",
+			value:     "identifiers that start with @ must be @in, @out, or @vars",
 			start:     start,
 			end:       endIdx,
 			lineCol:   l.lineColString(start),
@@ -1075,7 +1075,7 @@ func TestLexIdentAtSign(t *testing.T) {
 		},
 		{
 			tokenType:  tok_illegal,
-			value:      "identifiers that start with @ must be @in or @out",
+			value:      "identifiers that start with @ must be @in, @out, or @vars",
 			start:      17,
 			end:        26,
 			rangeValue: "@nonsense",
@@ -0,0 +1,182 @@
+package lang
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// provides reference data, such as country codes or asset inventories, to the lookup builtin functions.
+// implementations must be safe for concurrent use, since one provider may serve many program runs at once.
+type LookupProvider interface {
+	// returns the value stored under key in the named table, and whether it was found.
+	Lookup(ctx context.Context, table string, key string) (interface{}, bool, error)
+}
+
+// caches lookup results for the duration of a single program run
+type lookupCache struct {
+	provider LookupProvider
+	entries  map[lookupCacheKey]lookupCacheEntry
+}
+
+type lookupCacheKey struct {
+	table string
+	key   string
+}
+
+type lookupCacheEntry struct {
+	value object
+	found bool
+}
+
+type lookupCacheCtxKey struct{}
+
+func newLookupCache(provider LookupProvider) *lookupCache {
+	return &lookupCache{
+		provider: provider,
+		entries:  make(map[lookupCacheKey]lookupCacheEntry),
+	}
+}
+
+func lookupCacheFromContext(ctx context.Context) (*lookupCache, bool) {
+	cache, ok := ctx.Value(lookupCacheCtxKey{}).(*lookupCache)
+	return cache, ok
+}
+
+// returns a clone of the value stored under key, or NULL and false if it does not exist
+func (lc *lookupCache) get(ctx context.Context, table string, key string) (object, bool, error) {
+	cacheKey := lookupCacheKey{table: table, key: key}
+	if entry, ok := lc.entries[cacheKey]; ok {
+		return entry.value.clone(), entry.found, nil
+	}
+	raw, found, err := lc.provider.Lookup(ctx, table, key)
+	if err != nil {
+		return nil, false, err
+	}
+	var value object = obj_global_null
+	if found {
+		value = convertAnyToObject(raw, false)
+		if isObjectErr(value) {
+			return nil, false, fmt.Errorf("invalid value for key %q in lookup table %q: %w", key, table, objectToError(value))
+		}
+	}
+	lc.entries[cacheKey] = lookupCacheEntry{value: value, found: found}
+	return value.clone(), found, nil
+}
+
+// an in-memory LookupProvider. tables can be set directly, or loaded from JSON or CSV data.
+type MemoryLookupProvider struct {
+	mu     sync.RWMutex
+	tables map[string]map[string]interface{}
+}
+
+func NewMemoryLookupProvider() *MemoryLookupProvider {
+	return &MemoryLookupProvider{
+		tables: make(map[string]map[string]interface{}),
+	}
+}
+
+func (mp *MemoryLookupProvider) Lookup(ctx context.Context, table string, key string) (interface{}, bool, error) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+	t, ok := mp.tables[table]
+	if !ok {
+		return nil, false, fmt.Errorf("lookup table %q does not exist", table)
+	}
+	val, ok := t[key]
+	return val, ok, nil
+}
+
+// returns the names of all tables in the provider, sorted
+func (mp *MemoryLookupProvider) Tables() []string {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+	return slices.Sorted(maps.Keys(mp.tables))
+}
+
+// sets the contents of a table, replacing any existing table with the same name
+func (mp *MemoryLookupProvider) SetTable(table string, entries map[string]interface{}) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.tables[table] = maps.Clone(entries)
+}
+
+// loads a table from a JSON object, where each top-level key maps to its value
+func (mp *MemoryLookupProvider) LoadJSON(table string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	obj := convertBytesToObject(b)
+	if isObjectErr(obj) {
+		return fmt.Errorf("unable to load lookup table %q: %w", table, objectToError(obj))
+	}
+	m, ok := obj.(*objectMap)
+	if !ok {
+		return fmt.Errorf("unable to load lookup table %q: JSON data must be an object. got=%s", table, obj.getType())
+	}
+	entries := make(map[string]interface{}, len(m.kvPairs))
+	for k, v := range m.kvPairs {
+		native, err := convertObjectToNative(v)
+		if err != nil {
+			return fmt.Errorf("unable to load lookup table %q: %w", table, err)
+		}
+		entries[k] = native
+	}
+	mp.SetTable(table, entries)
+	return nil
+}
+
+// loads a table from CSV data with a header row. each row is stored under the value of its keyColumn,
+// as a map of column names to string values.
+func (mp *MemoryLookupProvider) LoadCSV(table string, r io.Reader, keyColumn string) error {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return fmt.Errorf("unable to load lookup table %q: CSV data is missing a header row", table)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to load lookup table %q: %w", table, err)
+	}
+	keyIdx := slices.Index(header, keyColumn)
+	if keyIdx == -1 {
+		return fmt.Errorf("unable to load lookup table %q: key column %q not found in CSV header", table, keyColumn)
+	}
+	entries := make(map[string]interface{})
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("unable to load lookup table %q: %w", table, err)
+		}
+		row := make(map[string]interface{}, len(header))
+		for idx, col := range header {
+			row[col] = record[idx]
+		}
+		entries[record[keyIdx]] = row
+	}
+	mp.SetTable(table, entries)
+	return nil
+}
+
+// the reference data used when running the lookup builtin examples against a store without a lookup provider
+func exampleLookupProvider() LookupProvider {
+	provider := NewMemoryLookupProvider()
+	provider.SetTable("countries", map[string]interface{}{
+		"US": map[string]interface{}{"name": "United States", "region": "NA"},
+		"DE": map[string]interface{}{"name": "Germany", "region": "EU"},
+		"JP": map[string]interface{}{"name": "Japan", "region": "APAC"},
+	})
+	provider.SetTable("teams", map[string]interface{}{
+		"alice": "platform",
+		"bob":   "security",
+	})
+	return provider
+}
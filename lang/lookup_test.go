@@ -0,0 +1,64 @@
+package lang
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLookupMemoryLoadJSON(t *testing.T) {
+	provider := NewMemoryLookupProvider()
+	err := provider.LoadJSON("assets", strings.NewReader(`{"host-1": {"owner": "alice", "cores": 4}, "host-2": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key       string
+		want      interface{}
+		wantFound bool
+	}{
+		{"host-1", map[string]interface{}{"owner": "alice", "cores": int64(4)}, true},
+		{"host-2", nil, true},
+		{"host-3", nil, false},
+	}
+	for _, tt := range tests {
+		got, found, err := provider.Lookup(context.Background(), "assets", tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != tt.wantFound {
+			t.Errorf("key %q: wrong found value. want=%t got=%t", tt.key, tt.wantFound, found)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("key %q: wrong value. want=%+v got=%+v", tt.key, tt.want, got)
+		}
+	}
+
+	if err := provider.LoadJSON("bad", strings.NewReader(`[1, 2]`)); err == nil {
+		t.Error("expected an error loading a JSON array as a lookup table")
+	}
+}
+
+func TestLookupMemoryLoadCSV(t *testing.T) {
+	provider := NewMemoryLookupProvider()
+	err := provider.LoadCSV("users", strings.NewReader("user,team\nalice,platform\nbob,security\n"), "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := provider.Lookup(context.Background(), "users", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"user": "bob", "team": "security"}
+	if !found || !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong value. want=%+v got=%+v (found=%t)", want, got, found)
+	}
+
+	if _, _, err := provider.Lookup(context.Background(), "missing", "bob"); err == nil {
+		t.Error("expected an error looking up a table that does not exist")
+	}
+	if err := provider.LoadCSV("users", strings.NewReader("user,team\n"), "id"); err == nil {
+		t.Error("expected an error loading a CSV without the key column")
+	}
+}
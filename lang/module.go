@@ -0,0 +1,45 @@
+package lang
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// resolves the names used in INCLUDE statements to the Morph source code of shared modules
+type ModuleResolver interface {
+	Resolve(name string) (string, error)
+}
+
+// a ModuleResolver that serves modules from a map of names to source code
+type MapModuleResolver map[string]string
+
+func (mr MapModuleResolver) Resolve(name string) (string, error) {
+	source, ok := mr[name]
+	if !ok {
+		return "", fmt.Errorf("module %q does not exist", name)
+	}
+	return source, nil
+}
+
+// a ModuleResolver that reads modules from a file system, using the INCLUDE name as the file path
+type FSModuleResolver struct {
+	fsys fs.FS
+}
+
+func NewFSModuleResolver(fsys fs.FS) *FSModuleResolver {
+	return &FSModuleResolver{fsys: fsys}
+}
+
+func (fr *FSModuleResolver) Resolve(name string) (string, error) {
+	b, err := fs.ReadFile(fr.fsys, name)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// tracks the modules being included, so that include cycles can be detected
+type moduleLoader struct {
+	resolver ModuleResolver
+	stack    []string
+}
@@ -0,0 +1,94 @@
+package lang
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// compiles a snippet of Morph source into a function entry that can be registered like any builtin function.
+// each argument is bound to a variable named after its parameter, and the function returns the value of the 'return' variable, or NULL if it is never set.
+// arguments accept any type unless WithArgs is used to describe them, in which case it must list one argument per parameter.
+func NewMorphFunctionEntry(name string, params []string, source string, opts ...functionEntryOpt) (*FunctionEntry, error) {
+	for idx, param := range params {
+		if len(param) == 0 || strings.HasPrefix(param, "@") || param == "return" {
+			return nil, fmt.Errorf("function %q: invalid parameter name %q", name, param)
+		}
+		if slices.Contains(params[:idx], param) {
+			return nil, fmt.Errorf("function %q: duplicate parameter name %q", name, param)
+		}
+	}
+	l := newLexer([]rune(source))
+	l.source = name
+	p := newParser(l)
+	program, err := p.parseProgram()
+	if err != nil {
+		return nil, fmt.Errorf("function %q: %w", name, err)
+	}
+
+	args := []FunctionArg{}
+	for _, param := range params {
+		args = append(args, NewFunctionArg(param, "", ANY...))
+	}
+	fn := func(ctx context.Context, args ...*Object) *Object {
+		return runMorphFunction(ctx, name, program, params, args)
+	}
+	fe := NewFunctionEntry(name, "", fn, append([]functionEntryOpt{WithArgs(args...)}, opts...)...)
+	fe.body = program
+	if len(fe.Args) != len(params) {
+		return nil, fmt.Errorf("function %q: argument descriptions do not match parameters. want=%d got=%d", name, len(params), len(fe.Args))
+	}
+	return fe, nil
+}
+
+// the deepest that calls between Morph functions can nest, so that runaway recursion fails with an error instead of overflowing the stack
+const maxMorphCallDepth = 1000
+
+type morphCallDepthKey struct{}
+
+// runs the body of a Morph function with the function store and strictness of the program run that called it
+func runMorphFunction(ctx context.Context, name string, program *program, params []string, args []*Object) *Object {
+	if res, ok := IsArgCountEqual(len(params), args); !ok {
+		return res
+	}
+	depth, _ := ctx.Value(morphCallDepthKey{}).(int)
+	if depth >= maxMorphCallDepth {
+		return ObjectError(fmt.Sprintf("maximum function call depth of %d exceeded", maxMorphCallDepth))
+	}
+	ctx = context.WithValue(ctx, morphCallDepthKey{}, depth+1)
+	caller, ok := programFromContext(ctx)
+	if !ok {
+		return ObjectError(fmt.Sprintf("function %s() can only be called while a program runs", name))
+	}
+	env := newEnvironment(caller.functionStore, WithContext(ctx))
+	env.strict = caller.strict
+	for idx, param := range params {
+		env.set(param, args[idx].inner.clone())
+	}
+	for _, stmt := range program.statements {
+		obj := stmt.eval(env)
+		if isObjectErr(obj) {
+			return &Object{inner: obj}
+		}
+		if obj.getType() == t_terminate {
+			if obj.(*objectTerminate).shouldReturnNull {
+				return ObjectNull
+			}
+			break
+		}
+	}
+	ret, ok := env.get("return")
+	if !ok {
+		return ObjectNull
+	}
+	return &Object{inner: ret}
+}
+
+type programKey struct{}
+
+// returns the program whose run called a function, which holds the function store and strictness of the run
+func programFromContext(ctx context.Context) (*Program, bool) {
+	prog, ok := ctx.Value(programKey{}).(*Program)
+	return prog, ok
+}
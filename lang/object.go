@@ -1,7 +1,9 @@
 package lang
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 )
@@ -18,11 +20,12 @@ type object interface {
 const (
 	t_arrow objectType = "ARROW"
 
-	t_integer objectType = "INTEGER"
-	t_float   objectType = "FLOAT"
-	t_boolean objectType = "BOOLEAN"
-	t_string  objectType = "STRING"
-	t_time    objectType = "TIME"
+	t_integer  objectType = "INTEGER"
+	t_float    objectType = "FLOAT"
+	t_boolean  objectType = "BOOLEAN"
+	t_string   objectType = "STRING"
+	t_time     objectType = "TIME"
+	t_duration objectType = "DURATION"
 
 	t_map   objectType = "MAP"
 	t_array objectType = "ARRAY"
@@ -61,21 +64,51 @@ func (a *objectArray) isTruthy() bool {
 }
 
 type objectMap struct {
-	kvPairs map[string]object
+	kvPairs map[string]object // read entries directly, but only change them through set and delete, which keep keys in sync
+	keys    []string          // the keys of kvPairs, in the order they were first set
+}
+
+func newObjectMap() *objectMap {
+	return &objectMap{kvPairs: make(map[string]object)}
+}
+
+// sets the entry, keeping the key's original position if it already exists
+func (m *objectMap) set(key string, value object) {
+	if _, ok := m.kvPairs[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.kvPairs[key] = value
+}
+
+func (m *objectMap) delete(key string) {
+	if _, ok := m.kvPairs[key]; !ok {
+		return
+	}
+	delete(m.kvPairs, key)
+	m.keys = slices.DeleteFunc(m.keys, func(k string) bool { return k == key })
+}
+
+// returns the keys in insertion order, or sorted if sortKeys is set
+func (m *objectMap) orderedKeys(sortKeys bool) []string {
+	if sortKeys {
+		return slices.Sorted(slices.Values(m.keys))
+	}
+	return m.keys
 }
 
 func (m *objectMap) getType() objectType { return t_map }
 func (m *objectMap) inspect() string {
 	pairs := []string{}
-	for key, obj := range m.kvPairs {
-		pairString := fmt.Sprintf("%s: %s", key, obj.inspect())
+	for _, key := range m.keys {
+		pairString := fmt.Sprintf("%s: %s", key, m.kvPairs[key].inspect())
 		pairs = append(pairs, pairString)
 	}
 	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
 }
 func (m *objectMap) clone() object {
 	ret := &objectMap{
-		kvPairs: make(map[string]object),
+		kvPairs: make(map[string]object, len(m.kvPairs)),
+		keys:    slices.Clone(m.keys),
 	}
 	for key, obj := range m.kvPairs {
 		ret.kvPairs[key] = obj.clone()
@@ -140,6 +173,8 @@ type objectArrowFunction struct {
 	paramName  string
 	statements []statement
 	functions  *FunctionStore
+	ctx        context.Context // the context of the environment the arrow function was declared in
+	strict     bool            // whether the arrow function was declared in a strict program
 }
 
 func (af *objectArrowFunction) getType() objectType { return t_arrow }
@@ -175,12 +210,13 @@ func (n *objectNull) isTruthy() bool { return false }
 // returned by builtin funcs when signaling to halt further processing and return the env values as-is.
 type objectTerminate struct {
 	shouldReturnNull bool
+	reason           string // optional reason passed by the caller, reported to the host via Result.Reason
 }
 
 func (t *objectTerminate) getType() objectType { return t_terminate }
 func (t *objectTerminate) inspect() string     { return "TERMINATE" }
 func (t *objectTerminate) clone() object {
-	return &objectTerminate{shouldReturnNull: t.shouldReturnNull}
+	return &objectTerminate{shouldReturnNull: t.shouldReturnNull, reason: t.reason}
 }
 func (t *objectTerminate) isTruthy() bool { return false }
 
@@ -219,3 +255,20 @@ func (t *objectTime) clone() object {
 func (t *objectTime) isTruthy() bool {
 	return !t.value.IsZero()
 }
+
+//
+
+type objectDuration struct {
+	value time.Duration
+}
+
+func (d *objectDuration) getType() objectType { return t_duration }
+func (d *objectDuration) inspect() string {
+	return d.value.String()
+}
+func (d *objectDuration) clone() object {
+	return &objectDuration{value: d.value}
+}
+func (d *objectDuration) isTruthy() bool {
+	return d.value != 0
+}
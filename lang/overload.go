@@ -0,0 +1,260 @@
+package lang
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// registers fe as an additional overload of the function with the same name in the "std" namespace
+func (fs *FunctionStore) RegisterOverload(fe *FunctionEntry) error {
+	return fs.RegisterOverloadToNamespace("std", fe)
+}
+
+// registers fe as an additional overload of the function with the same name in the namespace.
+// calls are dispatched to the overload whose argument types match. if no function has the name yet, fe is registered as is.
+// an overload that could accept the same arguments as an existing overload is rejected as ambiguous.
+func (fs *FunctionStore) RegisterOverloadToNamespace(namespace string, fe *FunctionEntry) error {
+	if len(namespace) == 0 {
+		namespace = "std"
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.frozen {
+		return errFunctionStoreFrozen
+	}
+	if err := checkReservedName(namespace, fe); err != nil {
+		return err
+	}
+	if err := fe.checkArgs(); err != nil {
+		return err
+	}
+	ns, ok := fs.namespaces[namespace]
+	if !ok {
+		fs.register(namespace, fe)
+		return nil
+	}
+	existing, ok := ns.Functions[fe.Name]
+	if !ok {
+		fs.register(namespace, fe)
+		return nil
+	}
+	added := *fe
+	added.Namespace = namespace
+	added.overloads = nil
+	for _, other := range existing.Overloads() {
+		if added.overlaps(other) {
+			return fmt.Errorf("ambiguous overload for function %q: %s accepts the same arguments as %s", added.fullName(), added.Signature(), other.Signature())
+		}
+	}
+	// copy the entry, so that snapshots already taken by programs keep their overloads
+	primary := *existing
+	primary.overloads = append(slices.Clone(existing.overloads), &added)
+	ns.Functions[fe.Name] = &primary
+	return nil
+}
+
+// returns every overload of the function, starting with the entry itself
+func (fe *FunctionEntry) Overloads() []*FunctionEntry {
+	return append([]*FunctionEntry{fe}, fe.overloads...)
+}
+
+// picks the overload that accepts the arguments, after placing any named arguments
+func (fe *FunctionEntry) resolve(args []object, names []string, values []object) (*FunctionEntry, []object, error) {
+	if len(fe.overloads) == 0 {
+		if len(names) == 0 {
+			return fe, args, nil
+		}
+		bound, err := fe.bindNamedArgs(args, names, values)
+		return fe, bound, err
+	}
+	for _, overload := range fe.Overloads() {
+		bound := args
+		if len(names) > 0 {
+			var err error
+			bound, err = overload.bindNamedArgs(args, names, values)
+			if err != nil {
+				continue
+			}
+		}
+		if overload.accepts(bound) {
+			return overload, bound, nil
+		}
+	}
+	argTypes := []string{}
+	for _, t := range objectTypes(args) {
+		argTypes = append(argTypes, string(t))
+	}
+	for _, name := range names {
+		argTypes = append(argTypes, name+"=...")
+	}
+	return nil, nil, fe.noOverloadErr(argTypes)
+}
+
+func (fe *FunctionEntry) noOverloadErr(argTypes []string) error {
+	signatures := []string{}
+	for _, overload := range fe.Overloads() {
+		signatures = append(signatures, overload.Signature())
+	}
+	return fmt.Errorf("function %q has no overload that accepts (%s)\n\tfunction signatures:\n\t\t%s", fe.fullName(), strings.Join(argTypes, ", "), strings.Join(signatures, "\n\t\t"))
+}
+
+func objectTypes(args []object) []PublicType {
+	ret := []PublicType{}
+	for _, arg := range args {
+		ret = append(ret, PublicType(arg.getType()))
+	}
+	return ret
+}
+
+// returns the parameter that receives the argument at the index, and whether there is one
+func (fe *FunctionEntry) argAt(idx int) (FunctionArg, bool) {
+	if idx < len(fe.Args) {
+		return fe.Args[idx], true
+	}
+	if len(fe.Args) > 0 && slices.Contains(fe.Attributes, FUNCTION_ATTRIBUTE_VARIADIC) {
+		return fe.Args[len(fe.Args)-1], true
+	}
+	return FunctionArg{}, false
+}
+
+// reports whether the arguments can be passed to the function
+func (fe *FunctionEntry) accepts(args []object) bool {
+	return fe.acceptsFunc(len(args), func(idx int, types []PublicType) bool {
+		if args[idx] == obj_omitted_arg {
+			return true
+		}
+		_, ok := matchTypes(types, args[idx])
+		return ok
+	})
+}
+
+// reports whether arguments of the types can be passed to the function, when only the argument types are known
+func (fe *FunctionEntry) acceptsTypes(argTypes []PublicType) bool {
+	return fe.acceptsFunc(len(argTypes), func(idx int, types []PublicType) bool {
+		return slices.Contains(types, argTypes[idx])
+	})
+}
+
+func (fe *FunctionEntry) acceptsFunc(count int, matches func(idx int, types []PublicType) bool) bool {
+	if count < fe.requiredArgCount() {
+		return false
+	}
+	for idx := range count {
+		wantArg, ok := fe.argAt(idx)
+		if !ok {
+			return false
+		}
+		if len(wantArg.Types) == 0 {
+			continue
+		}
+		if !matches(idx, wantArg.Types) {
+			return false
+		}
+	}
+	return true
+}
+
+// reports whether some list of arguments could be passed to both functions
+func (fe *FunctionEntry) overlaps(other *FunctionEntry) bool {
+	maxCount := max(len(fe.Args), len(other.Args)) + 1 // one past the longest list covers any variadic parameters
+	for count := 0; count <= maxCount; count++ {
+		if fe.acceptsCount(count) && other.acceptsCount(count) && fe.sharesTypes(other, count) {
+			return true
+		}
+	}
+	return false
+}
+
+func (fe *FunctionEntry) acceptsCount(count int) bool {
+	if count < fe.requiredArgCount() {
+		return false
+	}
+	return count <= len(fe.Args) || slices.Contains(fe.Attributes, FUNCTION_ATTRIBUTE_VARIADIC)
+}
+
+// reports whether every one of the first count parameters of both functions accepts a common type
+func (fe *FunctionEntry) sharesTypes(other *FunctionEntry, count int) bool {
+	for idx := range count {
+		a, _ := fe.argAt(idx)
+		b, _ := other.argAt(idx)
+		if len(a.Types) == 0 || len(b.Types) == 0 {
+			continue
+		}
+		if !slices.ContainsFunc(a.Types, func(t PublicType) bool { return typeInList(t, b.Types) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// picks overloads at compile time for calls whose argument types are known from literals,
+// and reports calls that no overload accepts
+func resolveOverloads(prog *program, fstore *FunctionStore) error {
+	calls := []functionCall{}
+	collectFunctionCalls(prog, &calls)
+	for _, call := range calls {
+		if call.expr == nil || len(call.expr.namedArguments) > 0 {
+			continue
+		}
+		fe, err := fstore.get(call.namespace, call.name)
+		if err != nil || len(fe.overloads) == 0 || fe.hasParameterizedArgs() {
+			continue // element types of parameterized arguments are only known at runtime
+		}
+		argTypes, ok := staticTypes(call.expr.arguments)
+		if !ok {
+			continue
+		}
+		idx := slices.IndexFunc(fe.Overloads(), func(overload *FunctionEntry) bool { return overload.acceptsTypes(argTypes) })
+		if idx < 0 {
+			typeNames := []string{}
+			for _, t := range argTypes {
+				typeNames = append(typeNames, string(t))
+			}
+			return fmt.Errorf("%s: %w", call.lineCol, fe.noOverloadErr(typeNames))
+		}
+		call.expr.overload = fe.Overloads()[idx]
+	}
+	return nil
+}
+
+func (fe *FunctionEntry) hasParameterizedArgs() bool {
+	for _, overload := range fe.Overloads() {
+		for _, arg := range overload.Args {
+			if hasParameterizedType(arg.Types) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// returns the types of the expressions, if every one of them is a literal
+func staticTypes(exprs []expression) ([]PublicType, bool) {
+	ret := []PublicType{}
+	for _, expr := range exprs {
+		var t PublicType
+		switch expr.(type) {
+		case *stringLiteral, *templateExpression:
+			t = STRING
+		case *integerLiteral:
+			t = INTEGER
+		case *floatLiteral:
+			t = FLOAT
+		case *booleanLiteral:
+			t = BOOLEAN
+		case *nullLiteral:
+			t = NULL
+		case *arrayLiteral:
+			t = ARRAY
+		case *mapLiteral:
+			t = MAP
+		case *arrowFunctionExpression:
+			t = ARROWFUNC
+		default:
+			return nil, false
+		}
+		ret = append(ret, t)
+	}
+	return ret, true
+}
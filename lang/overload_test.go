@@ -0,0 +1,145 @@
+package lang
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOverloadDispatch(t *testing.T) {
+	fstore := NewFunctionStore()
+	if err := fstore.Register(testOverloadEntry("describe", "string", NewFunctionArg("value", "", STRING))); err != nil {
+		t.Fatal(err)
+	}
+	if err := fstore.RegisterOverload(testOverloadEntry("describe", "number", NewFunctionArg("value", "", INTEGER, FLOAT))); err != nil {
+		t.Fatal(err)
+	}
+	if err := fstore.RegisterOverload(testOverloadEntry("describe", "pair", NewFunctionArg("value", "", INTEGER), NewFunctionArg("other", "", ANY...))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		program string
+		in      string
+		want    string
+	}{
+		{`SET @out = describe("a")`, `null`, `"string"`},
+		{`SET @out = describe(1.5)`, `null`, `"number"`},
+		{`SET @out = describe(@in.value)`, `{"value": 3}`, `"number"`},
+		{`SET @out = describe(@in.value, null)`, `{"value": 3}`, `"pair"`},
+		{`SET @out = describe(value=@in.value, other=1)`, `{"value": 3}`, `"pair"`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, tt.in, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %s", tt.program, err)
+		}
+	}
+
+	// argument types known from literals are checked at compile time
+	if _, err := NewProgram(`SET @out = describe(true)`, fstore); err == nil || !strings.Contains(err.Error(), `1:12: function "std.describe" has no overload that accepts (BOOLEAN)`) {
+		t.Errorf("expected compile time overload error. got=%v", err)
+	}
+	program, err := NewProgram(`SET @out = describe(@in)`, fstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := program.Run([]byte(`true`)); err == nil || !strings.Contains(err.Error(), "std.describe(value:STRING) STRING") {
+		t.Errorf("expected runtime overload error listing signatures. got=%v", err)
+	}
+}
+
+func TestOverloadAmbiguous(t *testing.T) {
+	tests := []struct {
+		description string
+		existing    *FunctionEntry
+		added       *FunctionEntry
+	}{
+		{
+			"shared argument type",
+			testOverloadEntry("f", "a", NewFunctionArg("value", "", STRING, INTEGER)),
+			testOverloadEntry("f", "b", NewFunctionArg("value", "", INTEGER)),
+		},
+		{
+			"optional argument",
+			testOverloadEntry("f", "a", NewFunctionArg("value", "", STRING)),
+			testOverloadEntry("f", "b", NewFunctionArg("value", "", STRING), NewOptionalFunctionArg("other", "", nil, STRING)),
+		},
+		{
+			"untyped argument",
+			testOverloadEntry("f", "a", NewFunctionArg("value", "")),
+			testOverloadEntry("f", "b", NewFunctionArg("value", "", MAP)),
+		},
+	}
+	for _, tt := range tests {
+		fstore := NewFunctionStore()
+		fstore.Register(tt.existing)
+		err := fstore.RegisterOverload(tt.added)
+		if err == nil || !strings.Contains(err.Error(), "ambiguous overload") {
+			t.Errorf("%s: expected ambiguous overload error. got=%v", tt.description, err)
+		}
+	}
+}
+
+func TestOverloadSnapshot(t *testing.T) {
+	fstore := NewFunctionStore()
+	fstore.Register(testOverloadEntry("describe", "string", NewFunctionArg("value", "", STRING)))
+	program, err := NewProgram(`SET @out = describe(@in)`, fstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fstore.RegisterOverload(testOverloadEntry("describe", "number", NewFunctionArg("value", "", INTEGER)))
+	if _, err := program.Run([]byte(`1`)); err == nil {
+		t.Error("expected overloads registered after compiling to be ignored by the program")
+	}
+	if err := testBuiltinFunctionEntry(fstore, `1`, `SET @out = describe(@in)`, `"number"`); err != nil {
+		t.Error(err)
+	}
+}
+
+func testOverloadEntry(name string, result string, args ...FunctionArg) *FunctionEntry {
+	return NewFunctionEntry(name, "returns which overload was called", func(ctx context.Context, args ...*Object) *Object {
+		return CastString(result)
+	}, WithArgs(args...), WithReturn(NewFunctionReturn("the overload name", STRING)))
+}
+
+func TestOverloadBuiltinLen(t *testing.T) {
+	fstore := newBuiltinFunctionStore()
+	in := `{"list": [[1, 2], {"a": [3]}, null], "obj": {"a": {"b": 1}, "c": [1, 2]}}`
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = len("abc")`, `3`},
+		{`SET @out = [len("héllo"), len("日本")]`, `[5, 2]`},
+		{`SET @out = len(@in.list)`, `3`},
+		{`SET @out = len(@in.obj)`, `2`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, in, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %s", tt.program, err)
+		}
+	}
+
+	// each overload carries the tags of the type it accepts
+	fe, err := fstore.get("std", "len")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, overload := range fe.Overloads() {
+		var want FunctionTag
+		switch overload.Args[0].Types[0] {
+		case STRING:
+			want = FUNCTION_TAG_STRINGS
+		case ARRAY:
+			want = FUNCTION_TAG_ARRAYS
+		case MAP:
+			want = FUNCTION_TAG_MAPS
+		}
+		for _, tag := range []FunctionTag{FUNCTION_TAG_STRINGS, FUNCTION_TAG_ARRAYS, FUNCTION_TAG_MAPS} {
+			if has := slices.Contains(overload.Tags, tag); has != (tag == want) {
+				t.Errorf("%s: wrong tags. got=%v", overload.Signature(), overload.Tags)
+			}
+		}
+	}
+}
@@ -54,6 +54,10 @@ type parser struct {
 	infixFuncMap  map[tokenType]infixFunc
 
 	errors []error
+
+	modules *moduleLoader // resolves INCLUDE statements. nil if includes are not configured
+
+	keywordKeys bool // reads keywords after a dot as keys, such as if in a.if. only paths passed to the path functions use it
 }
 
 func newParser(l *lexer) *parser {
@@ -119,7 +123,22 @@ func (p *parser) next() {
 func (p *parser) parseProgram() (*program, error) {
 	program := &program{statements: []statement{}}
 	for !p.isCurrentToken(tok_eof) && !p.isCurrentToken(tok_illegal) {
-		statement := p.parseStatement()
+		p.checkStatementKeyword()
+		if p.isCurrentToken(tok_include) {
+			included := p.parseIncludeStatement()
+			if p.hasErrors() {
+				return nil, p.errors[0]
+			}
+			program.statements = append(program.statements, included...)
+			p.next()
+			continue
+		}
+		var statement statement
+		if p.isCurrentToken(tok_param) {
+			statement = p.parseParamStatement()
+		} else {
+			statement = p.parseStatement()
+		}
 		if p.hasErrors() {
 			return nil, p.errors[0]
 		}
@@ -130,7 +149,19 @@ func (p *parser) parseProgram() (*program, error) {
 	return program, nil
 }
 
+// turns an identifier at the start of a statement into a PARAM or INCLUDE keyword when it is followed by what the statement expects
+func (p *parser) checkStatementKeyword() {
+	if !p.isCurrentToken(tok_ident) {
+		return
+	}
+	keyword, ok := statementKeywordMap[strings.ToLower(p.currentToken.value)]
+	if ok && p.isPeekToken(keyword.next) {
+		p.currentToken.tokenType = keyword.tokenType
+	}
+}
+
 func (p *parser) parseStatement() statement {
+	p.checkStatementKeyword()
 	switch p.currentToken.tokenType {
 	case tok_set:
 		return p.parseSetStatement()
@@ -138,6 +169,12 @@ func (p *parser) parseStatement() statement {
 		return p.parseDelStatement()
 	case tok_if:
 		return p.parseIfStatement()
+	case tok_param:
+		p.err("PARAM statements are only allowed at the top level of a program", p.currentToken.start)
+		return nil
+	case tok_include:
+		p.err("INCLUDE statements are only allowed at the top level of a program", p.currentToken.start)
+		return nil
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -156,6 +193,9 @@ func (p *parser) parseExpression(precedence int) expression {
 		return nil
 	}
 	leftExp := prefixFn()
+	if leftExp == nil {
+		return nil // the error was already recorded by the prefix parse function
+	}
 
 	for precedence < p.peekPrecedence() {
 		infixFn := p.infixFuncMap[p.peekToken.tokenType]
@@ -164,6 +204,9 @@ func (p *parser) parseExpression(precedence int) expression {
 		}
 		p.next()
 		leftExp = infixFn(leftExp)
+		if leftExp == nil {
+			return nil
+		}
 	}
 	return leftExp
 }
@@ -202,6 +245,10 @@ func (p *parser) parsePipeCall(left expression) expression {
 	ret := &callExpression{isPipe: true, pipeTok: p.currentToken}
 	precedence := lookupPrecedence(p.currentToken.tokenType)
 	p.next()
+	if p.isCurrentToken(tok_ident) && p.currentToken.value == "has" && p.isPeekToken(tok_lparen) {
+		p.err("has() cannot be the right side of a pipe, because it checks a variable or path instead of a value. use has(@in.a) instead", p.currentToken.start)
+		return nil
+	}
 	right := p.parseExpression(precedence)
 	rightFunc, ok := right.(*callExpression)
 	if !ok {
@@ -211,6 +258,7 @@ func (p *parser) parsePipeCall(left expression) expression {
 	ret.tok = rightFunc.tok
 	ret.name = rightFunc.name
 	ret.arguments = append(args, rightFunc.arguments...)
+	ret.namedArguments = rightFunc.namedArguments
 	ret.endPos = rightFunc.endPos
 	return ret
 }
@@ -249,12 +297,70 @@ func (p *parser) parseCallExpression(left expression) expression {
 		p.err("invalid function name", left.position().start)
 		return nil
 	}
-	ret := &callExpression{tok: p.currentToken, name: funcName}
-	ret.arguments = p.parseExpressionList(tok_rparen)
+	ret := &callExpression{tok: p.currentToken, name: funcName, arguments: []expression{}}
+	if !p.parseCallArguments(ret) {
+		return nil
+	}
 	ret.endPos = p.currentToken.end
+	if ident, ok := funcName.(*identifierExpression); ok && ident.value == "has" {
+		return p.toHasExpression(ident, ret)
+	}
 	return ret
 }
 
+// has() looks like a call, but checks its argument for presence instead of evaluating it
+func (p *parser) toHasExpression(name *identifierExpression, call *callExpression) expression {
+	if len(call.arguments) != 1 || len(call.namedArguments) > 0 {
+		p.err("has() takes a single variable or path, such as has(@in.a.b)", name.tok.start)
+		return nil
+	}
+	switch call.arguments[0].(type) {
+	case *identifierExpression, *pathExpression, *indexExpression:
+	default:
+		p.err(fmt.Sprintf("has() takes a single variable or path, such as has(@in.a.b). got=%s", call.arguments[0].string()), call.arguments[0].position().start)
+		return nil
+	}
+	return &hasExpression{tok: name.tok, target: call.arguments[0], endPos: call.endPos}
+}
+
+// parses positional arguments, followed by any arguments passed as name=value
+func (p *parser) parseCallArguments(call *callExpression) bool {
+	if p.isPeekToken(tok_rparen) {
+		p.next()
+		return true
+	}
+	for {
+		p.next() // to the next argument
+		if p.isCurrentToken(tok_ident) && p.isPeekToken(tok_assign) {
+			named := &namedArgument{tok: p.currentToken, name: p.currentToken.value}
+			for _, existing := range call.namedArguments {
+				if existing.name == named.name {
+					p.err(fmt.Sprintf("duplicate named argument %q", named.name), named.tok.start)
+					return false
+				}
+			}
+			p.next() // to =
+			p.next() // to value
+			named.value = p.parseExpression(lowest)
+			call.namedArguments = append(call.namedArguments, named)
+		} else {
+			if len(call.namedArguments) > 0 {
+				p.err("positional arguments must come before named arguments", p.currentToken.start)
+				return false
+			}
+			call.arguments = append(call.arguments, p.parseExpression(lowest))
+		}
+		if p.hasErrors() {
+			return false
+		}
+		if !p.isPeekToken(tok_comma) {
+			break
+		}
+		p.next() // to comma
+	}
+	return p.mustNextToken(tok_rparen)
+}
+
 func (p *parser) parseMapLiteral() expression {
 	ret := &mapLiteral{tok: p.currentToken}
 	ret.pairs = make(map[string]expression)
@@ -282,6 +388,9 @@ func (p *parser) parseMapLiteral() expression {
 			return nil
 		}
 		p.next() // from colon to right side expression
+		if _, ok := ret.pairs[strNode.value]; !ok {
+			ret.keys = append(ret.keys, strNode.value)
+		}
 		ret.pairs[strNode.value] = p.parseExpression(lowest)
 		if p.hasErrors() {
 			return nil
@@ -413,8 +522,16 @@ func (p *parser) parsePathExpression(left expression) expression {
 	}
 	ret.left = leftPart
 	p.next()
+	if p.keywordKeys {
+		if _, ok := keywordMap[strings.ToLower(p.currentToken.value)]; ok {
+			p.currentToken.tokenType = tok_ident
+		}
+	}
 
 	itemCandidate := p.parseExpression(precedence)
+	if itemCandidate == nil {
+		return nil // the error was already recorded while parsing the attribute
+	}
 	item, ok := itemCandidate.(pathPartExpression)
 	if !ok {
 		p.err(fmt.Sprintf("invalid path expression: %s", itemCandidate.string()), itemCandidate.position().start)
@@ -489,6 +606,9 @@ func (p *parser) parseSetStatement() *setStatement {
 	if p.currentToken.value == "@in" { // restrict modification of "@in" via set statement
 		p.err("SET statement cannot modify @in data", p.currentToken.start)
 	}
+	if p.currentToken.value == "@vars" {
+		p.err("SET statement cannot modify @vars data", p.currentToken.start)
+	}
 	potentialTarget := p.parseExpression(lowest)
 	target, ok := potentialTarget.(assignable)
 	if !ok {
@@ -516,6 +636,9 @@ func (p *parser) parseDelStatement() *delStatement {
 	if p.currentToken.value == "@in" {
 		p.err("DEL statement cannot delete @in data", p.currentToken.start)
 	}
+	if p.currentToken.value == "@vars" {
+		p.err("DEL statement cannot delete @vars data", p.currentToken.start)
+	}
 	potentialTarget := p.parseExpression(lowest)
 	target, ok := potentialTarget.(assignable)
 	if !ok {
@@ -530,6 +653,61 @@ func (p *parser) parseDelStatement() *delStatement {
 	return ret
 }
 
+// parses an included module, and returns its statements to be spliced into the program in place of the INCLUDE statement
+func (p *parser) parseIncludeStatement() []statement {
+	includeTok := p.currentToken
+	if !p.mustNextToken(tok_string) {
+		return nil
+	}
+	name := p.currentToken.value
+	if p.modules == nil {
+		p.err("INCLUDE statements require a module resolver", includeTok.start)
+		return nil
+	}
+	if slices.Contains(p.modules.stack, name) {
+		cycle := append(slices.Clone(p.modules.stack), name)
+		p.err(fmt.Sprintf("include cycle detected: %s", strings.Join(cycle, " -> ")), p.currentToken.start)
+		return nil
+	}
+	source, err := p.modules.resolver.Resolve(name)
+	if err != nil {
+		p.err(fmt.Sprintf("unable to include %q: %s", name, err.Error()), p.currentToken.start)
+		return nil
+	}
+	l := newLexer([]rune(source))
+	l.source = name
+	child := newParser(l)
+	child.modules = &moduleLoader{
+		resolver: p.modules.resolver,
+		stack:    append(slices.Clone(p.modules.stack), name),
+	}
+	included, err := child.parseProgram()
+	if err != nil {
+		p.errors = append(p.errors, err)
+		return nil
+	}
+	return included.statements
+}
+
+func (p *parser) parseParamStatement() *paramStatement {
+	ret := &paramStatement{tok: p.currentToken}
+	if !p.mustNextToken(tok_ident) {
+		return nil
+	}
+	if strings.HasPrefix(p.currentToken.value, "@") {
+		p.err("PARAM names cannot start with @", p.currentToken.start)
+		return nil
+	}
+	ret.name = &identifierExpression{tok: p.currentToken, value: p.currentToken.value}
+	if !p.isPeekToken(tok_assign) {
+		return ret
+	}
+	p.next() // to assign
+	p.next() // to default value
+	ret.value = p.parseExpression(lowest)
+	return ret
+}
+
 func (p *parser) parseIfStatement() *ifStatement {
 	ret := &ifStatement{tok: p.currentToken, consequence: []statement{}}
 	p.next() // to expr
@@ -609,8 +787,7 @@ func (p *parser) mustCurrentToken(t tokenType) bool {
 }
 
 func (p *parser) err(message string, position int) {
-	line, col := lineAndCol(p.lexer.input, position)
-	err := fmt.Errorf("parsing error at %d:%d:\n\t%s", line, col, message)
+	err := fmt.Errorf("parsing error at %s:\n\t%s", p.lexer.lineColString(position), message)
 	p.errors = append(p.errors, err)
 }
 
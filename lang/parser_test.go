@@ -1,6 +1,7 @@
 package lang
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -91,6 +92,45 @@ func TestParseFunctionCall(t *testing.T) {
 	}
 }
 
+func TestParseFunctionCallNamedArgs(t *testing.T) {
+	input := `@in.when | parse_time(format="unix", tz=@vars.tz)`
+	program := setupParserTest(t, input)
+	checkParserProgramLength(t, program, 1)
+	checkParserStatementType(t, program.statements[0], EXPRESSION_STATEMENT)
+	stmt := program.statements[0].(*expressionStatement)
+	callExpr, ok := stmt.expression.(*callExpression)
+	if !ok {
+		t.Fatalf("stmt.expression is not of type *callExpression. got=%T", stmt.expression)
+	}
+	if len(callExpr.arguments) != 1 {
+		t.Fatalf("expected arguments to be len 1. got=%d", len(callExpr.arguments))
+	}
+	if len(callExpr.namedArguments) != 2 {
+		t.Fatalf("expected named arguments to be len 2. got=%d", len(callExpr.namedArguments))
+	}
+	if got := callExpr.namedArguments[0]; got.name != "format" {
+		t.Errorf("wrong name for named argument. want=format got=%s", got.name)
+	}
+	testLiteralExpression(t, callExpr.namedArguments[0].value, "unix")
+	if got := callExpr.namedArguments[1].name; got != "tz" {
+		t.Errorf("wrong name for named argument. want=tz got=%s", got)
+	}
+
+	errTests := []struct {
+		input   string
+		wantErr string
+	}{
+		{`myfunc(a=1, 2)`, "positional arguments must come before named arguments"},
+		{`myfunc(a=1, a=2)`, `duplicate named argument "a"`},
+	}
+	for _, tt := range errTests {
+		_, err := newParser(newLexer([]rune(tt.input))).parseProgram()
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error to contain %q. got=%v", tt.input, tt.wantErr, err)
+		}
+	}
+}
+
 func TestParseIndexExpression(t *testing.T) {
 	input := "myArray[3+2]"
 	program := setupParserTest(t, input)
@@ -270,6 +310,45 @@ func TestParseDelStatement(t *testing.T) {
 	testLiteralExpression(t, myvarIndent, "myvar")
 }
 
+func TestParseParamStatement(t *testing.T) {
+	input := `PARAM threshold = 10
+	PARAM region`
+	program := setupParserTest(t, input)
+	checkParserProgramLength(t, program, 2)
+	checkParserStatementType(t, program.statements[0], PARAM_STATEMENT)
+	stmt := program.statements[0].(*paramStatement)
+	testLiteralExpression(t, stmt.name, "threshold")
+	testLiteralExpression(t, stmt.value, 10)
+
+	checkParserStatementType(t, program.statements[1], PARAM_STATEMENT)
+	stmt = program.statements[1].(*paramStatement)
+	testLiteralExpression(t, stmt.name, "region")
+	if stmt.value != nil {
+		t.Errorf("expected PARAM without a default to have a nil value. got=%T", stmt.value)
+	}
+}
+
+func TestParseIncludeStatement(t *testing.T) {
+	input := `SET a = 1
+	INCLUDE "shared"
+	SET c = 3`
+	l := newLexer([]rune(input))
+	p := newParser(l)
+	p.modules = &moduleLoader{resolver: MapModuleResolver{"shared": "SET b = 2\nDEL b"}, stack: []string{}}
+	program, err := p.parseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkParserProgramLength(t, program, 4)
+	checkParserStatementType(t, program.statements[0], SET_STATEMENT)
+	checkParserStatementType(t, program.statements[1], SET_STATEMENT)
+	checkParserStatementType(t, program.statements[2], DEL_STATEMENT)
+	checkParserStatementType(t, program.statements[3], SET_STATEMENT)
+	if lc := program.statements[1].token().lineCol; lc != "shared:1:1" {
+		t.Errorf("expected included statement position to include the module name. want=%q got=%q", "shared:1:1", lc)
+	}
+}
+
 func TestParseSetStatement(t *testing.T) {
 	input := "SET myvar = 5"
 	program := setupParserTest(t, input)
@@ -648,6 +727,7 @@ const (
 	SET_STATEMENT
 	IF_STATEMENT
 	DEL_STATEMENT
+	PARAM_STATEMENT
 )
 
 func checkParserStatementType(t *testing.T, statement statement, stype statementType) {
@@ -668,6 +748,10 @@ func checkParserStatementType(t *testing.T, statement statement, stype statement
 		if _, ok := statement.(*ifStatement); !ok {
 			t.Fatalf("statement is not of type *ifStatement. got=%T", statement)
 		}
+	case PARAM_STATEMENT:
+		if _, ok := statement.(*paramStatement); !ok {
+			t.Fatalf("statement is not of type *paramStatement. got=%T", statement)
+		}
 	default:
 		t.Errorf("statment type not supported")
 	}
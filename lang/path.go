@@ -0,0 +1,288 @@
+package lang
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// one step of a path into a value: either a map key or an array index
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+	pointer bool // a JSON Pointer reference token, which is used as an index on arrays and as a key on maps
+}
+
+// resolves a JSON Pointer step against the object it is applied to. other steps are returned as is.
+func (ps pathStep) forObject(obj object) (pathStep, error) {
+	if !ps.pointer {
+		return ps, nil
+	}
+	if _, ok := obj.(*objectArray); !ok {
+		return pathStep{key: ps.key}, nil
+	}
+	idx, err := strconv.Atoi(ps.key)
+	if err != nil || idx < 0 || strings.HasPrefix(ps.key, "+") || (len(ps.key) > 1 && ps.key[0] == '0') {
+		return ps, fmt.Errorf("%q is not a valid array index", ps.key)
+	}
+	return pathStep{index: idx, isIndex: true}, nil
+}
+
+// parses a path like a.b[0]."some key" into its steps, with the same parser as paths in programs.
+// keys are identifiers or double quoted strings, separated by dots, and array indexes are non-negative integers in brackets.
+// an empty path has no steps, and refers to the value itself.
+func parsePath(path string) ([]pathStep, error) {
+	if len(path) == 0 {
+		return []pathStep{}, nil
+	}
+	// the path is parsed as a path from @in, so that paths that start with an index are path expressions too
+	root := "@in."
+	if strings.HasPrefix(path, "[") {
+		root = "@in"
+	}
+	p := newParser(newLexer([]rune(root + path)))
+	p.keywordKeys = true // a key like "if" can't be a keyword here, since a path holds no statements or values
+	expr := p.parseExpression(lowest)
+	if !p.hasErrors() && !p.isPeekToken(tok_eof) {
+		p.err(fmt.Sprintf("unexpected sequence: %s", p.lexer.stringFromToken(p.peekToken)), p.peekToken.start)
+	}
+	if p.hasErrors() {
+		// positions would include the @in prefix, so only the message is kept
+		_, msg, _ := strings.Cut(p.errors[0].Error(), "\n\t")
+		return nil, fmt.Errorf("invalid path %q: %s", path, msg)
+	}
+	steps := []pathStep{}
+	if err := appendPathSteps(&steps, expr); err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	return steps[1:], nil // the first step is the @in root
+}
+
+// flattens a parsed path expression into steps, from the root outwards
+func appendPathSteps(steps *[]pathStep, expr expression) error {
+	switch v := expr.(type) {
+	case *identifierExpression:
+		*steps = append(*steps, pathStep{key: v.value})
+	case *stringLiteral:
+		*steps = append(*steps, pathStep{key: v.value})
+	case *pathExpression:
+		if err := appendPathSteps(steps, v.left); err != nil {
+			return err
+		}
+		return appendPathSteps(steps, v.attribute)
+	case *indexExpression:
+		if err := appendPathSteps(steps, v.left); err != nil {
+			return err
+		}
+		idx, ok := v.index.(*integerLiteral)
+		if !ok {
+			return fmt.Errorf("index %s must be a non-negative integer", v.index.string())
+		}
+		*steps = append(*steps, pathStep{index: int(idx.value), isIndex: true})
+	default:
+		return fmt.Errorf("%s is not a key or an index", expr.string())
+	}
+	return nil
+}
+
+// parses a JSON Pointer (RFC 6901) like /a/b/2/c into its steps. the empty pointer refers to the value itself.
+func parseJSONPointer(ptr string) ([]pathStep, error) {
+	steps := []pathStep{}
+	if len(ptr) == 0 {
+		return steps, nil
+	}
+	if ptr[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q: pointers must be empty or start with /", ptr)
+	}
+	for _, token := range strings.Split(ptr[1:], "/") {
+		for idx := 0; idx < len(token); idx++ {
+			if token[idx] == '~' && (idx+1 == len(token) || (token[idx+1] != '0' && token[idx+1] != '1')) {
+				return nil, fmt.Errorf("invalid JSON pointer %q: ~ must be escaped as ~0", ptr)
+			}
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		steps = append(steps, pathStep{key: token, pointer: true})
+	}
+	return steps, nil
+}
+
+// follows the steps from the object. missing map keys and null values resolve to null, like paths in programs.
+func getPath(obj object, steps []pathStep) (object, error) {
+	current := obj
+	for idx, step := range steps {
+		if current == obj_global_null {
+			return obj_global_null, nil
+		}
+		next, ok, err := pathChild(current, step)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pathString(steps[:idx+1]), err)
+		}
+		if !ok {
+			return obj_global_null, nil
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// returns the entry of a map or array that the step refers to, and whether it exists
+func pathChild(obj object, step pathStep) (object, bool, error) {
+	step, err := step.forObject(obj)
+	if err != nil {
+		return nil, false, err
+	}
+	if step.isIndex {
+		arr, ok := obj.(*objectArray)
+		if !ok {
+			return nil, false, fmt.Errorf("cannot index a non-array object of type %s", obj.getType())
+		}
+		if step.index >= len(arr.entries) {
+			return nil, false, fmt.Errorf("index is out of range for target array")
+		}
+		return arr.entries[step.index], true, nil
+	}
+	m, ok := obj.(*objectMap)
+	if !ok {
+		return nil, false, fmt.Errorf("cannot access a key on a non-map object of type %s", obj.getType())
+	}
+	entry, ok := m.kvPairs[step.key]
+	return entry, ok, nil
+}
+
+// sets the value at the steps, creating any missing maps along the way like SET does.
+// array entries can be replaced, but arrays are never grown.
+func setPath(obj object, steps []pathStep, value object) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("cannot set an empty path")
+	}
+	current := obj
+	for idx, step := range steps {
+		last := idx == len(steps)-1
+		step, err := step.forObject(current)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pathString(steps[:idx+1]), err)
+		}
+		if step.isIndex {
+			arr, ok := current.(*objectArray)
+			if !ok {
+				return fmt.Errorf("%s: cannot index a non-array object of type %s", pathString(steps[:idx+1]), current.getType())
+			}
+			if step.index >= len(arr.entries) {
+				return fmt.Errorf("%s: index is out of range for target array", pathString(steps[:idx+1]))
+			}
+			if last {
+				arr.entries[step.index] = value
+				return nil
+			}
+			current = arr.entries[step.index]
+			continue
+		}
+		m, ok := current.(*objectMap)
+		if !ok {
+			return fmt.Errorf("%s: cannot access a key on a non-map object of type %s", pathString(steps[:idx+1]), current.getType())
+		}
+		if last {
+			m.set(step.key, value)
+			return nil
+		}
+		next, ok := m.kvPairs[step.key]
+		if !ok {
+			next = newObjectMap()
+			m.set(step.key, next)
+		}
+		current = next
+	}
+	return nil
+}
+
+// reports whether every step of the path exists. keys set to null exist, but nothing exists beneath them.
+func hasPath(obj object, steps []pathStep) bool {
+	current := obj
+	for _, step := range steps {
+		next, ok, err := pathChild(current, step)
+		if err != nil || !ok {
+			return false
+		}
+		current = next
+	}
+	return true
+}
+
+// removes the map key or array entry at the end of the path, and reports whether it existed.
+// later array entries shift down to fill the gap.
+func delPath(obj object, steps []pathStep) (bool, error) {
+	if len(steps) == 0 {
+		return false, fmt.Errorf("cannot delete an empty path")
+	}
+	parent, err := getPath(obj, steps[:len(steps)-1])
+	if err != nil {
+		return false, err
+	}
+	last, err := steps[len(steps)-1].forObject(parent)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", pathString(steps), err)
+	}
+	switch v := parent.(type) {
+	case *objectMap:
+		if last.isIndex {
+			return false, fmt.Errorf("%s: cannot index a non-array object of type %s", pathString(steps), parent.getType())
+		}
+		_, ok := v.kvPairs[last.key]
+		v.delete(last.key)
+		return ok, nil
+	case *objectArray:
+		if !last.isIndex {
+			return false, fmt.Errorf("%s: cannot access a key on a non-map object of type %s", pathString(steps), parent.getType())
+		}
+		if last.index >= len(v.entries) {
+			return false, nil
+		}
+		v.entries = slices.Delete(v.entries, last.index, last.index+1)
+		return true, nil
+	case *objectNull:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s: cannot delete from a non-map object of type %s", pathString(steps), parent.getType())
+	}
+}
+
+// renders steps back into path syntax, for error messages
+func pathString(steps []pathStep) string {
+	var sb strings.Builder
+	if len(steps) > 0 && steps[0].pointer {
+		for _, step := range steps {
+			sb.WriteString("/")
+			sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(step.key, "~", "~0"), "/", "~1"))
+		}
+		return sb.String()
+	}
+	for idx, step := range steps {
+		switch {
+		case step.isIndex:
+			fmt.Fprintf(&sb, "[%d]", step.index)
+			continue
+		case idx > 0:
+			sb.WriteString(".")
+		}
+		if isPathIdentifier(step.key) {
+			sb.WriteString(step.key)
+		} else {
+			sb.WriteString(strconv.Quote(step.key))
+		}
+	}
+	return sb.String()
+}
+
+func isPathIdentifier(key string) bool {
+	if len(key) == 0 || !isLetter(rune(key[0])) {
+		return false
+	}
+	for _, char := range key {
+		if !isLetter(char) && !isDigit(char) && !isLineChar(char) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package lang
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPathParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []pathStep
+	}{
+		{``, []pathStep{}},
+		{`a`, []pathStep{{key: "a"}}},
+		{`a.b-c.d_1`, []pathStep{{key: "a"}, {key: "b-c"}, {key: "d_1"}}},
+		{`a[0][12].b`, []pathStep{{key: "a"}, {index: 0, isIndex: true}, {index: 12, isIndex: true}, {key: "b"}}},
+		{`[1]`, []pathStep{{index: 1, isIndex: true}}},
+		{`a."some key"."quote\"d"`, []pathStep{{key: "a"}, {key: "some key"}, {key: `quote"d`}}},
+		{`if.null[0].True`, []pathStep{{key: "if"}, {key: "null"}, {index: 0, isIndex: true}, {key: "True"}}},
+	}
+	for _, tt := range tests {
+		got, err := parsePath(tt.input)
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: wrong steps. want=%v got=%v", tt.input, tt.want, got)
+		}
+		if rendered := pathString(got); rendered != tt.input {
+			t.Errorf("wrong rendered path. want=%s got=%s", tt.input, rendered)
+		}
+	}
+
+	// paths are parsed by the language parser, so these are the parser's messages for the same mistakes in a program.
+	// the error positions are left out, since they would count the @in prefix that the path is parsed with.
+	errTests := []struct {
+		input   string
+		wantErr string
+	}{
+		{`a.`, `invalid path "a.": unexpected EOF`},
+		{`.a`, "unexpected sequence: ."},
+		{`a..b`, "unexpected sequence: ."},
+		{`a[0`, `expected="]" got="EOF"`},
+		{`a[-1]`, "index (-1) must be a non-negative integer"},
+		{`a[x]`, "index x must be a non-negative integer"},
+		{`a"b"`, `unexpected sequence: "b"`},
+		{`a."b`, "string literal not terminated"},
+		{`a.'${x}'`, "is not a key or an index"},
+		{`a + b`, "is not a key or an index"},
+		{`1a`, "unexpected sequence"},
+	}
+	for _, tt := range errTests {
+		_, err := parsePath(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error to contain %q. got=%v", tt.input, tt.wantErr, err)
+		}
+	}
+}
+
+func TestPathJSONPointer(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []pathStep
+	}{
+		{``, []pathStep{}},
+		{`/`, []pathStep{{key: "", pointer: true}}},
+		{`/a/0`, []pathStep{{key: "a", pointer: true}, {key: "0", pointer: true}}},
+		{`/a~1b/m~0n`, []pathStep{{key: "a/b", pointer: true}, {key: "m~n", pointer: true}}},
+	}
+	for _, tt := range tests {
+		got, err := parseJSONPointer(tt.input)
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: wrong steps. want=%v got=%v", tt.input, tt.want, got)
+		}
+		if rendered := pathString(got); rendered != tt.input {
+			t.Errorf("wrong rendered pointer. want=%s got=%s", tt.input, rendered)
+		}
+	}
+	for _, input := range []string{`a/b`, `/a~2`, `/a~`} {
+		if _, err := parseJSONPointer(input); err == nil {
+			t.Errorf("%s: expected error", input)
+		}
+	}
+}
+
+func TestPathFunctions(t *testing.T) {
+	fstore := newBuiltinFunctionStore()
+	in := `{"a": {"b": [10, {"c": null}], "0": "zero"}, "key": "a.b[1].c"}`
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = get_path(@in, @in.key)`, `null`},
+		{`SET @out = get_path(@in, "a.b[0]")`, `10`},
+		{`SET @out = get_path(@in, "")`, in},
+		{`SET @out = get_pointer(@in, "/a/b/0")`, `10`},
+		{`SET @out = get_pointer(@in, "/a/0")`, `"zero"`},
+		{`SET @out = [has_path(@in, @in.key), has_path(@in, "a.b[2]"), has_path(@in, "a.b.c"), has_path(@in, "a.b[1].c.d")]`, `[true, false, false, false]`},
+		{`SET @out = [has_pointer(@in, "/a/b/1/c"), has_pointer(@in, "/a/b/01")]`, `[true, false]`},
+		{`SET @out = set_path(@in.a, "b[1].c", 5)`, `{"b": [10, {"c": 5}], "0": "zero"}`},
+		{`SET @out = set_path(null, "x.y", 1)`, `{"x": {"y": 1}}`},
+		{`SET @out = set_path({"if": {"null": 1}}, "if.null", 2)`, `{"if": {"null": 2}}`},
+		{`SET @out = get_path({"if": [{"true": 1}]}, "if[0].true")`, `1`},
+		{`SET @out = set_pointer([1, 2], "/1", "two")`, `[1, "two"]`},
+		{`SET @out = del_path(@in.a, "b[0]")`, `{"b": [{"c": null}], "0": "zero"}`},
+		{`SET @out = del_pointer(@in.a, "/0")`, `{"b": [10, {"c": null}]}`},
+		{`SET @out = del_path(@in.a, "missing.deeper")`, `{"b": [10, {"c": null}], "0": "zero"}`},
+		{`SET x = set_path(@in, "a.b", 1)
+SET @out = get_path(@in, "a.b[0]")`, `10`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, in, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %v", tt.program, err)
+		}
+	}
+
+	errTests := []struct {
+		program string
+		wantErr string
+	}{
+		{`SET @out = get_path(@in, "a.b[5]")`, "get_path() a.b[5]: index is out of range"},
+		{`SET @out = get_path(@in, "a.b.c")`, "get_path() a.b.c: cannot access a key on a non-map object of type ARRAY"},
+		{`SET @out = get_pointer(@in, "/a/b/x")`, `get_pointer() /a/b/x: "x" is not a valid array index`},
+		{`SET @out = set_path(@in, "a.b[2]", 1)`, "set_path() a.b[2]: index is out of range"},
+		{`SET @out = set_path(@in, "", 1)`, "set_path() cannot set an empty path"},
+		{`SET @out = del_path(@in, "a[0]")`, "del_path() a[0]: cannot index a non-array object of type MAP"},
+		{`SET @out = has_path(@in, "a..b")`, "has_path() invalid path"},
+	}
+	for _, tt := range errTests {
+		program, err := NewProgram(tt.program, fstore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = program.Run([]byte(in))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error to contain %q. got=%v", tt.program, tt.wantErr, err)
+		}
+	}
+}
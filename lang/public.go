@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type Function func(ctx context.Context, args ...*Object) *Object
@@ -22,6 +25,25 @@ func (o *Object) Type() string {
 	return string(o.inner.getType())
 }
 
+// decodes JSON data into an object, the same way program input is decoded
+func ObjectFromJSON(data []byte) (*Object, error) {
+	obj := convertBytesToObject(data)
+	if isObjectErr(obj) {
+		return nil, objectToError(obj)
+	}
+	return &Object{inner: obj}, nil
+}
+
+// encodes the object the same way a program's output is encoded, with map keys in insertion order
+func (o *Object) MarshalJSON() ([]byte, error) {
+	return convertObjectToJSON(o.inner)
+}
+
+// same as MarshalJSON, but encodes map keys in sorted order if sortKeys is set
+func (o *Object) EncodeJSON(sortKeys bool) ([]byte, error) {
+	return convertObjectToJSONOrdered(o.inner, sortKeys)
+}
+
 type PublicType string
 
 // wrappers for public types
@@ -34,13 +56,14 @@ const (
 	ARRAY     PublicType = PublicType(t_array)
 	ARROWFUNC PublicType = PublicType(t_arrow)
 	TIME      PublicType = PublicType(t_time)
+	DURATION  PublicType = PublicType(t_duration)
 	NULL      PublicType = PublicType(t_null)
 	ERROR     PublicType = PublicType(t_error)
 )
 
-var BASIC_WITHOUT_ERROR = []PublicType{INTEGER, FLOAT, BOOLEAN, STRING, MAP, ARRAY, TIME, NULL}
-var BASIC = []PublicType{INTEGER, FLOAT, BOOLEAN, STRING, MAP, ARRAY, TIME, NULL, ERROR}
-var ANY = []PublicType{INTEGER, FLOAT, BOOLEAN, STRING, MAP, ARRAY, TIME, ERROR, NULL, ARROWFUNC}
+var BASIC_WITHOUT_ERROR = []PublicType{INTEGER, FLOAT, BOOLEAN, STRING, MAP, ARRAY, TIME, DURATION, NULL}
+var BASIC = []PublicType{INTEGER, FLOAT, BOOLEAN, STRING, MAP, ARRAY, TIME, DURATION, NULL, ERROR}
+var ANY = []PublicType{INTEGER, FLOAT, BOOLEAN, STRING, MAP, ARRAY, TIME, DURATION, ERROR, NULL, ARROWFUNC}
 
 func (o *Object) AsAny() (interface{}, error) {
 	switch o.Type() {
@@ -60,9 +83,14 @@ func (o *Object) AsAny() (interface{}, error) {
 		return o.AsString()
 	case string(TIME):
 		return o.AsTime()
+	case string(DURATION):
+		return o.AsDuration()
 	case string(ERROR):
 		return o.AsError()
 	default:
+		if custom, ok := o.inner.(*objectCustom); ok {
+			return custom.value, nil
+		}
 		return nil, fmt.Errorf("unable to convert Object: not a convertible type. got=%s", o.Type())
 	}
 }
@@ -83,6 +111,14 @@ func (o *Object) AsTime() (time.Time, error) {
 	return t.value, nil
 }
 
+func (o *Object) AsDuration() (time.Duration, error) {
+	d, ok := o.inner.(*objectDuration)
+	if !ok {
+		return 0, fmt.Errorf("unable to convert object to Duration: underlying structure is not a duration type. got=%s", o.inner.getType())
+	}
+	return d.value, nil
+}
+
 func (o *Object) AsInt() (int64, error) {
 	i, ok := o.inner.(*objectInteger)
 	if !ok {
@@ -173,6 +209,109 @@ func (o *Object) AsArray() ([]interface{}, error) {
 	return ret, nil
 }
 
+// returns the value at the path, such as "user.emails[0]", using the same path syntax as programs.
+// keys are identifiers or double quoted strings, and an empty path returns the object itself.
+// missing keys resolve to null, like they do in programs. invalid paths, indexes that are out of range,
+// and keys or indexes used on the wrong type of object return an error object.
+// the result shares its data with o, so it should be cloned before being changed.
+func (o *Object) Get(path string) *Object {
+	steps, err := parsePath(path)
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	ret, err := getPath(o.inner, steps)
+	if err != nil {
+		return ObjectError(err.Error())
+	}
+	return &Object{inner: ret}
+}
+
+// sets the value at the path, creating any missing maps along the way like SET does.
+// existing array entries can be replaced, but arrays are never grown.
+// the object is changed in place, so clone arguments before changing them, since they may be shared with the program's variables.
+func (o *Object) Set(path string, value *Object) error {
+	steps, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	var toSet object = obj_global_null
+	if value != nil {
+		toSet = value.inner.clone()
+	}
+	return setPath(o.inner, steps, toSet)
+}
+
+// returns a deep copy of the object
+func (o *Object) Clone() *Object {
+	return &Object{inner: o.inner.clone()}
+}
+
+// returns the keys of a map in insertion order, or nil if the object is not a map.
+// maps decoded from JSON keep the order of the data, and maps converted from Go maps have sorted keys.
+func (o *Object) Keys() []string {
+	m, ok := o.inner.(*objectMap)
+	if !ok {
+		return nil
+	}
+	return slices.Clone(m.keys)
+}
+
+// returns the number of entries in a map or array, the number of characters in a string like len() does, or 0 for any other type
+func (o *Object) Len() int {
+	switch v := o.inner.(type) {
+	case *objectMap:
+		return len(v.kvPairs)
+	case *objectArray:
+		return len(v.entries)
+	case *objectString:
+		return utf8.RuneCountInString(v.value)
+	default:
+		return 0
+	}
+}
+
+// returns the array entry at the index, or an error object if the object is not an array or the index is out of range
+func (o *Object) Index(i int) *Object {
+	arr, ok := o.inner.(*objectArray)
+	if !ok {
+		return ObjectError(fmt.Sprintf("cannot index a non-array object of type %s", o.inner.getType()))
+	}
+	if i < 0 || i >= len(arr.entries) {
+		return ObjectError("index is out of range for target array")
+	}
+	return &Object{inner: arr.entries[i]}
+}
+
+// iterates over the keys and values of a map in insertion order, like Keys. yields nothing if the object is not a map.
+func (o *Object) All() iter.Seq2[string, *Object] {
+	return func(yield func(string, *Object) bool) {
+		m, ok := o.inner.(*objectMap)
+		if !ok {
+			return
+		}
+		for _, key := range slices.Clone(m.keys) {
+			if !yield(key, &Object{inner: m.kvPairs[key]}) {
+				return
+			}
+		}
+	}
+}
+
+// iterates over the indexes and entries of an array. yields nothing if the object is not an array.
+func (o *Object) Elements() iter.Seq2[int, *Object] {
+	return func(yield func(int, *Object) bool) {
+		arr, ok := o.inner.(*objectArray)
+		if !ok {
+			return
+		}
+		for idx, entry := range arr.entries {
+			if !yield(idx, &Object{inner: entry}) {
+				return
+			}
+		}
+	}
+}
+
 type ObjectArrowFN struct {
 	inner  *objectArrowFunction
 	errObj *Object
@@ -187,7 +326,23 @@ func (af *ObjectArrowFN) GetError() *Object {
 }
 
 func (af *ObjectArrowFN) Run(input interface{}) interface{} {
-	env := newEnvironment(af.inner.functions)
+	store := af.runObjects(input)
+	if af.HasError() {
+		return nil
+	}
+	ret, err := convertMapStringObjectToNative(store)
+	if err != nil {
+		af.errObj = ObjectError(err.Error())
+		return nil
+	}
+	return ret
+}
+
+// same as Run, but returns the variables of the arrow function as objects, so maps keep their key order.
+// returns nil if the arrow function failed.
+func (af *ObjectArrowFN) runObjects(input interface{}) map[string]object {
+	env := newEnvironment(af.inner.functions, WithContext(af.inner.ctx))
+	env.strict = af.inner.strict
 	startingObj := convertAnyToObject(input, false)
 	if isObjectErr(startingObj) {
 		af.errObj = &Object{inner: startingObj}
@@ -208,12 +363,7 @@ func (af *ObjectArrowFN) Run(input interface{}) interface{} {
 			break
 		}
 	}
-	ret, err := convertMapStringObjectToNative(env.store)
-	if err != nil {
-		af.errObj = ObjectError(err.Error())
-		return nil
-	}
-	return ret
+	return env.store
 }
 
 func (o *Object) AsArrowFunction() (*ObjectArrowFN, error) {
@@ -230,6 +380,16 @@ var ObjectNull = &Object{inner: obj_global_null}
 var ObjectTerminate = &Object{inner: obj_global_term}
 var ObjectTerminateDrop = &Object{inner: obj_global_term_drop}
 
+// same as ObjectTerminate, but reports a reason for halting to the host via Result.Reason
+func ObjectTerminateWithReason(reason string) *Object {
+	return &Object{inner: &objectTerminate{shouldReturnNull: false, reason: reason}}
+}
+
+// same as ObjectTerminateDrop, but reports a reason for dropping to the host via Result.Reason
+func ObjectTerminateDropWithReason(reason string) *Object {
+	return &Object{inner: &objectTerminate{shouldReturnNull: true, reason: reason}}
+}
+
 func ObjectError(msg string) *Object {
 	return &Object{
 		inner: newObjectErrWithoutLC(msg),
@@ -388,8 +548,25 @@ func CastFloat(value interface{}) *Object {
 	return ret
 }
 
+// casts a Go duration to a morph Duration Object so it can be used when defining custom functions
+// input must be one of: time.Duration, or a string in the format accepted by time.ParseDuration, such as "1h30m"
+func CastDuration(value interface{}) *Object {
+	switch v := value.(type) {
+	case time.Duration:
+		return &Object{inner: &objectDuration{value: v}}
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return ObjectError(fmt.Sprintf("unable to cast STRING as DURATION. Invalid STRING %s", v))
+		}
+		return &Object{inner: &objectDuration{value: d}}
+	default:
+		return ObjectError("unable to cast underlying type as DURATION. unsupported input type")
+	}
+}
+
 // casts a Go type to a morph String Object so it can be used when defining custom functions
-// input must be one of: int, int8, int16, int32, int64, float32, float64, string, bool, time
+// input must be one of: int, int8, int16, int32, int64, float32, float64, string, bool, time, duration
 func CastString(value interface{}) *Object {
 	ret := &Object{
 		inner: obj_global_null,
@@ -415,6 +592,8 @@ func CastString(value interface{}) *Object {
 		ret.inner = &objectString{value: fmt.Sprintf("%d", v)}
 	case time.Time:
 		ret.inner = &objectString{value: v.Format(time.RFC3339Nano)}
+	case time.Duration:
+		ret.inner = &objectString{value: v.String()}
 	default:
 		return ObjectError("unable to cast type as STRING. unsupported type")
 	}
@@ -475,6 +654,8 @@ func CastError(value interface{}) *Object {
 	return ObjectError("unable to cast type as error. unsupported type")
 }
 
+// casts any Go value to the matching morph Object. values other than the basic types are converted
+// the way encoding/json would encode them, honoring json struct tags, omitempty, json.Marshaler, and encoding.TextMarshaler.
 func CastAuto(value interface{}) *Object {
 	if value == nil {
 		return ObjectNull
@@ -495,10 +676,16 @@ func CastAuto(value interface{}) *Object {
 		return CastArray(v)
 	case time.Time:
 		return CastTime(v)
+	case time.Duration:
+		return CastDuration(v)
 	case error:
 		return CastError(v)
 	default:
-		return ObjectError("unable to automatically cast type for value")
+		obj := convertAnyToObject(v, false)
+		if isObjectErr(obj) {
+			return ObjectError(fmt.Sprintf("unable to automatically cast type for value: %s", objectToError(obj)))
+		}
+		return &Object{inner: obj}
 	}
 }
 
@@ -3,6 +3,7 @@ package lang
 import (
 	"reflect"
 	"slices"
+	"strings"
 	"testing"
 )
 
@@ -214,3 +215,173 @@ func testPublicObjectAsFloat(t *testing.T, obj *Object, want float64) bool {
 	return true
 
 }
+
+func TestPublicRunObject(t *testing.T) {
+	program, err := NewProgram(`SET in_copy = @in
+SET in_copy.count = @in.count + 1
+SET @out = in_copy`, DefaultFunctionStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, err := ObjectFromJSON([]byte(`{"count": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := program.RunObject(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := res.OutputObject().AsMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(map[string]interface{}{"count": int64(2)}, got) {
+		t.Errorf("wrong output. got=%+v", got)
+	}
+	in, err := input.AsMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(map[string]interface{}{"count": int64(1)}, in) {
+		t.Errorf("expected RunObject to leave its input unchanged. got=%+v", in)
+	}
+}
+
+func TestPublicExpression(t *testing.T) {
+	program, err := NewExpression(`len(@in.name) > 2 && @in.name != "root"`, DefaultFunctionStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := program.Run([]byte(`{"name": "alice"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "true" {
+		t.Errorf("wrong expression output. got=%s", res)
+	}
+
+	compileTests := []struct {
+		expression string
+		wantErr    string
+	}{
+		{"true\nSET @out = 1", "parsing error at 2:1:\n\texpected a single expression. unexpected sequence: SET"},
+		{`@in.a == )`, "parsing error at 1:10:"},
+		{`SET @out = 1`, "parsing error at 1:1:"},
+		{`upper(@in.a)`, ""},
+	}
+	for _, tt := range compileTests {
+		_, err := NewExpression(tt.expression, DefaultFunctionStore())
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error: %s", tt.expression, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: expected error to contain %q. got=%v", tt.expression, tt.wantErr, err)
+		}
+	}
+}
+
+func TestPublicNilFunctionStore(t *testing.T) {
+	program, err := NewProgram(`SET @out = 1`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := program.Run([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "1" {
+		t.Errorf("wrong output. got=%s", res)
+	}
+	program, err = NewProgram(`SET @out = len("abc")`, nil)
+	if err == nil {
+		_, err = program.Run([]byte(`{}`))
+	}
+	if err == nil {
+		t.Error("expected an error when calling a function without a function store")
+	}
+}
+
+func TestPublicPath(t *testing.T) {
+	obj, err := ObjectFromJSON([]byte(`{"user": {"name": "ada", "emails": ["a@x.io", "b@x.io"], "odd key": 1}, "none": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	getTests := []struct {
+		path string
+		want string
+	}{
+		{`user.name`, `"ada"`},
+		{`user.emails[1]`, `"b@x.io"`},
+		{`user."odd key"`, `1`},
+		{`user.missing.deeper`, `null`},
+		{`none.deeper[0]`, `null`},
+	}
+	for _, tt := range getTests {
+		b, err := obj.Get(tt.path).MarshalJSON()
+		if err != nil || string(b) != tt.want {
+			t.Errorf("Get(%s): want=%s got=%s err=%v", tt.path, tt.want, b, err)
+		}
+	}
+	for _, path := range []string{`user.emails[2]`, `user.name.first`, `user[0]`, `user..name`} {
+		if got := obj.Get(path); got.Type() != string(ERROR) {
+			t.Errorf("Get(%s): expected error object. got=%s", path, got.Type())
+		}
+	}
+
+	if err := obj.Set(`user.address.city`, CastString("Boston")); err != nil {
+		t.Fatal(err)
+	}
+	if err := obj.Set(`user.emails[0]`, CastString("c@x.io")); err != nil {
+		t.Fatal(err)
+	}
+	if err := obj.Set(`user.emails[5]`, CastString("d@x.io")); err == nil || !strings.Contains(err.Error(), "user.emails[5]: index is out of range") {
+		t.Errorf("expected out of range error. got=%v", err)
+	}
+	if err := obj.Set(`user.name.first`, CastString("x")); err == nil {
+		t.Error("expected error setting a key on a string")
+	}
+	user := obj.Get("user")
+	if want := []string{"name", "emails", "odd key", "address"}; !slices.Equal(user.Keys(), want) {
+		t.Errorf("wrong keys. want=%v got=%v", want, user.Keys())
+	}
+	if user.Len() != 4 || user.Get("emails").Len() != 2 || obj.Get("none").Len() != 0 {
+		t.Errorf("wrong lengths. got=%d %d", user.Len(), user.Get("emails").Len())
+	}
+	if got := CastString("héllo").Len(); got != 5 {
+		t.Errorf("wrong string length. want=5 got=%d", got)
+	}
+	if email, _ := user.Get("emails").Index(0).AsString(); email != "c@x.io" {
+		t.Errorf("wrong email. got=%s", email)
+	}
+	if user.Get("emails").Index(2).Type() != string(ERROR) {
+		t.Error("expected error object for out of range index")
+	}
+	if city, _ := obj.Get("user.address.city").AsString(); city != "Boston" {
+		t.Errorf("wrong city. got=%s", city)
+	}
+
+	keys := []string{}
+	for key, value := range user.All() {
+		keys = append(keys, key+"="+value.Type())
+	}
+	if want := "name=STRING,emails=ARRAY,odd key=INTEGER,address=MAP"; strings.Join(keys, ",") != want {
+		t.Errorf("wrong entries. want=%s got=%s", want, strings.Join(keys, ","))
+	}
+	emails := []string{}
+	for _, value := range user.Get("emails").Elements() {
+		email, _ := value.AsString()
+		emails = append(emails, email)
+	}
+	if want := []string{"c@x.io", "b@x.io"}; !slices.Equal(emails, want) {
+		t.Errorf("wrong emails. want=%v got=%v", want, emails)
+	}
+
+	clone := user.Clone()
+	clone.Set("name", CastString("grace"))
+	if name, _ := user.Get("name").AsString(); name != "ada" {
+		t.Errorf("expected clone to not share data. got=%s", name)
+	}
+}
@@ -0,0 +1,96 @@
+package lang
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// stores values that persist across program runs, such as counters and deduplication keys, for the state functions.
+// implementations must be safe for concurrent use, since one store may serve many program runs at once.
+// a ttl of zero means the value never expires.
+type StateStore interface {
+	// returns the value stored under key, and whether it was found
+	Get(ctx context.Context, key string) (interface{}, bool, error)
+	// stores the value under key, replacing any existing value
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// atomically adds delta to the integer stored under key and returns the result.
+	// a missing key starts at zero, and the ttl only applies when the key is created.
+	Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+type stateStoreKey struct{}
+
+func stateStoreFromContext(ctx context.Context) (StateStore, bool) {
+	store, ok := ctx.Value(stateStoreKey{}).(StateStore)
+	return store, ok
+}
+
+// an in-memory StateStore. expired values are removed when they are next accessed.
+// ttls are measured with the Clock of the program run, so hosts that set a clock control expiry as well.
+type MemoryStateStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryStateEntry
+}
+
+type memoryStateEntry struct {
+	value   interface{}
+	expires time.Time // zero if the entry never expires
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{
+		entries: make(map[string]memoryStateEntry),
+	}
+}
+
+func (ms *MemoryStateStore) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	entry, ok := ms.getEntry(ctx, key)
+	return entry.value, ok, nil
+}
+
+func (ms *MemoryStateStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.entries[key] = memoryStateEntry{value: value, expires: ms.expiry(ctx, ttl)}
+	return nil
+}
+
+func (ms *MemoryStateStore) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	entry, ok := ms.getEntry(ctx, key)
+	if !ok {
+		entry = memoryStateEntry{value: int64(0), expires: ms.expiry(ctx, ttl)}
+	}
+	current, ok := entry.value.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unable to increment state key %q: value is not an integer", key)
+	}
+	entry.value = current + delta
+	ms.entries[key] = entry
+	return current + delta, nil
+}
+
+// returns the entry for key, removing it if it has expired. callers must hold the lock.
+func (ms *MemoryStateStore) getEntry(ctx context.Context, key string) (memoryStateEntry, bool) {
+	entry, ok := ms.entries[key]
+	if !ok {
+		return memoryStateEntry{}, false
+	}
+	if !entry.expires.IsZero() && !ClockFromContext(ctx).Now().Before(entry.expires) {
+		delete(ms.entries, key)
+		return memoryStateEntry{}, false
+	}
+	return entry, true
+}
+
+func (ms *MemoryStateStore) expiry(ctx context.Context, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return ClockFromContext(ctx).Now().Add(ttl)
+}
@@ -0,0 +1,108 @@
+package lang
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStateMemoryTTL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStateStore()
+	ctx := context.WithValue(context.Background(), clockKey{}, FixedClock{Time: now})
+
+	if err := store.Set(ctx, "short", "value", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "forever", "value", 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []int64{1, 2, 3} {
+		got, err := store.Incr(ctx, "counter", 1, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("wrong counter value. want=%d got=%d", want, got)
+		}
+	}
+
+	ctx = context.WithValue(context.Background(), clockKey{}, FixedClock{Time: now.Add(time.Minute)})
+	if _, ok, _ := store.Get(ctx, "short"); ok {
+		t.Error("expected value to expire after its ttl")
+	}
+	if _, ok, _ := store.Get(ctx, "forever"); !ok {
+		t.Error("expected value without a ttl to never expire")
+	}
+	got, err := store.Incr(ctx, "counter", 1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 1 {
+		t.Errorf("expected expired counter to restart. want=1 got=%d", got)
+	}
+
+	if _, err := store.Incr(ctx, "forever", 1, 0); err == nil {
+		t.Error("expected an error incrementing a non-integer value")
+	}
+}
+
+func TestStateRunClock(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStateStore()
+	program, err := NewProgram(`SET @out = state.seen(@in, 60)`, newBuiltinFunctionStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{now, `false`},
+		{now.Add(59 * time.Second), `true`},
+		{now.Add(time.Minute), `false`},
+	}
+	for _, tt := range tests {
+		res, err := program.RunResult([]byte(`"key"`), WithStateStore(store), WithClock(FixedClock{Time: tt.at}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(res.Output) != tt.want {
+			t.Errorf("at %s: expected state ttls to follow the run's clock. want=%s got=%s", tt.at, tt.want, res.Output)
+		}
+	}
+}
+
+func TestStateTTLArg(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStateStore()
+	program, err := NewProgram(`SET @out = [state.incr("named", ttl=60), state.incr("named")]`, newBuiltinFunctionStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		at   time.Time
+		want string
+	}{
+		{now, `[1,2]`},
+		{now.Add(time.Minute), `[1,2]`},
+	} {
+		res, err := program.RunResult([]byte(`null`), WithStateStore(store), WithClock(FixedClock{Time: tt.at}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(res.Output) != tt.want {
+			t.Errorf("at %s: expected a named ttl argument. want=%s got=%s", tt.at, tt.want, res.Output)
+		}
+	}
+
+	program, err = NewProgram(`SET @out = state.incr("a", 1, 2)`, newBuiltinFunctionStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = program.RunResult([]byte(`null`), WithStateStore(store))
+	if err == nil || !strings.Contains(err.Error(), "too many arguments") {
+		t.Errorf("expected an error passing more than one ttl. got=%v", err)
+	}
+}
@@ -58,12 +58,14 @@ const (
 	tok_gteq      tokenType = ">="
 
 	//keywords
-	tok_if    tokenType = "IF"
-	tok_set   tokenType = "SET"
-	tok_del   tokenType = "DEL"
-	tok_true  tokenType = "TRUE"
-	tok_false tokenType = "FALSE"
-	tok_null  tokenType = "NULL"
+	tok_if      tokenType = "IF"
+	tok_set     tokenType = "SET"
+	tok_del     tokenType = "DEL"
+	tok_param   tokenType = "PARAM"
+	tok_include tokenType = "INCLUDE"
+	tok_true    tokenType = "TRUE"
+	tok_false   tokenType = "FALSE"
+	tok_null    tokenType = "NULL"
 
 	tok_eof     tokenType = "EOF"
 	tok_illegal tokenType = "ILLEGAL"
@@ -78,6 +80,16 @@ var keywordMap = map[string]tokenType{
 	"null":  tok_null,
 }
 
+// keywords that are only recognized as the first token of a statement, so that they can still be used as names elsewhere.
+// each maps to the token type that must follow the keyword.
+var statementKeywordMap = map[string]struct {
+	tokenType tokenType
+	next      tokenType
+}{
+	"param":   {tok_param, tok_ident},
+	"include": {tok_include, tok_string},
+}
+
 func lookupTokenKeyword(ident string) tokenType {
 	ident = strings.ToLower(ident)
 	if ret, ok := keywordMap[ident]; ok {
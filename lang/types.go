@@ -0,0 +1,164 @@
+package lang
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// returns the type of an array whose entries each match one of the types, such as ARRAY<STRING>
+func ArrayOf(types ...PublicType) PublicType {
+	return PublicType(fmt.Sprintf("%s<%s>", ARRAY, typeListString(types)))
+}
+
+// returns the type of a map whose values each match one of the types, such as MAP<INTEGER>
+func MapOf(types ...PublicType) PublicType {
+	return PublicType(fmt.Sprintf("%s<%s>", MAP, typeListString(types)))
+}
+
+// joins the types with "|", collapsing the full ANY and BASIC lists to their names
+func typeListString(types []PublicType) string {
+	if containsAllTypes(types, ANY) {
+		return "ANY"
+	}
+	if containsAllTypes(types, BASIC) {
+		return "BASIC"
+	}
+	strs := []string{}
+	for _, t := range types {
+		strs = append(strs, string(t))
+	}
+	return strings.Join(strs, "|")
+}
+
+// reports whether types includes every type in group
+func containsAllTypes(types []PublicType, group []PublicType) bool {
+	for _, t := range group {
+		if !slices.Contains(types, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// splits a parameterized type like ARRAY<STRING|INTEGER> into its container type and element types
+func (t PublicType) parameterized() (PublicType, []PublicType, bool) {
+	str := string(t)
+	open := strings.Index(str, "<")
+	if open < 0 || !strings.HasSuffix(str, ">") {
+		return t, nil, false
+	}
+	container := PublicType(str[:open])
+	inner := str[open+1 : len(str)-1]
+	elems := []PublicType{}
+	depth := 0
+	start := 0
+	for idx, r := range inner {
+		switch r {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case '|':
+			if depth == 0 {
+				elems = append(elems, PublicType(inner[start:idx]))
+				start = idx + 1
+			}
+		}
+	}
+	elems = append(elems, PublicType(inner[start:]))
+	switch {
+	case len(elems) == 1 && elems[0] == "ANY":
+		elems = ANY
+	case len(elems) == 1 && elems[0] == "BASIC":
+		elems = BASIC
+	}
+	return container, elems, true
+}
+
+func hasParameterizedType(types []PublicType) bool {
+	return slices.ContainsFunc(types, func(t PublicType) bool {
+		_, _, ok := t.parameterized()
+		return ok
+	})
+}
+
+// reports whether some value could match both types
+func typesOverlap(a, b PublicType) bool {
+	if a == b {
+		return true
+	}
+	containerA, elemsA, okA := a.parameterized()
+	containerB, elemsB, okB := b.parameterized()
+	if containerA != containerB {
+		return false
+	}
+	if !okA || !okB {
+		return true // an unparameterized container accepts every parameterized one
+	}
+	// empty containers match any element types, but are dispatched to the first overload rather than treated as ambiguous
+	return slices.ContainsFunc(elemsA, func(t PublicType) bool { return typeInList(t, elemsB) })
+}
+
+func typeInList(t PublicType, types []PublicType) bool {
+	return slices.ContainsFunc(types, func(other PublicType) bool { return typesOverlap(t, other) })
+}
+
+// describes the first value that did not match the wanted types
+type typeMismatch struct {
+	path string // the path of the value from the argument, such as [2]["name"], or empty for the argument itself
+	got  PublicType
+}
+
+// checks the value against each of the types. parameterized container types also check every entry.
+// values of custom types match their own type name, and any list that includes every type in ANY.
+// if nothing matches, the mismatch that reached furthest into the value is returned.
+func matchTypes(types []PublicType, value object) (typeMismatch, bool) {
+	if _, ok := value.(*objectCustom); ok && containsAllTypes(types, ANY) {
+		return typeMismatch{}, true
+	}
+	best := typeMismatch{got: PublicType(value.getType())}
+	for _, t := range types {
+		mismatch, ok := matchType(t, value)
+		if ok {
+			return typeMismatch{}, true
+		}
+		if len(mismatch.path) > len(best.path) {
+			best = mismatch
+		}
+	}
+	return best, false
+}
+
+func matchType(t PublicType, value object) (typeMismatch, bool) {
+	valueType := PublicType(value.getType())
+	container, elems, ok := t.parameterized()
+	if !ok || container != valueType {
+		return typeMismatch{got: valueType}, t == valueType
+	}
+	switch v := value.(type) {
+	case *objectArray:
+		for idx, entry := range v.entries {
+			if mismatch, ok := matchTypes(elems, entry); !ok {
+				mismatch.path = fmt.Sprintf("[%d]%s", idx, mismatch.path)
+				return mismatch, false
+			}
+		}
+	case *objectMap:
+		for _, key := range v.keys {
+			if mismatch, ok := matchTypes(elems, v.kvPairs[key]); !ok {
+				mismatch.path = fmt.Sprintf("[%q]%s", key, mismatch.path)
+				return mismatch, false
+			}
+		}
+	}
+	return typeMismatch{}, true
+}
+
+// describes where the mismatch occurred, for error messages
+func (tm typeMismatch) describe(name string) string {
+	if len(tm.path) == 0 {
+		return string(tm.got)
+	}
+	return fmt.Sprintf("%s at %s%s", tm.got, name, tm.path)
+}
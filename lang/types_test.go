@@ -0,0 +1,101 @@
+package lang
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTypesParameterized(t *testing.T) {
+	tests := []struct {
+		input         PublicType
+		wantString    string
+		wantContainer PublicType
+		wantElems     []PublicType
+	}{
+		{ArrayOf(STRING), "ARRAY<STRING>", ARRAY, []PublicType{STRING}},
+		{MapOf(INTEGER, FLOAT), "MAP<INTEGER|FLOAT>", MAP, []PublicType{INTEGER, FLOAT}},
+		{ArrayOf(ANY...), "ARRAY<ANY>", ARRAY, ANY},
+		{ArrayOf(MapOf(STRING), NULL), "ARRAY<MAP<STRING>|NULL>", ARRAY, []PublicType{MapOf(STRING), NULL}},
+	}
+	for _, tt := range tests {
+		if string(tt.input) != tt.wantString {
+			t.Errorf("wrong type string. want=%s got=%s", tt.wantString, tt.input)
+		}
+		container, elems, ok := tt.input.parameterized()
+		if !ok || container != tt.wantContainer || !slices.Equal(elems, tt.wantElems) {
+			t.Errorf("%s: wrong parameters. got=%s %v %t", tt.input, container, elems, ok)
+		}
+	}
+	if _, _, ok := ARRAY.parameterized(); ok {
+		t.Error("expected ARRAY to not be parameterized")
+	}
+}
+
+func TestTypesFunctionArgs(t *testing.T) {
+	fstore := NewFunctionStore()
+	fstore.Register(NewFunctionEntry("total", "adds up the counts of each group", func(ctx context.Context, args ...*Object) *Object {
+		groups, _ := args[0].AsMap()
+		total := int64(0)
+		for _, counts := range groups {
+			for _, count := range counts.([]interface{}) {
+				total += count.(int64)
+			}
+		}
+		return CastInt(total)
+	}, WithArgs(
+		NewFunctionArg("groups", "", MapOf(ArrayOf(INTEGER))),
+	), WithReturn(
+		NewFunctionReturn("", INTEGER),
+	)))
+	fe, _ := fstore.get("std", "total")
+	if want := "std.total(groups:MAP<ARRAY<INTEGER>>) INTEGER"; fe.Signature() != want {
+		t.Errorf("wrong signature. want=%s got=%s", want, fe.Signature())
+	}
+
+	if err := testBuiltinFunctionEntry(fstore, `{"a": [1, 2], "b": [], "c": [3]}`, `SET @out = total(@in)`, `6`); err != nil {
+		t.Error(err)
+	}
+	errTests := []struct {
+		in      string
+		wantErr string
+	}{
+		{`{"a": [1, 2], "b": [3, "4"]}`, `want=MAP<ARRAY<INTEGER>>. got=STRING at groups["b"][1]`},
+		{`{"a": 1}`, `want=MAP<ARRAY<INTEGER>>. got=INTEGER at groups["a"]`},
+		{`[1]`, `want=MAP<ARRAY<INTEGER>>. got=ARRAY`},
+	}
+	program, err := NewProgram(`SET @out = total(@in)`, fstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range errTests {
+		_, err := program.Run([]byte(tt.in))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error to contain %q. got=%v", tt.in, tt.wantErr, err)
+		}
+	}
+}
+
+func TestTypesOverloads(t *testing.T) {
+	fstore := NewFunctionStore()
+	fstore.Register(testOverloadEntry("describe", "strings", NewFunctionArg("value", "", ArrayOf(STRING))))
+	if err := fstore.RegisterOverload(testOverloadEntry("describe", "numbers", NewFunctionArg("value", "", ArrayOf(INTEGER, FLOAT)))); err != nil {
+		t.Fatal(err)
+	}
+	if err := fstore.RegisterOverload(testOverloadEntry("describe", "any", NewFunctionArg("value", "", ARRAY))); err == nil || !strings.Contains(err.Error(), "ambiguous overload") {
+		t.Errorf("expected ARRAY to be ambiguous with ARRAY<STRING>. got=%v", err)
+	}
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`SET @out = describe(["a"])`, `"strings"`},
+		{`SET @out = describe([1, 2.5])`, `"numbers"`},
+	}
+	for _, tt := range tests {
+		if err := testBuiltinFunctionEntry(fstore, `null`, tt.program, tt.want); err != nil {
+			t.Errorf("%s: %s", tt.program, err)
+		}
+	}
+}
@@ -1,12 +1,24 @@
 package morph
 
 import (
+	"context"
+	"maps"
+
 	"github.com/hudsn/morph/lang"
 )
 
 type morph struct {
 	program       *lang.Program
 	functionStore *lang.FunctionStore
+	vars          map[string]interface{}
+	lookups       lang.LookupProvider
+	state         lang.StateStore
+	modules       lang.ModuleResolver
+	policy        lang.FunctionPolicy
+	clock         lang.Clock
+	deterministic bool
+	strict        bool
+	sortKeys      bool
 }
 
 type Opt func(*morph)
@@ -17,24 +29,175 @@ func WithFunctionStore(fstore *lang.FunctionStore) func(*morph) {
 	}
 }
 
+// exposes host-provided values to every run of the program as the read-only @vars variable.
+// parameters declared with PARAM that have no default must be present, or New returns an error.
+func WithVars(vars map[string]interface{}) func(*morph) {
+	return func(m *morph) {
+		m.vars = vars
+	}
+}
+
+// sets the provider of reference data for the lookup functions, taking precedence over a provider set on the function store
+func WithLookupProvider(provider lang.LookupProvider) func(*morph) {
+	return func(m *morph) {
+		m.lookups = provider
+	}
+}
+
+// sets the store used by the state functions, which persist values such as counters and deduplication keys across runs
+func WithStateStore(store lang.StateStore) func(*morph) {
+	return func(m *morph) {
+		m.state = store
+	}
+}
+
+// sets the resolver used to load the shared modules named by INCLUDE statements
+func WithModuleResolver(resolver lang.ModuleResolver) func(*morph) {
+	return func(m *morph) {
+		m.modules = resolver
+	}
+}
+
+// only allows the program to call the listed functions. see lang.FunctionPolicy for the accepted formats.
+// programs that call any other function fail to compile.
+func WithAllowedFunctions(names ...string) func(*morph) {
+	return func(m *morph) {
+		m.policy.AllowedFunctions = append([]string{}, names...)
+	}
+}
+
+// rejects programs that call functions with any of the capabilities, such as lang.FUNCTION_CAPABILITY_NONDETERMINISTIC
+func WithDeniedCapabilities(capabilities ...lang.FunctionCapability) func(*morph) {
+	return func(m *morph) {
+		m.policy.DeniedCapabilities = append(m.policy.DeniedCapabilities, capabilities...)
+	}
+}
+
+// sets the clock used by now() and other time-relative functions, instead of the system clock
+func WithClock(clock lang.Clock) func(*morph) {
+	return func(m *morph) {
+		m.clock = clock
+	}
+}
+
+// makes every run reproducible: the time is read once at the start of each run, so every call to now() in a run returns the same value,
+// and programs that call other nondeterministic functions, such as the state functions, fail to compile.
+// combine with WithClock to control the time itself.
+func WithDeterministic() func(*morph) {
+	return func(m *morph) {
+		m.deterministic = true
+	}
+}
+
+// makes reading a missing path or an undefined variable an error with its line and column, instead of NULL.
+// programs can check whether a path exists with has(), such as has(@in.a.b).
+func WithStrict() func(*morph) {
+	return func(m *morph) {
+		m.strict = true
+	}
+}
+
+// writes the keys of maps in the output in sorted order. by default, keys keep the order of the input,
+// and keys added by the program follow in the order they were first set.
+// it applies to every output of the program, and a pipeline sorts the keys of its final output if its last stage uses it.
+func WithSortedKeys() func(*morph) {
+	return func(m *morph) {
+		m.sortKeys = true
+	}
+}
+
 func New(input string, opts ...Opt) (*morph, error) {
 	m := &morph{
 		functionStore: lang.DefaultFunctionStore(),
+		vars:          map[string]interface{}{},
 	}
 
 	for _, fn := range opts {
 		fn(m)
 	}
 
-	program, err := lang.NewProgram(input, m.functionStore)
+	if m.deterministic {
+		m.policy.DeniedCapabilities = append(m.policy.DeniedCapabilities, lang.FUNCTION_CAPABILITY_NONDETERMINISTIC)
+		m.policy.FixedClock = true
+	}
+
+	program, err := m.compile(input)
 	if err != nil {
 		return nil, err
 	}
+	if err := program.CheckVars(m.vars); err != nil {
+		return nil, err
+	}
 	m.program = program
 
 	return m, nil
 }
 
+// compiles input with the function store, module resolver, function policy, and strictness of m
+func (m *morph) compile(input string) (*lang.Program, error) {
+	return lang.NewProgram(input, m.functionStore, lang.WithModuleResolver(m.modules), lang.WithFunctionPolicy(m.policy), lang.WithStrict(m.strict))
+}
+
+// compiles a single expression, such as a pipeline stage condition, with the same options as compile
+func (m *morph) compileExpression(input string) (*lang.Program, error) {
+	return lang.NewExpression(input, m.functionStore, lang.WithModuleResolver(m.modules), lang.WithFunctionPolicy(m.policy), lang.WithStrict(m.strict))
+}
+
 func (m *morph) Exec(inputData []byte) ([]byte, error) {
-	return m.program.Run(inputData)
+	res, err := m.exec(context.Background(), inputData, m.vars)
+	if err != nil {
+		return nil, err
+	}
+	return res.Output, nil
+}
+
+// same as Exec, but merges vars over the values set with WithVars for this run only
+func (m *morph) ExecWithVars(inputData []byte, vars map[string]interface{}) ([]byte, error) {
+	merged := map[string]interface{}{}
+	maps.Copy(merged, m.vars)
+	maps.Copy(merged, vars)
+	if err := m.program.CheckVars(merged); err != nil {
+		return nil, err
+	}
+	res, err := m.exec(context.Background(), inputData, merged)
+	if err != nil {
+		return nil, err
+	}
+	return res.Output, nil
+}
+
+// runs the program and returns every output record: the values passed to emit_record(), followed by @out if it was set.
+// a run halted by drop() returns no records.
+func (m *morph) ExecAll(inputData []byte) ([][]byte, error) {
+	res, err := m.exec(context.Background(), inputData, m.vars)
+	if err != nil {
+		return nil, err
+	}
+	return res.All(), nil
+}
+
+// runs the program and reports how the run finished, including whether it was halted early by drop() or emit(), and the reason given
+func (m *morph) ExecResult(inputData []byte) (*lang.Result, error) {
+	return m.exec(context.Background(), inputData, m.vars)
+}
+
+func (m *morph) exec(ctx context.Context, inputData []byte, vars map[string]interface{}) (*lang.Result, error) {
+	return m.program.RunResult(inputData, lang.WithContext(ctx), lang.WithVars(vars), lang.WithLookupProvider(m.lookups), lang.WithStateStore(m.state), lang.WithClock(m.runClock()), lang.WithSortedKeys(m.sortKeys))
+}
+
+// same as exec, but runs against an already decoded input and leaves the output unencoded
+func (m *morph) execObject(ctx context.Context, input *lang.Object) (*lang.Result, error) {
+	return m.program.RunObject(input, lang.WithContext(ctx), lang.WithVars(m.vars), lang.WithLookupProvider(m.lookups), lang.WithStateStore(m.state), lang.WithClock(m.runClock()), lang.WithSortedKeys(m.sortKeys))
+}
+
+// returns the clock for a single run. in deterministic mode the time is frozen for the whole run.
+func (m *morph) runClock() lang.Clock {
+	if !m.deterministic {
+		return m.clock
+	}
+	clock := m.clock
+	if clock == nil {
+		clock = lang.SystemClock{}
+	}
+	return lang.FixedClock{Time: clock.Now()}
 }
@@ -1,12 +1,17 @@
 package morph
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/hudsn/morph/lang"
 )
@@ -657,7 +662,1021 @@ func TestMorphSetByValue(t *testing.T) {
 	checkTestMorphCase(t, test, lang.NewFunctionStore())
 }
 
+func TestMorphStream(t *testing.T) {
+	m, err := New(`
+	IF @in.skip == true :: { drop() }
+	SET @out.id = @in.id * 10
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `{"id": 1}
+{"id": 2, "skip": true}
+
+{"id": 3}
+`
+	var out bytes.Buffer
+	err = m.ExecStream(context.Background(), strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"id\":10}\n{\"id\":30}\n"
+	if out.String() != want {
+		t.Errorf("wrong stream output. want=%q got=%q", want, out.String())
+	}
+}
+
+func TestMorphStreamErrorPolicy(t *testing.T) {
+	m, err := New(`SET @out = @in.num + 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "{\"num\": 1}\n{\"num\": \"a\"}\nnot json\n{\"num\": 2}"
+
+	var out bytes.Buffer
+	err = m.ExecStream(context.Background(), strings.NewReader(input), &out)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Fatalf("expected abort policy to return an error for line 2. got=%v", err)
+	}
+	if out.String() != "2\n" {
+		t.Errorf("wrong output before abort. want=%q got=%q", "2\n", out.String())
+	}
+
+	out.Reset()
+	err = m.ExecStream(context.Background(), strings.NewReader(input), &out, WithErrorPolicy(ERROR_POLICY_SKIP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "2\n3\n" {
+		t.Errorf("wrong output for skip policy. want=%q got=%q", "2\n3\n", out.String())
+	}
+
+	out.Reset()
+	var errOut bytes.Buffer
+	err = m.ExecStream(context.Background(), strings.NewReader(input), &out, WithErrorWriter(&errOut))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "2\n3\n" {
+		t.Errorf("wrong output for report policy. want=%q got=%q", "2\n3\n", out.String())
+	}
+	errLines := strings.Split(strings.TrimSpace(errOut.String()), "\n")
+	if len(errLines) != 2 {
+		t.Fatalf("expected 2 reported errors. got=%d: %s", len(errLines), errOut.String())
+	}
+	for idx, wantLine := range []int{2, 3} {
+		var reported struct {
+			Line  int    `json:"line"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(errLines[idx]), &reported); err != nil {
+			t.Fatal(err)
+		}
+		if reported.Line != wantLine || reported.Error == "" {
+			t.Errorf("wrong reported error. want line=%d got=%+v", wantLine, reported)
+		}
+	}
+}
+
+func TestMorphStreamParallelOrder(t *testing.T) {
+	m, err := New(`SET @out = @in`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input, want strings.Builder
+	for i := range 500 {
+		fmt.Fprintf(&input, "%d\n", i)
+		fmt.Fprintf(&want, "%d\n", i)
+	}
+	var out bytes.Buffer
+	err = m.ExecStream(context.Background(), strings.NewReader(input.String()), &out, WithWorkers(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want.String() {
+		t.Errorf("parallel stream output is out of order or incomplete")
+	}
+}
+
+func TestMorphStreamParallelCancel(t *testing.T) {
+	m, err := New(`SET @out = map(@in, x ~> { SET return = x.value * 2 })`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input strings.Builder
+	for range 200 {
+		input.WriteString("[1, 2, 3, 4, 5, 6, 7, 8, 9, 10]\n")
+	}
+	// cancel at a different point of each run, so that some cancellations land while the reader is queueing a record
+	for i := range 50 {
+		ctx, cancel := context.WithCancel(context.Background())
+		timer := time.AfterFunc(time.Duration(i*20)*time.Microsecond, cancel)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- m.ExecStream(ctx, strings.NewReader(input.String()), io.Discard, WithWorkers(2))
+		}()
+		select {
+		case err := <-errs:
+			if err != nil && !errors.Is(err, context.Canceled) {
+				t.Errorf("run %d: expected nil or a cancellation error. got=%v", i, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("run %d: parallel stream did not return after its context was cancelled", i)
+		}
+		timer.Stop()
+		cancel()
+	}
+}
+
+func TestMorphExecAll(t *testing.T) {
+	tests := []struct {
+		description string
+		program     string
+		srcJSON     string
+		want        []string
+	}{
+		{
+			description: "records are returned in emit order, followed by @out",
+			srcJSON:     `{"events": [{"id": 1}, {"id": 2}]}`,
+			program: `
+			map(@in.events, e ~> {
+				SET record = e.value
+				SET record.index = e.index
+				emit_record(record)
+			})
+			SET @out.count = len(@in.events)
+			`,
+			want: []string{`{"id":1,"index":0}`, `{"id":2,"index":1}`, `{"count":2}`},
+		},
+		{
+			description: "records are copies of the value at the time they were emitted",
+			srcJSON:     `{}`,
+			program: `
+			SET x = {"n": 1}
+			emit_record(x)
+			SET x.n = 2
+			emit_record(x)
+			`,
+			want: []string{`{"n":1}`, `{"n":2}`},
+		},
+		{
+			description: "emit keeps records emitted so far",
+			srcJSON:     `{}`,
+			program: `
+			emit_record(1)
+			emit()
+			emit_record(2)
+			`,
+			want: []string{`1`},
+		},
+		{
+			description: "drop discards all records",
+			srcJSON:     `{}`,
+			program: `
+			emit_record(1)
+			SET @out = 2
+			drop()
+			`,
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		m, err := New(tt.program)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := m.ExecAll([]byte(tt.srcJSON))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotStrings := []string{}
+		for _, record := range got {
+			gotStrings = append(gotStrings, string(record))
+		}
+		if !reflect.DeepEqual(tt.want, gotStrings) {
+			t.Errorf("%s: wrong records. want=%v got=%v", tt.description, tt.want, gotStrings)
+		}
+	}
+}
+
+func TestMorphStreamFanOut(t *testing.T) {
+	m, err := New(`map(@in, e ~> { emit_record(e.value) })`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = m.ExecStream(context.Background(), strings.NewReader("[1, 2]\n[3]\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1\n2\n3\n" {
+		t.Errorf("wrong stream output. want=%q got=%q", "1\n2\n3\n", out.String())
+	}
+}
+
+func TestMorphExecResult(t *testing.T) {
+	tests := []struct {
+		description      string
+		program          string
+		wantOutput       string
+		wantDropped      bool
+		wantEmittedEarly bool
+		wantReason       string
+	}{
+		{
+			description: "normal run",
+			program:     `SET @out = null`,
+			wantOutput:  `null`,
+		},
+		{
+			description: "drop without a reason",
+			program:     `SET @out = 1 drop()`,
+			wantOutput:  `null`,
+			wantDropped: true,
+		},
+		{
+			description: "drop with a reason",
+			program:     `SET @out = 1 drop("not needed")`,
+			wantOutput:  `null`,
+			wantDropped: true,
+			wantReason:  "not needed",
+		},
+		{
+			description:      "emit with a reason",
+			program:          `SET @out = 1 emit("done early") SET @out = 2`,
+			wantOutput:       `1`,
+			wantEmittedEarly: true,
+			wantReason:       "done early",
+		},
+		{
+			description: "drop inside an arrow function does not drop the run",
+			program:     `SET @out = map([1], e ~> { drop("inner") })`,
+			wantOutput:  `[1]`,
+		},
+	}
+	for _, tt := range tests {
+		m, err := New(tt.program)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := m.ExecResult([]byte(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(res.Output) != tt.wantOutput {
+			t.Errorf("%s: wrong output. want=%s got=%s", tt.description, tt.wantOutput, string(res.Output))
+		}
+		if res.Dropped != tt.wantDropped {
+			t.Errorf("%s: wrong Dropped value. want=%t got=%t", tt.description, tt.wantDropped, res.Dropped)
+		}
+		if res.EmittedEarly != tt.wantEmittedEarly {
+			t.Errorf("%s: wrong EmittedEarly value. want=%t got=%t", tt.description, tt.wantEmittedEarly, res.EmittedEarly)
+		}
+		if res.Reason != tt.wantReason {
+			t.Errorf("%s: wrong Reason. want=%q got=%q", tt.description, tt.wantReason, res.Reason)
+		}
+	}
+}
+
+func TestMorphDropEmitTooManyArgs(t *testing.T) {
+	for _, program := range []string{`drop("a", "b")`, `emit("a", "b")`} {
+		m, err := New(program)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = m.Exec([]byte(`{}`))
+		if err == nil || !strings.Contains(err.Error(), "too many arguments supplied") {
+			t.Errorf("expected too many arguments error for %s. got=%v", program, err)
+		}
+	}
+}
+
+func TestMorphVars(t *testing.T) {
+	program := `
+	PARAM threshold = 10
+	PARAM region
+	SET @out.region = @vars.region
+	SET @out.alert = @in.count > @vars.threshold
+	SET @out.flag = @vars.flags.beta
+	`
+	m, err := New(program, WithVars(map[string]interface{}{
+		"region": "us-east",
+		"flags":  map[string]interface{}{"beta": true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Exec([]byte(`{"count": 11}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "vars with param default", `{"region": "us-east", "alert": true, "flag": true}`, got)
+
+	got, err = m.ExecWithVars([]byte(`{"count": 11}`), map[string]interface{}{"threshold": 20, "region": "eu-west"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "per-call vars override instance vars", `{"region": "eu-west", "alert": false, "flag": true}`, got)
+}
+
+func TestMorphVarsMissingParam(t *testing.T) {
+	_, err := New(`
+	PARAM threshold = 10
+	PARAM region
+	PARAM tenant
+	SET @out = @vars.region`, WithVars(map[string]interface{}{"tenant": "abc"}))
+	if err == nil || !strings.Contains(err.Error(), "missing required parameters: region") {
+		t.Errorf("expected missing parameter error at compile time. got=%v", err)
+	}
+}
+
+func TestMorphVarsErr(t *testing.T) {
+	tests := []testMorphError{
+		{
+			description:     "check that @vars cannot be set",
+			program:         `SET @vars.x = 1`,
+			wantErrContains: []string{"parsing error at 1:5:", "SET statement cannot modify @vars data"},
+		},
+		{
+			description:     "check that @vars cannot be deleted",
+			program:         `DEL @vars`,
+			wantErrContains: []string{"parsing error at 1:5:", "DEL statement cannot delete @vars data"},
+		},
+		{
+			description:     "check that PARAM is only allowed at the top level",
+			program:         `IF true :: { PARAM x = 1 }`,
+			wantErrContains: []string{"parsing error at 1:14:", "PARAM statements are only allowed at the top level"},
+		},
+	}
+	for _, tt := range tests {
+		if err := checkTestMorphParseError(t, tt, lang.NewFunctionStore()); err != nil {
+			t.Error(err.Error())
+		}
+	}
+}
+
+func TestMorphLookups(t *testing.T) {
+	provider := &testMorphCountingLookups{inner: lang.NewMemoryLookupProvider()}
+	provider.inner.SetTable("teams", map[string]interface{}{"alice": "platform", "bob": "security"})
+
+	program := `
+	SET @out.first = lookup("teams", @in.user)
+	SET @out.second = lookup_default("teams", @in.user, "none")
+	SET @out.many = lookup_many("teams", ["bob", @in.user, "carol"])
+	`
+	m, err := New(program, WithLookupProvider(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Exec([]byte(`{"user": "alice"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "lookup builtins", `{"first": "platform", "second": "platform", "many": ["security", "platform", null]}`, got)
+	if provider.calls != 3 {
+		t.Errorf("expected lookups to be cached within a run. want=3 provider calls, got=%d", provider.calls)
+	}
+
+	_, err = m.Exec([]byte(`{"user": "alice"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 6 {
+		t.Errorf("expected the lookup cache to be reset between runs. want=6 provider calls, got=%d", provider.calls)
+	}
+
+	fstore := lang.DefaultFunctionStore()
+	storeProvider := lang.NewMemoryLookupProvider()
+	storeProvider.SetTable("teams", map[string]interface{}{"alice": "from-store"})
+	fstore.SetLookupProvider(storeProvider)
+	m, err = New(`SET @out = lookup("teams", "alice")`, WithFunctionStore(fstore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = m.Exec([]byte(`null`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "function store lookup provider", `"from-store"`, got)
+}
+
+func TestMorphLookupsErr(t *testing.T) {
+	m, err := New(`SET @out = lookup("teams", "alice")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Exec([]byte(`null`))
+	if err == nil || !strings.Contains(err.Error(), "requires a lookup provider") {
+		t.Errorf("expected missing lookup provider error. got=%v", err)
+	}
+
+	m, err = New(`SET @out = lookup("nope", "alice")`, WithLookupProvider(lang.NewMemoryLookupProvider()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Exec([]byte(`null`))
+	if err == nil || !strings.Contains(err.Error(), `lookup table "nope" does not exist`) {
+		t.Errorf("expected missing table error. got=%v", err)
+	}
+}
+
+func TestMorphState(t *testing.T) {
+	program := `
+	IF state.seen(@in.id) :: { drop("duplicate") }
+	SET @out.id = @in.id
+	SET @out.count = state.incr("total")
+	`
+	m, err := New(program, WithStateStore(lang.NewMemoryStateStore()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `{"id": 1}
+{"id": 2}
+{"id": 1}
+{"id": 3}
+`
+	out := &bytes.Buffer{}
+	if err := m.ExecStream(context.Background(), strings.NewReader(input), out); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":1,"count":1}
+{"id":2,"count":2}
+{"id":3,"count":3}
+`
+	if out.String() != want {
+		t.Errorf("wrong stream output with state.\nwant:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestMorphStateErr(t *testing.T) {
+	m, err := New(`SET @out = state.incr("total")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Exec([]byte(`null`))
+	if err == nil || !strings.Contains(err.Error(), "requires a state store") {
+		t.Errorf("expected missing state store error. got=%v", err)
+	}
+
+	// keywords cannot be used as namespaced function names
+	_, err = New(`SET @out = state.set("a", 1)`)
+	if err == nil || !strings.Contains(err.Error(), "parsing error at 1:18:") {
+		t.Errorf("expected a parsing error when calling a keyword as a namespaced function. got=%v", err)
+	}
+}
+
+func TestMorphPipeline(t *testing.T) {
+	normalize := testMorphMustNew(t, `SET @out = @in
+	SET @out.user = @in.USER
+	DEL @out.USER`)
+	enrich := testMorphMustNew(t, `SET @out = @in
+	SET @out.is_admin = @in.user == "root"`)
+	redact := testMorphMustNew(t, `SET @out = @in
+	SET @out.password = "REDACTED"`)
+
+	pipeline, err := NewPipeline(
+		NewStage("normalize", normalize),
+		NewStage("enrich", enrich),
+		NewStage("redact", redact, WithCondition(`@in.password != null`)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := pipeline.Exec([]byte(`{"USER": "root", "password": "hunter2"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "pipeline stages run in order", `{"user": "root", "is_admin": true, "password": "REDACTED"}`, got)
+
+	got, err = pipeline.Exec([]byte(`{"USER": "bob"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "pipeline skips stages whose condition is false", `{"user": "bob", "is_admin": false}`, got)
+}
+
+func TestMorphPipelineConditionServices(t *testing.T) {
+	provider := lang.NewMemoryLookupProvider()
+	provider.SetTable("admins", map[string]interface{}{"root": true})
+	tag := testMorphMustNew(t, `SET @out = @in
+	SET @out.admin = true`, WithLookupProvider(provider))
+	first := testMorphMustNew(t, `SET @out = @in
+	SET @out.first = true`, WithStateStore(lang.NewMemoryStateStore()))
+
+	pipeline, err := NewPipeline(
+		NewStage("tag", tag, WithCondition(`lookup_default("admins", @in.user, false)`)),
+		NewStage("first", first, WithCondition(`!state.seen(@in.user)`)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := pipeline.Exec([]byte(`{"user": "root"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "conditions use the stage's lookups and state", `{"user": "root", "admin": true, "first": true}`, got)
+	got, err = pipeline.Exec([]byte(`{"user": "root"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "conditions share state between runs", `{"user": "root", "admin": true}`, got)
+}
+
+func TestMorphPipelineDrop(t *testing.T) {
+	filter := testMorphMustNew(t, `IF @in.level == "debug" :: { drop() }
+	SET @out = @in`)
+	fail := testMorphMustNew(t, `SET @out = int("not a number")`)
+	pipeline, err := NewPipeline(NewStage("filter", filter), NewStage("fail", fail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := pipeline.Exec([]byte(`{"level": "debug"}`))
+	if err != nil {
+		t.Fatalf("expected drop to stop the pipeline before the failing stage. got=%v", err)
+	}
+	testMorphCheckJSON(t, "dropped pipeline", `null`, got)
+
+	filter = testMorphMustNew(t, `IF @in.level == "debug" :: { drop("too noisy") }
+	SET @out = @in`)
+	pipeline, err = NewPipeline(NewStage("pass", testMorphMustNew(t, `SET @out = @in`)), NewStage("filter", filter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := pipeline.ExecResult([]byte(`{"level": "debug"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Dropped || res.DroppedStage != "filter" || res.Reason != "too noisy" || string(res.Output) != "null" || len(res.Records) != 0 {
+		t.Errorf("expected the result to describe the drop. got=%+v", res)
+	}
+	res, err = pipeline.ExecResult([]byte(`{"level": "info"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Dropped || len(res.Records) != 1 {
+		t.Errorf("expected a result without a drop. got=%+v", res)
+	}
+	testMorphCheckJSON(t, "pipeline result output", `{"level": "info"}`, res.Output)
+
+	pipeline, err = NewPipeline(NewStage("filter", filter), NewStage("fail", fail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pipeline.Exec([]byte(`{"level": "info"}`))
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "fail" {
+		t.Errorf("expected error labelled with the failing stage. got=%v", err)
+	}
+}
+
+func TestMorphPipelineFanOut(t *testing.T) {
+	split := testMorphMustNew(t, `map(@in.events, e ~> { emit_record(e.value) })`)
+	tag := testMorphMustNew(t, `IF @in.id == 2 :: { drop() }
+	SET @out = @in
+	SET @out.tagged = true`)
+	pipeline, err := NewPipeline(NewStage("split", split), NewStage("tag", tag))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := pipeline.ExecAll([]byte(`{"events": [{"id": 1}, {"id": 2}, {"id": 3}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`{"id":1,"tagged":true}`, `{"id":3,"tagged":true}`}
+	if len(got) != len(want) {
+		t.Fatalf("wrong number of records. want=%d got=%d", len(want), len(got))
+	}
+	for idx := range want {
+		testMorphCheckJSON(t, "pipeline fan-out", want[idx], got[idx])
+	}
+	if _, err := pipeline.Exec([]byte(`{"events": [{"id": 1}, {"id": 3}]}`)); err == nil {
+		t.Error("expected Exec to reject a pipeline that produced several records")
+	}
+}
+
+func TestMorphPipelineErr(t *testing.T) {
+	m := testMorphMustNew(t, `SET @out = @in`)
+	_, err := NewPipeline(NewStage("bad-condition", m, WithCondition(`@in.x ==`)))
+	if err == nil || !strings.Contains(err.Error(), `pipeline stage "bad-condition": invalid condition`) {
+		t.Errorf("expected invalid condition error. got=%v", err)
+	}
+	_, err = NewPipeline(NewStage("positions", m, WithCondition(`@in.x == )`)))
+	if err == nil || !strings.Contains(err.Error(), "parsing error at 1:10:") {
+		t.Errorf("expected condition errors to point at the condition itself. got=%v", err)
+	}
+	_, err = NewPipeline(NewStage("injected", m, WithCondition("true\nSET @out = 1")))
+	if err == nil || !testMorphCheckContainsAll(err.Error(), "invalid condition", "2:1:", "expected a single expression") {
+		t.Errorf("expected a condition with a statement to be rejected. got=%v", err)
+	}
+
+	pipeline, err := NewPipeline(NewStage("not-bool", m, WithCondition(`@in.x`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pipeline.Exec([]byte(`{"x": 1}`))
+	if err == nil || !strings.Contains(err.Error(), "condition must evaluate to a boolean") {
+		t.Errorf("expected non-boolean condition error. got=%v", err)
+	}
+
+	restricted := testMorphMustNew(t, `SET @out = @in`, WithAllowedFunctions("len"))
+	_, err = NewPipeline(NewStage("policy", restricted, WithCondition(`upper(@in.x) == "A"`)))
+	if err == nil || !strings.Contains(err.Error(), `pipeline stage "policy": invalid condition`) {
+		t.Errorf("expected the condition to be subject to the stage's function policy. got=%v", err)
+	}
+	deterministic := testMorphMustNew(t, `SET @out = @in`, WithDeterministic(), WithStateStore(lang.NewMemoryStateStore()))
+	_, err = NewPipeline(NewStage("deterministic", deterministic, WithCondition(`state.seen(@in.x)`)))
+	if err == nil || !strings.Contains(err.Error(), `pipeline stage "deterministic": invalid condition`) {
+		t.Errorf("expected a deterministic stage to reject nondeterministic conditions. got=%v", err)
+	}
+	strict := testMorphMustNew(t, `SET @out = @in`, WithStrict())
+	pipeline, err = NewPipeline(NewStage("strict", strict, WithCondition(`@in.missing == null`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pipeline.Exec([]byte(`{"x": 1}`)); err == nil {
+		t.Error("expected a strict stage to reject a condition that reads a missing path")
+	}
+}
+
+func TestMorphInclude(t *testing.T) {
+	modules := fstest.MapFS{
+		"normalize.morph": {Data: []byte(`SET @out.user = @in.USER
+INCLUDE "defaults.morph"`)},
+		"defaults.morph": {Data: []byte(`SET @out.source = "morph"`)},
+	}
+	m, err := New(`INCLUDE "normalize.morph"
+SET @out.count = @in.count`, WithModuleResolver(lang.NewFSModuleResolver(modules)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Exec([]byte(`{"USER": "alice", "count": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "included statements", `{"user": "alice", "source": "morph", "count": 2}`, got)
+
+	// runtime errors in included statements report the module name
+	m, err = New(`INCLUDE "bad"`, WithModuleResolver(lang.MapModuleResolver{"bad": "SET x = 1\nSET @out = int(@in)"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Exec([]byte(`"abc"`))
+	if err == nil || !strings.Contains(err.Error(), "bad:2:12:") {
+		t.Errorf("expected runtime error to report the module name. got=%v", err)
+	}
+}
+
+func TestMorphIncludeErr(t *testing.T) {
+	tests := []struct {
+		description     string
+		program         string
+		modules         lang.MapModuleResolver
+		wantErrContains []string
+	}{
+		{
+			description:     "check that parse errors report the module name",
+			program:         "SET @out = 1\nINCLUDE \"broken\"",
+			modules:         lang.MapModuleResolver{"broken": "SET x = 1\nSET = 2"},
+			wantErrContains: []string{"parsing error at broken:2:5:"},
+		},
+		{
+			description:     "check that include cycles are detected",
+			program:         `INCLUDE "a"`,
+			modules:         lang.MapModuleResolver{"a": `INCLUDE "b"`, "b": `INCLUDE "a"`},
+			wantErrContains: []string{"parsing error at b:1:9:", "include cycle detected: a -> b -> a"},
+		},
+		{
+			description:     "check that missing modules are reported",
+			program:         `INCLUDE "missing"`,
+			modules:         lang.MapModuleResolver{},
+			wantErrContains: []string{"parsing error at 1:9:", `unable to include "missing": module "missing" does not exist`},
+		},
+		{
+			description:     "check that INCLUDE is only allowed at the top level",
+			program:         `IF true :: { INCLUDE "a" }`,
+			modules:         lang.MapModuleResolver{"a": `SET x = 1`},
+			wantErrContains: []string{"parsing error at 1:14:", "INCLUDE statements are only allowed at the top level"},
+		},
+		{
+			description:     "check that INCLUDE requires a resolver",
+			program:         `INCLUDE "a"`,
+			wantErrContains: []string{"parsing error at 1:1:", "INCLUDE statements require a module resolver"},
+		},
+	}
+	for _, tt := range tests {
+		opts := []Opt{}
+		if tt.modules != nil {
+			opts = append(opts, WithModuleResolver(tt.modules))
+		}
+		_, err := New(tt.program, opts...)
+		if err == nil {
+			t.Errorf("%s: expected error. got none", tt.description)
+			continue
+		}
+		if !testMorphCheckContainsAll(err.Error(), tt.wantErrContains...) {
+			t.Errorf("%s: expected error to contain %q. got=%q", tt.description, tt.wantErrContains, err.Error())
+		}
+	}
+}
+
+func TestMorphMorphFunction(t *testing.T) {
+	fstore := lang.DefaultFunctionStore()
+	clamp, err := lang.NewMorphFunctionEntry("clamp", []string{"value", "low", "high"}, `
+	SET return = max(low, min(value, high))`,
+		lang.WithDescription("Limits a number to a range"),
+		lang.WithReturn(lang.NewFunctionReturn("the clamped number", lang.INTEGER, lang.FLOAT)),
+		lang.WithExamples(lang.NewProgramExample(`null`, `SET @out = helpers.clamp(15, 0, 10)`, `10`)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fstore.RegisterToNamespace("helpers", clamp)
+	percent, err := lang.NewMorphFunctionEntry("percent", []string{"part", "total"}, `
+	IF total == 0 :: SET return = 0
+	IF total != 0 :: SET return = helpers.clamp(int(float(part) / float(total) * 100), 0, 100)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fstore.Register(percent)
+
+	if err := lang.RunFunctionStoreExamples(fstore); err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(`SET @out.pct = percent(@in.done, @in.total)
+	SET @out.zero = percent(1, 0)
+	SET @out.value = value`, WithFunctionStore(fstore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Exec([]byte(`{"done": 3, "total": 4}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "morph function", `{"pct": 75, "zero": 0, "value": null}`, got)
+}
+
+func TestMorphMorphFunctionErr(t *testing.T) {
+	if _, err := lang.NewMorphFunctionEntry("bad", []string{"a", "a"}, `SET return = a`); err == nil {
+		t.Error("expected duplicate parameter error")
+	}
+	if _, err := lang.NewMorphFunctionEntry("bad", []string{"a"}, `SET return = `); err == nil || !strings.Contains(err.Error(), "parsing error at bad:1:") {
+		t.Errorf("expected parse error with the function name. got=%v", err)
+	}
+
+	fstore := lang.DefaultFunctionStore()
+	toInt, err := lang.NewMorphFunctionEntry("to_int", []string{"value"}, `SET return = int(value)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fstore.Register(toInt)
+	m, err := New(`SET @out = to_int("abc")`, WithFunctionStore(fstore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Exec([]byte(`null`))
+	if err == nil || !strings.Contains(err.Error(), "to_int:1:14:") {
+		t.Errorf("expected runtime error to report the function source position. got=%v", err)
+	}
+
+	forever, err := lang.NewMorphFunctionEntry("forever", []string{"n"}, `SET return = forever(n + 1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fstore.Register(forever)
+	m, err = New(`SET @out = forever(0)`, WithFunctionStore(fstore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Exec([]byte(`null`))
+	if err == nil || !strings.Contains(err.Error(), "maximum function call depth of 1000 exceeded") {
+		t.Errorf("expected unbounded recursion to fail with a call depth error. got=%v", err)
+	}
+
+	// the body runs with the caller's store and strictness
+	readB, err := lang.NewMorphFunctionEntry("read_b", []string{"value"}, `SET return = value.b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fstore.Register(readB)
+	m, err = New(`SET @out = read_b(@in)`, WithFunctionStore(fstore), WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Exec([]byte(`{"a": 1}`))
+	if err == nil || !strings.Contains(err.Error(), "read_b:1:") {
+		t.Errorf("expected a strict program to run Morph function bodies strictly. got=%v", err)
+	}
+	fnErr, err := readB.Fn(context.Background(), lang.ObjectNull).AsError()
+	if err != nil || !strings.Contains(fnErr.Error(), "function read_b() can only be called while a program runs") {
+		t.Errorf("expected an error calling a Morph function outside of a program run. got=%v, %v", fnErr, err)
+	}
+}
+
+func TestMorphFunctionPolicy(t *testing.T) {
+	m := testMorphMustNew(t, `SET @out = int(state.incr("count"))`, WithAllowedFunctions("int", "state.*"), WithStateStore(lang.NewMemoryStateStore()))
+	got, err := m.Exec([]byte(`null`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "allowed functions", `1`, got)
+
+	_, err = New(`SET @out = map(@in, e ~> { SET return = len(e.value) })`, WithAllowedFunctions("map"))
+	if err == nil || !testMorphCheckContainsAll(err.Error(), "1:41:", "function std.len() is not allowed") {
+		t.Errorf("expected disallowed function error. got=%v", err)
+	}
+	_, err = New(`SET @out = now()`, WithDeniedCapabilities(lang.FUNCTION_CAPABILITY_CLOCK))
+	if err == nil || !testMorphCheckContainsAll(err.Error(), "function std.now() is not allowed", `denied capability "clock"`) {
+		t.Errorf("expected denied capability error. got=%v", err)
+	}
+	_, err = New(`SET @out = now()`, WithDeniedCapabilities(lang.FUNCTION_CAPABILITY_NONDETERMINISTIC))
+	if err == nil || !testMorphCheckContainsAll(err.Error(), "function std.now() is not allowed", `denied capability "nondeterministic"`) {
+		t.Errorf("expected denied capability error. got=%v", err)
+	}
+
+	fstore := lang.DefaultFunctionStore()
+	stamp, err := lang.NewMorphFunctionEntry("stamp", []string{"value"}, `SET return = {"value": value, "at": string(now())}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fstore.RegisterToNamespace("helpers", stamp)
+	countdown, err := lang.NewMorphFunctionEntry("countdown", []string{"n"}, `
+	IF n > 0 :: SET return = helpers.countdown(n - 1)
+	IF n <= 0 :: SET return = helpers.stamp(n)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fstore.RegisterToNamespace("helpers", countdown)
+	_, err = New(`SET @out = helpers.countdown(3)`, WithFunctionStore(fstore), WithAllowedFunctions("helpers.*", "string"))
+	if err == nil || !testMorphCheckContainsAll(err.Error(), "function helpers.countdown() is not allowed", "stamp:1:44: function std.now() is not allowed") {
+		t.Errorf("expected the policy to check the bodies of Morph functions. got=%v", err)
+	}
+	_, err = New(`SET @out = helpers.countdown(3)`, WithFunctionStore(fstore), WithDeniedCapabilities(lang.FUNCTION_CAPABILITY_CLOCK))
+	if err == nil || !strings.Contains(err.Error(), `denied capability "clock"`) {
+		t.Errorf("expected denied capabilities to apply to the bodies of Morph functions. got=%v", err)
+	}
+	testMorphMustNew(t, `SET @out = helpers.countdown(3)`, WithFunctionStore(fstore), WithAllowedFunctions("helpers.*", "string", "now"))
+
+	modules := lang.MapModuleResolver{"dedupe": `IF state.seen(@in.id) :: { drop() }`}
+	_, err = New(`INCLUDE "dedupe"`, WithModuleResolver(modules), WithDeterministic())
+	if err == nil || !testMorphCheckContainsAll(err.Error(), "dedupe:1:9:", `denied capability "nondeterministic"`) {
+		t.Errorf("expected the policy to check included modules. got=%v", err)
+	}
+}
+
+func TestMorphClock(t *testing.T) {
+	fixed := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := testMorphMustNew(t, `SET @out = string(now())`, WithClock(lang.FixedClock{Time: fixed}))
+	got, err := m.Exec([]byte(`null`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "fixed clock", `"2025-03-01T12:00:00Z"`, got)
+
+	ticks := 0
+	m = testMorphMustNew(t, `SET @out = [string(now()), string(now())]`, WithDeterministic(), WithClock(testMorphTickingClock(func() time.Time {
+		ticks++
+		return fixed.Add(time.Duration(ticks) * time.Second)
+	})))
+	got, err = m.Exec([]byte(`null`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "deterministic clock", `["2025-03-01T12:00:01Z", "2025-03-01T12:00:01Z"]`, got)
+
+	_, err = New(`SET @out = state.incr("count")`, WithDeterministic(), WithStateStore(lang.NewMemoryStateStore()))
+	if err == nil || !strings.Contains(err.Error(), `denied capability "nondeterministic"`) {
+		t.Errorf("expected deterministic mode to reject state functions. got=%v", err)
+	}
+}
+
+func TestMorphOverloadErr(t *testing.T) {
+	_, err := New(`SET @out = len(5)`)
+	if err == nil || !testMorphCheckContainsAll(err.Error(), "1:12:", "no overload that accepts (INTEGER)", "std.len(target:MAP) INTEGER") {
+		t.Errorf("expected compile time overload error. got=%v", err)
+	}
+	m := testMorphMustNew(t, `SET @out = len(@in)`)
+	_, err = m.Exec([]byte(`5`))
+	if err == nil || !strings.Contains(err.Error(), "no overload that accepts (INTEGER)") {
+		t.Errorf("expected runtime overload error. got=%v", err)
+	}
+}
+
+func TestMorphStrict(t *testing.T) {
+	program := `IF has(@in.nickname) :: SET @out.nickname = @in.nickname
+SET @out.name = @in.name`
+	m := testMorphMustNew(t, program, WithStrict())
+	got, err := m.Exec([]byte(`{"name": "ada", "nickname": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "explicit null", `{"name": "ada", "nickname": null}`, got)
+	got, err = m.Exec([]byte(`{"name": "ada"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "missing key", `{"name": "ada"}`, got)
+
+	_, err = m.Exec([]byte(`{"nickname": "a"}`))
+	if err == nil || !strings.Contains(err.Error(), `2:21: path "@in.name" does not exist`) {
+		t.Errorf("expected strict mode error. got=%v", err)
+	}
+	got, err = testMorphMustNew(t, program).Exec([]byte(`{"nickname": "a"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMorphCheckJSON(t, "not strict", `{"name": null, "nickname": "a"}`, got)
+}
+
+func TestMorphKeyOrder(t *testing.T) {
+	input := []byte(`{"b": 1, "a": {"d": 2, "c": 3}}`)
+	program := `SET @out = @in
+SET @out.aa = true`
+	got, err := testMorphMustNew(t, program).Exec(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"b":1,"a":{"d":2,"c":3},"aa":true}`; string(got) != want {
+		t.Errorf("wrong insertion order output. want=%s got=%s", want, got)
+	}
+
+	sorted := testMorphMustNew(t, program, WithSortedKeys())
+	got, err = sorted.Exec(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"a":{"c":3,"d":2},"aa":true,"b":1}`
+	if string(got) != want {
+		t.Errorf("wrong sorted output. want=%s got=%s", want, got)
+	}
+
+	pipeline, err := NewPipeline(NewStage("first", testMorphMustNew(t, `SET @out = @in`)), NewStage("sorted", sorted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = pipeline.Exec(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("wrong sorted pipeline output. want=%s got=%s", want, got)
+	}
+
+	pipeline, err = NewPipeline(NewStage("sorted", sorted), NewStage("last", testMorphMustNew(t, `SET @out = @in`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = pipeline.Exec(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"b":1,"a":{"d":2,"c":3},"aa":true}`; string(got) != want {
+		t.Errorf("expected the last stage to keep insertion order even after a sorted stage. want=%s got=%s", want, got)
+	}
+}
+
 // helpers
+func testMorphMustNew(t *testing.T, program string, opts ...Opt) *morph {
+	m, err := New(program, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+type testMorphTickingClock func() time.Time
+
+func (tc testMorphTickingClock) Now() time.Time {
+	return tc()
+}
+
+type testMorphCountingLookups struct {
+	inner *lang.MemoryLookupProvider
+	calls int
+}
+
+func (cl *testMorphCountingLookups) Lookup(ctx context.Context, table string, key string) (interface{}, bool, error) {
+	cl.calls++
+	return cl.inner.Lookup(ctx, table, key)
+}
+
+func testMorphCheckJSON(t *testing.T, description string, wantJSON string, got []byte) bool {
+	var gotInterface interface{}
+	err := json.Unmarshal(got, &gotInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wantInterface interface{}
+	err = json.Unmarshal([]byte(wantJSON), &wantInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(wantInterface, gotInterface) {
+		t.Errorf("%s: WRONG VALUE\n\twant:\n\t\t%s\n\tgot\n\t\t%s\n", description, wantJSON, string(got))
+		return false
+	}
+	return true
+}
+
 func testMorphCustomFn999(ctx context.Context, args ...*lang.Object) *lang.Object {
 	if ret, ok := lang.IsArgCountEqual(0, args); !ok {
 		return ret
@@ -0,0 +1,204 @@
+package morph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hudsn/morph/lang"
+)
+
+// runs several programs in sequence, where the @out of each stage becomes the @in of the next.
+// values are passed between stages without being encoded to JSON.
+type Pipeline struct {
+	stages []*PipelineStage
+}
+
+type PipelineStage struct {
+	name      string
+	morph     *morph
+	condition string
+	check     *lang.Program
+}
+
+type StageOpt func(*PipelineStage)
+
+// only runs the stage when the Morph expression evaluates to true against the stage's @in. the condition must be a single expression, not statements.
+// records that do not match the condition are passed to the next stage unchanged.
+// the condition is compiled with the same options as the stage's program, so it is subject to the same function policy and strictness.
+func WithCondition(expression string) StageOpt {
+	return func(ps *PipelineStage) {
+		ps.condition = expression
+	}
+}
+
+// creates a pipeline stage from a compiled program. the name labels any errors from the stage.
+func NewStage(name string, m *morph, opts ...StageOpt) *PipelineStage {
+	s := &PipelineStage{
+		name:  name,
+		morph: m,
+	}
+	for _, fn := range opts {
+		fn(s)
+	}
+	return s
+}
+
+// an error from a single pipeline stage
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (se *StageError) Error() string {
+	return fmt.Sprintf("pipeline stage %q: %s", se.Stage, se.Err.Error())
+}
+
+func (se *StageError) Unwrap() error {
+	return se.Err
+}
+
+func NewPipeline(stages ...*PipelineStage) (*Pipeline, error) {
+	if len(stages) == 0 {
+		return nil, errors.New("a pipeline requires at least one stage")
+	}
+	for _, s := range stages {
+		if s.morph == nil {
+			return nil, &StageError{Stage: s.name, Err: errors.New("stage has no program")}
+		}
+		if len(s.condition) == 0 {
+			continue
+		}
+		check, err := s.morph.compileExpression(s.condition)
+		if err != nil {
+			return nil, &StageError{Stage: s.name, Err: fmt.Errorf("invalid condition: %w", err)}
+		}
+		s.check = check
+	}
+	return &Pipeline{stages: stages}, nil
+}
+
+// describes the outcome of running a pipeline
+type PipelineResult struct {
+	Output  []byte   // the JSON encoded final record if the pipeline produced exactly one, otherwise "null"
+	Records [][]byte // every JSON encoded final record, in order. empty if every record was dropped
+
+	Dropped      bool   // whether a stage halted a record with drop()
+	DroppedStage string // the name of the stage that dropped the first dropped record
+	Reason       string // the reason passed to drop() for the first dropped record, if any
+}
+
+// runs every stage and returns the final output. a drop() in any stage stops the pipeline and returns null.
+// if a stage emits several records with emit_record(), use ExecAll instead. use ExecResult to find out whether the record was dropped.
+func (p *Pipeline) Exec(inputData []byte) ([]byte, error) {
+	res, err := p.ExecResult(inputData)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Records) > 1 {
+		return nil, fmt.Errorf("pipeline produced %d records; use ExecAll to receive every record", len(res.Records))
+	}
+	return res.Output, nil
+}
+
+// runs every stage and returns every final record. records emitted by a stage with emit_record() are each passed through the remaining stages.
+// records halted by drop() are removed from the pipeline.
+func (p *Pipeline) ExecAll(inputData []byte) ([][]byte, error) {
+	res, err := p.ExecResult(inputData)
+	if err != nil {
+		return nil, err
+	}
+	return res.Records, nil
+}
+
+// runs every stage and describes the outcome, including whether a stage dropped a record and why
+func (p *Pipeline) ExecResult(inputData []byte) (*PipelineResult, error) {
+	ret := &PipelineResult{Output: []byte("null"), Records: [][]byte{}}
+	outputs, err := p.run(context.Background(), inputData, ret)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range outputs {
+		b, err := o.EncodeJSON(p.sortKeys())
+		if err != nil {
+			return nil, err
+		}
+		ret.Records = append(ret.Records, b)
+	}
+	if len(ret.Records) == 1 {
+		ret.Output = ret.Records[0]
+	}
+	return ret, nil
+}
+
+// reports whether the final output should have sorted keys, which follows the program of the last stage, since it produces the output
+func (p *Pipeline) sortKeys() bool {
+	return p.stages[len(p.stages)-1].morph.sortKeys
+}
+
+// runs every stage, and records the first drop() in res
+func (p *Pipeline) run(ctx context.Context, inputData []byte, res *PipelineResult) ([]*lang.Object, error) {
+	input, err := lang.ObjectFromJSON(inputData)
+	if err != nil {
+		return nil, err
+	}
+	items := []*lang.Object{input}
+	for _, stage := range p.stages {
+		next := []*lang.Object{}
+		for _, item := range items {
+			outputs, dropped, err := stage.run(ctx, item)
+			if err != nil {
+				return nil, &StageError{Stage: stage.name, Err: err}
+			}
+			if dropped != nil && !res.Dropped {
+				res.Dropped = true
+				res.DroppedStage = stage.name
+				res.Reason = dropped.Reason
+			}
+			next = append(next, outputs...)
+		}
+		if len(next) == 0 {
+			return next, nil
+		}
+		items = next
+	}
+	return items, nil
+}
+
+// runs the stage against a single record, and returns the records to pass to the next stage.
+// if the stage dropped the record, the result of its run is returned too.
+func (s *PipelineStage) run(ctx context.Context, item *lang.Object) ([]*lang.Object, *lang.Result, error) {
+	if s.check != nil {
+		ok, err := s.matches(ctx, item)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return []*lang.Object{item}, nil, nil
+		}
+	}
+	res, err := s.morph.execObject(ctx, item)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.Dropped {
+		return nil, res, nil
+	}
+	if len(res.RecordObjects()) > 0 {
+		return res.AllObjects(), nil, nil
+	}
+	return []*lang.Object{res.OutputObject()}, nil, nil
+}
+
+func (s *PipelineStage) matches(ctx context.Context, item *lang.Object) (bool, error) {
+	m := s.morph
+	res, err := s.check.RunObject(item, lang.WithContext(ctx), lang.WithVars(m.vars), lang.WithLookupProvider(m.lookups), lang.WithStateStore(m.state), lang.WithClock(m.runClock()))
+	if err != nil {
+		return false, fmt.Errorf("condition: %w", err)
+	}
+	out := res.OutputObject()
+	if out.Type() != string(lang.BOOLEAN) {
+		return false, fmt.Errorf("condition must evaluate to a boolean. got=%s", out.Type())
+	}
+	return out.AsBool()
+}
@@ -0,0 +1,247 @@
+package morph
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hudsn/morph/lang"
+)
+
+// determines what ExecStream does when a single record fails to parse or run
+type ErrorPolicy int
+
+const (
+	ERROR_POLICY_ABORT  ErrorPolicy = iota // stop processing and return the error of the failed record
+	ERROR_POLICY_SKIP                      // ignore the failed record and continue with the next one
+	ERROR_POLICY_REPORT                    // write the failed record's line number and error to the error writer, and continue
+)
+
+type streamConfig struct {
+	errorPolicy ErrorPolicy
+	errorWriter io.Writer
+	workers     int
+}
+
+type StreamOpt func(*streamConfig)
+
+func WithErrorPolicy(policy ErrorPolicy) StreamOpt {
+	return func(sc *streamConfig) {
+		sc.errorPolicy = policy
+	}
+}
+
+// writes one JSON line per failed record to w, in the form {"line": 1, "error": "..."}
+// also sets the error policy to ERROR_POLICY_REPORT
+func WithErrorWriter(w io.Writer) StreamOpt {
+	return func(sc *streamConfig) {
+		sc.errorPolicy = ERROR_POLICY_REPORT
+		sc.errorWriter = w
+	}
+}
+
+// runs records on a pool of n workers. output order always matches input order.
+func WithWorkers(n int) StreamOpt {
+	return func(sc *streamConfig) {
+		sc.workers = n
+	}
+}
+
+// reads newline-delimited JSON from r, runs the program once per line, and writes each output as a line to w.
+// blank lines are ignored, and records that call drop() produce no output line.
+// records that call emit_record() produce one line per emitted record, followed by @out if it was set.
+func (m *morph) ExecStream(ctx context.Context, r io.Reader, w io.Writer, opts ...StreamOpt) error {
+	cfg := &streamConfig{
+		errorPolicy: ERROR_POLICY_ABORT,
+		workers:     1,
+	}
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	if cfg.errorPolicy == ERROR_POLICY_REPORT && cfg.errorWriter == nil {
+		return errors.New("stream error policy ERROR_POLICY_REPORT requires an error writer")
+	}
+
+	out := bufio.NewWriter(w)
+	var err error
+	if cfg.workers <= 1 {
+		err = m.execStreamSequential(ctx, r, out, cfg)
+	} else {
+		err = m.execStreamParallel(ctx, r, out, cfg)
+	}
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+type streamRecord struct {
+	line   int
+	data   []byte
+	result *lang.Result
+	err    error
+	done   chan struct{}
+}
+
+func (m *morph) execStreamSequential(ctx context.Context, r io.Reader, out *bufio.Writer, cfg *streamConfig) error {
+	reader := newStreamLineReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rec, err := reader.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		m.runStreamRecord(ctx, rec)
+		if err := writeStreamRecord(out, rec, cfg); err != nil {
+			return err
+		}
+	}
+}
+
+func (m *morph) execStreamParallel(ctx context.Context, r io.Reader, out *bufio.Writer, cfg *streamConfig) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan *streamRecord, cfg.workers)
+	ordered := make(chan *streamRecord, cfg.workers*2)
+	var readErr error
+
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		reader := newStreamLineReader(r)
+		for {
+			rec, err := reader.next()
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+			select {
+			case ordered <- rec:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- rec:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for range cfg.workers {
+		go func() {
+			for rec := range jobs {
+				m.runStreamRecord(ctx, rec)
+			}
+		}()
+	}
+
+	for rec := range ordered {
+		// the reader can abandon a record that it already queued here when ctx is cancelled, so its done channel may never be closed
+		select {
+		case <-rec.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := writeStreamRecord(out, rec, cfg); err != nil {
+			return err
+		}
+	}
+	// the reader only sets readErr before closing ordered, so it is safe to read once the loop is done
+	return readErr
+}
+
+func (m *morph) runStreamRecord(ctx context.Context, rec *streamRecord) {
+	defer close(rec.done)
+	rec.result, rec.err = m.exec(ctx, rec.data, m.vars)
+}
+
+func writeStreamRecord(out *bufio.Writer, rec *streamRecord, cfg *streamConfig) error {
+	if rec.err != nil {
+		switch cfg.errorPolicy {
+		case ERROR_POLICY_SKIP:
+			return nil
+		case ERROR_POLICY_REPORT:
+			return writeStreamError(cfg.errorWriter, rec)
+		default:
+			return fmt.Errorf("line %d: %w", rec.line, rec.err)
+		}
+	}
+	if rec.result.Dropped {
+		return nil
+	}
+	lines := [][]byte{rec.result.Output}
+	if len(rec.result.Records) > 0 {
+		lines = rec.result.All()
+	}
+	for _, line := range lines {
+		if _, err := out.Write(line); err != nil {
+			return err
+		}
+		if err := out.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeStreamError(w io.Writer, rec *streamRecord) error {
+	b, err := json.Marshal(struct {
+		Line  int    `json:"line"`
+		Error string `json:"error"`
+	}{
+		Line:  rec.line,
+		Error: rec.err.Error(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+type streamLineReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func newStreamLineReader(r io.Reader) *streamLineReader {
+	return &streamLineReader{reader: bufio.NewReader(r)}
+}
+
+// returns the next non-blank line as a record, or io.EOF once the input is exhausted
+func (s *streamLineReader) next() (*streamRecord, error) {
+	for {
+		data, err := s.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(data) == 0 && err == io.EOF {
+			return nil, io.EOF
+		}
+		s.line++
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			continue
+		}
+		return &streamRecord{line: s.line, data: data, done: make(chan struct{})}, nil
+	}
+}